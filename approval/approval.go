@@ -0,0 +1,145 @@
+package approval
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const _tokenLength = 24
+
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name       string   `json:"name"`
+	Workspaces []string `json:"workspaces,omitempty"`
+	MaxDestroy *int     `json:"max_destroy,omitempty"`
+	MaxReplace *int     `json:"max_replace,omitempty"`
+	Protected  []string `json:"protected,omitempty"`
+}
+
+type Gate struct {
+	rules []Rule
+}
+
+func New(cfg Config) (*Gate, error) {
+	rules := make([]Rule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.MaxDestroy == nil && rule.MaxReplace == nil && len(rule.Protected) == 0 {
+			return nil, fmt.Errorf("approval rule %q: no thresholds or protected addresses", rule.Name)
+		}
+		for _, p := range slices.Concat(rule.Workspaces, rule.Protected) {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("approval rule %q: invalid pattern %q", rule.Name, p)
+			}
+		}
+		rules[i] = rule
+	}
+	return &Gate{rules: rules}, nil
+}
+
+func (g *Gate) Applies(workspace string) bool {
+	return slices.ContainsFunc(g.rules, func(rule Rule) bool {
+		return rule.covers(workspace)
+	})
+}
+
+func (g *Gate) Check(workspace string, planned []run.Planned) []string {
+	var reasons []string
+
+	var destroys, replaces int
+	for _, p := range planned {
+		switch p.Action {
+		case "destroy":
+			destroys++
+		case "replace":
+			replaces++
+		}
+	}
+
+	for _, rule := range g.rules {
+		if !rule.covers(workspace) {
+			continue
+		}
+		if rule.MaxDestroy != nil && destroys > *rule.MaxDestroy {
+			reasons = append(reasons, fmt.Sprintf("%s: %d to destroy (max %d)", rule.Name, destroys, *rule.MaxDestroy))
+		}
+		if rule.MaxReplace != nil && replaces > *rule.MaxReplace {
+			reasons = append(reasons, fmt.Sprintf("%s: %d to replace (max %d)", rule.Name, replaces, *rule.MaxReplace))
+		}
+		for _, p := range planned {
+			if p.Action != "destroy" && p.Action != "replace" {
+				continue
+			}
+			if matchAny(rule.Protected, p.Address) {
+				reasons = append(reasons, fmt.Sprintf("%s: protected resource %s will be %s", rule.Name, p.Address, pastTense(p.Action)))
+			}
+		}
+	}
+
+	return reasons
+}
+
+func Token(workspace string, planned []run.Planned, planHash, nonce string) string {
+	lines := make([]string, 0, len(planned))
+	for _, p := range planned {
+		lines = append(lines, p.Action+" "+p.Address)
+	}
+	slices.Sort(lines)
+
+	h := sha256.New()
+	h.Write([]byte(workspace + "\n" + planHash + "\n" + nonce + "\n"))
+	h.Write([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(h.Sum(nil))[:_tokenLength]
+}
+
+func NewNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (rule Rule) covers(workspace string) bool {
+	return len(rule.Workspaces) == 0 || matchAny(rule.Workspaces, workspace)
+}
+
+func matchAny(patterns []string, s string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		matched, _ := path.Match(p, s)
+		return matched
+	})
+}
+
+func pastTense(action string) string {
+	if action == "destroy" {
+		return "destroyed"
+	}
+	return "replaced"
+}
@@ -0,0 +1,138 @@
+package approval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestGate_Check(t *testing.T) {
+	gate, err := New(Config{Rules: []Rule{
+		{Name: "prod", Workspaces: []string{"prod/*"}, MaxDestroy: intPtr(0), MaxReplace: intPtr(1)},
+		{Name: "databases", Protected: []string{"aws_db_instance.*", "module.db.*"}},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		workspace string
+		planned   []run.Planned
+		want      []string
+	}{
+		{
+			name:      "creates only",
+			workspace: "prod/vpc",
+			planned:   []run.Planned{{Address: "aws_vpc.main", Action: "create"}},
+		},
+		{
+			name:      "destroy over threshold",
+			workspace: "prod/vpc",
+			planned:   []run.Planned{{Address: "aws_subnet.a", Action: "destroy"}},
+			want:      []string{"prod: 1 to destroy (max 0)"},
+		},
+		{
+			name:      "replace within threshold",
+			workspace: "prod/vpc",
+			planned:   []run.Planned{{Address: "aws_instance.web", Action: "replace"}},
+		},
+		{
+			name:      "other workspace",
+			workspace: "dev/vpc",
+			planned:   []run.Planned{{Address: "aws_subnet.a", Action: "destroy"}},
+		},
+		{
+			name:      "protected replace",
+			workspace: "dev/db",
+			planned: []run.Planned{
+				{Address: "module.db.aws_db_instance.main", Action: "replace"},
+				{Address: "aws_db_instance.other", Action: "update"},
+			},
+			want: []string{"databases: protected resource module.db.aws_db_instance.main will be replaced"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gate.Check(tt.workspace, tt.planned)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGate_Applies(t *testing.T) {
+	gate, err := New(Config{Rules: []Rule{{Workspaces: []string{"prod/*"}, MaxDestroy: intPtr(0)}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !gate.Applies("prod/vpc") {
+		t.Error("expected gate to apply to prod/vpc")
+	}
+	if gate.Applies("dev/vpc") {
+		t.Error("expected gate not to apply to dev/vpc")
+	}
+}
+
+func TestToken(t *testing.T) {
+	a := []run.Planned{{Address: "aws_subnet.a", Action: "destroy"}, {Address: "aws_vpc.main", Action: "create"}}
+	b := []run.Planned{{Address: "aws_vpc.main", Action: "create"}, {Address: "aws_subnet.a", Action: "destroy"}}
+	c := []run.Planned{{Address: "aws_subnet.a", Action: "replace"}, {Address: "aws_vpc.main", Action: "create"}}
+
+	token := Token("prod", a, "plan", "nonce")
+	if token != Token("prod", b, "plan", "nonce") {
+		t.Error("token should not depend on plan order")
+	}
+	if token == Token("prod", c, "plan", "nonce") {
+		t.Error("token should change when an action changes")
+	}
+	if token == Token("dev", a, "plan", "nonce") {
+		t.Error("token should change with the workspace")
+	}
+	if token == Token("prod", a, "other plan", "nonce") {
+		t.Error("token should change with the plan file")
+	}
+	if token == Token("prod", a, "plan", NewNonce()) {
+		t.Error("token should change with the nonce")
+	}
+	if len(token) != _tokenLength {
+		t.Errorf("token length = %d, want %d", len(token), _tokenLength)
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.tfplan")
+	if err := os.WriteFile(path, []byte("plan"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	if sum := sha256.Sum256([]byte("plan")); hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("HashFile() = %q, want the sha256 of the file", hash)
+	}
+	if _, err := HashFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{Rules: []Rule{{Name: "empty"}}}); err == nil {
+		t.Error("expected error for rule without conditions")
+	}
+	if _, err := New(Config{Rules: []Rule{{Protected: []string{"aws_[.x"}}}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_stateFile        = "terraform.tfstate"
+	_environmentFile  = "environment"
+	_defaultWorkspace = "default"
+	_terragruntCache  = ".terragrunt-cache/*/*/.terraform"
+	_defaultTFCHost   = "app.terraform.io"
+)
+
+func Detect(workdir string) *run.StateInfo {
+	dir := dataDir(workdir)
+	data, err := os.ReadFile(filepath.Join(dir, _stateFile))
+	if err != nil {
+		dir = latestTerragruntDir(workdir)
+		if dir == "" {
+			return nil
+		}
+		if data, err = os.ReadFile(filepath.Join(dir, _stateFile)); err != nil {
+			return nil
+		}
+	}
+	return parse(data, currentWorkspace(dir), workdir)
+}
+
+func Parse(data []byte, workspace string) *run.StateInfo {
+	return parse(data, workspace, "")
+}
+
+func parse(data []byte, workspace, workdir string) *run.StateInfo {
+	var state struct {
+		Backend *struct {
+			Type   string         `json:"type"`
+			Config map[string]any `json:"config"`
+		} `json:"backend"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil || state.Backend == nil || state.Backend.Type == "" {
+		return nil
+	}
+
+	info := &run.StateInfo{
+		Backend:   state.Backend.Type,
+		Workspace: workspace,
+		ID:        identity(state.Backend.Type, state.Backend.Config, workspace, workdir),
+	}
+	if info.Workspace == _defaultWorkspace {
+		info.Workspace = ""
+	}
+	return info
+}
+
+func Identity(backendType string, config map[string]any, workspace string) string {
+	return identity(backendType, config, workspace, "")
+}
+
+func identity(backendType string, config map[string]any, workspace, workdir string) string {
+	str := func(key string) string {
+		if v, ok := config[key].(string); ok {
+			return v
+		}
+		return ""
+	}
+	named := workspace != "" && workspace != _defaultWorkspace
+
+	switch backendType {
+	case "s3":
+		key := str("key")
+		if named {
+			prefix := str("workspace_key_prefix")
+			if prefix == "" {
+				prefix = "env:"
+			}
+			key = prefix + "/" + workspace + "/" + key
+		}
+		return "s3://" + str("bucket") + "/" + key
+	case "gcs":
+		name := workspace
+		if name == "" {
+			name = _defaultWorkspace
+		}
+		return "gcs://" + joinPath(str("bucket"), str("prefix"), name+".tfstate")
+	case "azurerm":
+		key := str("key")
+		if named {
+			key += "env:" + workspace
+		}
+		return "azurerm://" + joinPath(str("storage_account_name"), str("container_name"), key)
+	case "remote", "cloud":
+		return "tfc://" + joinPath(firstNonEmpty(str("hostname"), os.Getenv("TF_CLOUD_HOSTNAME"), _defaultTFCHost),
+			firstNonEmpty(str("organization"), os.Getenv("TF_CLOUD_ORGANIZATION")), remoteWorkspace(config, workspace))
+	case "consul":
+		path := str("path")
+		if named {
+			path += "-env:" + workspace
+		}
+		return "consul://" + joinPath(firstNonEmpty(str("address"), os.Getenv("CONSUL_HTTP_ADDR")), path)
+	case "kubernetes":
+		name := workspace
+		if name == "" {
+			name = _defaultWorkspace
+		}
+		return "kubernetes://" + joinPath(firstNonEmpty(str("namespace"), "default"), "tfstate-"+name+"-"+str("secret_suffix"))
+	case "pg":
+		name := workspace
+		if name == "" {
+			name = _defaultWorkspace
+		}
+		return "pg://" + joinPath(firstNonEmpty(str("schema_name"), "terraform_remote_state"), name)
+	case "http":
+		u, err := url.Parse(str("address"))
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		u.User, u.RawQuery, u.Fragment = nil, "", ""
+		return u.String()
+	case "local":
+		path := firstNonEmpty(str("path"), _stateFile)
+		if named {
+			path = filepath.Join(firstNonEmpty(str("workspace_dir"), "terraform.tfstate.d"), workspace, _stateFile)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workdir, path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return "local://" + filepath.ToSlash(path)
+	default:
+		return ""
+	}
+}
+
+func remoteWorkspace(config map[string]any, workspace string) string {
+	workspaces, _ := config["workspaces"].(map[string]any)
+	if name, ok := workspaces["name"].(string); ok && name != "" {
+		return name
+	}
+	if prefix, ok := workspaces["prefix"].(string); ok && prefix != "" {
+		return prefix + workspace
+	}
+	return firstNonEmpty(os.Getenv("TF_WORKSPACE"), workspace)
+}
+
+func currentWorkspace(dir string) string {
+	if ws := os.Getenv("TF_WORKSPACE"); ws != "" {
+		return ws
+	}
+	data, err := os.ReadFile(filepath.Join(dir, _environmentFile))
+	if err != nil {
+		return _defaultWorkspace
+	}
+	if ws := strings.TrimSpace(string(data)); ws != "" {
+		return ws
+	}
+	return _defaultWorkspace
+}
+
+func latestTerragruntDir(workdir string) string {
+	matches, _ := filepath.Glob(filepath.Join(workdir, _terragruntCache))
+	var (
+		latest   string
+		modified time.Time
+	)
+	for _, dir := range matches {
+		fi, err := os.Stat(filepath.Join(dir, _stateFile))
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().After(modified) {
+			latest, modified = dir, fi.ModTime()
+		}
+	}
+	return latest
+}
+
+func dataDir(workdir string) string {
+	dir := os.Getenv("TF_DATA_DIR")
+	if dir == "" {
+		dir = ".terraform"
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(workdir, dir)
+}
+
+func joinPath(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.Trim(p, "/"); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -1,39 +1,352 @@
 package ci
 
-import "os"
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const _overridePrefix = "TFJOURNAL_CI_"
 
 type Info struct {
-	Provider string `json:"provider"`
-	RunID    string `json:"run_id,omitempty"`
-	Workflow string `json:"workflow,omitempty"`
-	Actor    string `json:"actor,omitempty"`
-	URL      string `json:"url,omitempty"`
+	Provider       string `json:"provider"`
+	RunID          string `json:"run_id,omitempty"`
+	Workflow       string `json:"workflow,omitempty"`
+	Job            string `json:"job,omitempty"`
+	Attempt        int    `json:"attempt,omitempty"`
+	Event          string `json:"event,omitempty"`
+	Actor          string `json:"actor,omitempty"`
+	PullRequest    string `json:"pull_request,omitempty"`
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+	SourceBranch   string `json:"source_branch,omitempty"`
+	TargetBranch   string `json:"target_branch,omitempty"`
+	Commit         string `json:"commit,omitempty"`
+	URL            string `json:"url,omitempty"`
+}
+
+var providers = []func() *Info{
+	atlantis,
+	spacelift,
+	githubActions,
+	gitlabCI,
+	buildkite,
+	circleCI,
+	azurePipelines,
+	bitbucketPipelines,
+	jenkins,
 }
 
 func Detect() *Info {
-	if os.Getenv("GITHUB_ACTIONS") == "true" {
-		return &Info{
-			Provider: "github-actions",
-			RunID:    os.Getenv("GITHUB_RUN_ID"),
-			Workflow: os.Getenv("GITHUB_WORKFLOW"),
-			Actor:    os.Getenv("GITHUB_ACTOR"),
-			URL:      os.Getenv("GITHUB_SERVER_URL") + "/" + os.Getenv("GITHUB_REPOSITORY") + "/actions/runs/" + os.Getenv("GITHUB_RUN_ID"),
+	var info *Info
+	for _, detect := range providers {
+		if info = detect(); info != nil {
+			break
 		}
 	}
+	return applyOverrides(info)
+}
+
+func IsCI() bool {
+	return Detect() != nil
+}
 
-	if os.Getenv("GITLAB_CI") == "true" {
-		return &Info{
-			Provider: "gitlab-ci",
-			RunID:    os.Getenv("CI_PIPELINE_ID"),
-			Workflow: os.Getenv("CI_JOB_NAME"),
-			Actor:    os.Getenv("GITLAB_USER_LOGIN"),
-			URL:      os.Getenv("CI_PIPELINE_URL"),
+func applyOverrides(info *Info) *Info {
+	if provider := os.Getenv(_overridePrefix + "PROVIDER"); provider != "" {
+		if info == nil {
+			info = &Info{}
 		}
+		info.Provider = provider
+	}
+	if info == nil {
+		return nil
 	}
 
-	return nil
+	fields := []struct {
+		name string
+		dst  *string
+	}{
+		{"RUN_ID", &info.RunID},
+		{"WORKFLOW", &info.Workflow},
+		{"JOB", &info.Job},
+		{"EVENT", &info.Event},
+		{"ACTOR", &info.Actor},
+		{"PR", &info.PullRequest},
+		{"PR_URL", &info.PullRequestURL},
+		{"SOURCE_BRANCH", &info.SourceBranch},
+		{"TARGET_BRANCH", &info.TargetBranch},
+		{"COMMIT", &info.Commit},
+		{"URL", &info.URL},
+	}
+	for _, f := range fields {
+		if v := os.Getenv(_overridePrefix + f.name); v != "" {
+			*f.dst = v
+		}
+	}
+	if attempt := envInt(_overridePrefix + "ATTEMPT"); attempt > 0 {
+		info.Attempt = attempt
+	}
+	return info
 }
 
-func IsCI() bool {
-	return Detect() != nil
+func githubActions() *Info {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+	repoURL := os.Getenv("GITHUB_SERVER_URL") + "/" + os.Getenv("GITHUB_REPOSITORY")
+	pr := githubPullRequest()
+	return &Info{
+		Provider:       "github-actions",
+		RunID:          os.Getenv("GITHUB_RUN_ID"),
+		Workflow:       os.Getenv("GITHUB_WORKFLOW"),
+		Job:            os.Getenv("GITHUB_JOB"),
+		Attempt:        envInt("GITHUB_RUN_ATTEMPT"),
+		Event:          os.Getenv("GITHUB_EVENT_NAME"),
+		Actor:          os.Getenv("GITHUB_ACTOR"),
+		PullRequest:    pr,
+		PullRequestURL: joinURL(repoURL+"/pull/", pr),
+		SourceBranch:   firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME"),
+		TargetBranch:   os.Getenv("GITHUB_BASE_REF"),
+		Commit:         os.Getenv("GITHUB_SHA"),
+		URL:            repoURL + "/actions/runs/" + os.Getenv("GITHUB_RUN_ID"),
+	}
+}
+
+func githubPullRequest() string {
+	if rest, ok := strings.CutPrefix(os.Getenv("GITHUB_REF"), "refs/pull/"); ok {
+		number, _, _ := strings.Cut(rest, "/")
+		return number
+	}
+	if os.Getenv("GITHUB_HEAD_REF") == "" {
+		return ""
+	}
+	data, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return ""
+	}
+	var event struct {
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil || event.PullRequest.Number == 0 {
+		return ""
+	}
+	return strconv.Itoa(event.PullRequest.Number)
+}
+
+func gitlabCI() *Info {
+	if os.Getenv("GITLAB_CI") != "true" {
+		return nil
+	}
+	mr := os.Getenv("CI_MERGE_REQUEST_IID")
+	var mrURL string
+	if project := firstEnv("CI_MERGE_REQUEST_PROJECT_URL", "CI_PROJECT_URL"); project != "" {
+		mrURL = joinURL(project+"/-/merge_requests/", mr)
+	}
+	return &Info{
+		Provider:       "gitlab-ci",
+		RunID:          os.Getenv("CI_PIPELINE_ID"),
+		Workflow:       os.Getenv("CI_JOB_NAME"),
+		Job:            os.Getenv("CI_JOB_NAME"),
+		Event:          os.Getenv("CI_PIPELINE_SOURCE"),
+		Actor:          os.Getenv("GITLAB_USER_LOGIN"),
+		PullRequest:    mr,
+		PullRequestURL: mrURL,
+		SourceBranch:   firstEnv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH"),
+		TargetBranch:   os.Getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+		Commit:         os.Getenv("CI_COMMIT_SHA"),
+		URL:            os.Getenv("CI_PIPELINE_URL"),
+	}
+}
+
+func jenkins() *Info {
+	if os.Getenv("JENKINS_URL") == "" {
+		return nil
+	}
+	return &Info{
+		Provider:       "jenkins",
+		RunID:          os.Getenv("BUILD_NUMBER"),
+		Workflow:       os.Getenv("JOB_NAME"),
+		Job:            os.Getenv("STAGE_NAME"),
+		Actor:          firstEnv("BUILD_USER_ID", "CHANGE_AUTHOR"),
+		PullRequest:    os.Getenv("CHANGE_ID"),
+		PullRequestURL: os.Getenv("CHANGE_URL"),
+		SourceBranch:   firstEnv("CHANGE_BRANCH", "BRANCH_NAME"),
+		TargetBranch:   os.Getenv("CHANGE_TARGET"),
+		Commit:         os.Getenv("GIT_COMMIT"),
+		URL:            os.Getenv("BUILD_URL"),
+	}
+}
+
+func circleCI() *Info {
+	if os.Getenv("CIRCLECI") != "true" {
+		return nil
+	}
+	prURL := os.Getenv("CIRCLE_PULL_REQUEST")
+	pr := os.Getenv("CIRCLE_PR_NUMBER")
+	if pr == "" && prURL != "" {
+		pr = path.Base(prURL)
+	}
+	return &Info{
+		Provider:       "circleci",
+		RunID:          os.Getenv("CIRCLE_WORKFLOW_ID"),
+		Workflow:       os.Getenv("CIRCLE_PROJECT_REPONAME"),
+		Job:            os.Getenv("CIRCLE_JOB"),
+		Actor:          os.Getenv("CIRCLE_USERNAME"),
+		PullRequest:    pr,
+		PullRequestURL: prURL,
+		SourceBranch:   os.Getenv("CIRCLE_BRANCH"),
+		Commit:         os.Getenv("CIRCLE_SHA1"),
+		URL:            os.Getenv("CIRCLE_BUILD_URL"),
+	}
+}
+
+func buildkite() *Info {
+	if os.Getenv("BUILDKITE") != "true" {
+		return nil
+	}
+	pr := os.Getenv("BUILDKITE_PULL_REQUEST")
+	if pr == "false" {
+		pr = ""
+	}
+	attempt := 0
+	if retries := os.Getenv("BUILDKITE_RETRY_COUNT"); retries != "" {
+		if n, err := strconv.Atoi(retries); err == nil {
+			attempt = n + 1
+		}
+	}
+	return &Info{
+		Provider:     "buildkite",
+		RunID:        os.Getenv("BUILDKITE_BUILD_NUMBER"),
+		Workflow:     os.Getenv("BUILDKITE_PIPELINE_SLUG"),
+		Job:          os.Getenv("BUILDKITE_LABEL"),
+		Attempt:      attempt,
+		Event:        os.Getenv("BUILDKITE_SOURCE"),
+		Actor:        firstEnv("BUILDKITE_BUILD_CREATOR_EMAIL", "BUILDKITE_BUILD_CREATOR"),
+		PullRequest:  pr,
+		SourceBranch: os.Getenv("BUILDKITE_BRANCH"),
+		TargetBranch: os.Getenv("BUILDKITE_PULL_REQUEST_BASE_BRANCH"),
+		Commit:       os.Getenv("BUILDKITE_COMMIT"),
+		URL:          os.Getenv("BUILDKITE_BUILD_URL"),
+	}
+}
+
+func azurePipelines() *Info {
+	if !strings.EqualFold(os.Getenv("TF_BUILD"), "true") {
+		return nil
+	}
+	buildID := os.Getenv("BUILD_BUILDID")
+	var url string
+	if collection := os.Getenv("SYSTEM_COLLECTIONURI"); collection != "" {
+		url = strings.TrimSuffix(collection, "/") + "/" + os.Getenv("SYSTEM_TEAMPROJECT") + "/_build/results?buildId=" + buildID
+	}
+	source := os.Getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH")
+	if source == "" {
+		source = os.Getenv("BUILD_SOURCEBRANCH")
+	}
+	return &Info{
+		Provider:     "azure-pipelines",
+		RunID:        buildID,
+		Workflow:     os.Getenv("BUILD_DEFINITIONNAME"),
+		Job:          os.Getenv("SYSTEM_JOBDISPLAYNAME"),
+		Attempt:      envInt("SYSTEM_JOBATTEMPT"),
+		Event:        os.Getenv("BUILD_REASON"),
+		Actor:        os.Getenv("BUILD_REQUESTEDFOR"),
+		PullRequest:  firstEnv("SYSTEM_PULLREQUEST_PULLREQUESTNUMBER", "SYSTEM_PULLREQUEST_PULLREQUESTID"),
+		SourceBranch: strings.TrimPrefix(source, "refs/heads/"),
+		TargetBranch: strings.TrimPrefix(os.Getenv("SYSTEM_PULLREQUEST_TARGETBRANCH"), "refs/heads/"),
+		Commit:       os.Getenv("BUILD_SOURCEVERSION"),
+		URL:          url,
+	}
+}
+
+func bitbucketPipelines() *Info {
+	buildNumber := os.Getenv("BITBUCKET_BUILD_NUMBER")
+	if buildNumber == "" {
+		return nil
+	}
+	pr := os.Getenv("BITBUCKET_PR_ID")
+	var url, prURL string
+	if repo := os.Getenv("BITBUCKET_REPO_FULL_NAME"); repo != "" {
+		url = "https://bitbucket.org/" + repo + "/pipelines/results/" + buildNumber
+		prURL = joinURL("https://bitbucket.org/"+repo+"/pull-requests/", pr)
+	}
+	return &Info{
+		Provider:       "bitbucket-pipelines",
+		RunID:          buildNumber,
+		Workflow:       os.Getenv("BITBUCKET_REPO_SLUG"),
+		Job:            os.Getenv("BITBUCKET_STEP_UUID"),
+		Actor:          os.Getenv("BITBUCKET_STEP_TRIGGERER_UUID"),
+		PullRequest:    pr,
+		PullRequestURL: prURL,
+		SourceBranch:   os.Getenv("BITBUCKET_BRANCH"),
+		TargetBranch:   os.Getenv("BITBUCKET_PR_DESTINATION_BRANCH"),
+		Commit:         os.Getenv("BITBUCKET_COMMIT"),
+		URL:            url,
+	}
+}
+
+func atlantis() *Info {
+	if os.Getenv("ATLANTIS_TERRAFORM_VERSION") == "" {
+		return nil
+	}
+	return &Info{
+		Provider:       "atlantis",
+		Workflow:       os.Getenv("PROJECT_NAME"),
+		Event:          os.Getenv("COMMAND_NAME"),
+		Actor:          firstEnv("USER_NAME", "PULL_AUTHOR"),
+		PullRequest:    os.Getenv("PULL_NUM"),
+		PullRequestURL: os.Getenv("PULL_URL"),
+		SourceBranch:   os.Getenv("HEAD_BRANCH_NAME"),
+		TargetBranch:   os.Getenv("BASE_BRANCH_NAME"),
+		Commit:         os.Getenv("HEAD_COMMIT"),
+		URL:            os.Getenv("PULL_URL"),
+	}
+}
+
+func spacelift() *Info {
+	runID := os.Getenv("TF_VAR_spacelift_run_id")
+	if runID == "" {
+		return nil
+	}
+	stack := os.Getenv("TF_VAR_spacelift_stack_id")
+	var url string
+	if account := os.Getenv("TF_VAR_spacelift_account_name"); account != "" {
+		url = "https://" + account + ".app.spacelift.io/stack/" + stack + "/run/" + runID
+	}
+	return &Info{
+		Provider:     "spacelift",
+		RunID:        runID,
+		Workflow:     stack,
+		Actor:        os.Getenv("TF_VAR_spacelift_run_trigger"),
+		SourceBranch: os.Getenv("TF_VAR_spacelift_commit_branch"),
+		Commit:       os.Getenv("TF_VAR_spacelift_commit_sha"),
+		URL:          url,
+	}
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func envInt(key string) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func joinURL(base, number string) string {
+	if number == "" {
+		return ""
+	}
+	return base + number
 }
@@ -1,10 +1,37 @@
 package ci
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
+var (
+	ciPrefixes = []string{
+		"GITHUB_", "GITLAB_", "CI_", "CIRCLE", "BUILDKITE", "BUILD_", "SYSTEM_",
+		"BITBUCKET_", "CHANGE_", "TF_VAR_spacelift_", _overridePrefix,
+	}
+	ciVars = []string{
+		"JENKINS_URL", "GIT_COMMIT", "BRANCH_NAME", "JOB_NAME", "STAGE_NAME", "TF_BUILD",
+		"ATLANTIS_TERRAFORM_VERSION", "BASE_BRANCH_NAME", "HEAD_BRANCH_NAME", "HEAD_COMMIT",
+		"PULL_NUM", "PULL_AUTHOR", "PULL_URL", "USER_NAME", "PROJECT_NAME", "COMMAND_NAME",
+	}
+)
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if slices.Contains(ciVars, key) || slices.ContainsFunc(ciPrefixes, func(p string) bool { return strings.HasPrefix(key, p) }) {
+			t.Setenv(key, "")
+		}
+	}
+}
+
 func TestDetect_GitHubActions(t *testing.T) {
+	clearEnv(t)
 	t.Setenv("GITHUB_ACTIONS", "true")
 	t.Setenv("GITHUB_RUN_ID", "12345")
 	t.Setenv("GITHUB_WORKFLOW", "CI")
@@ -26,9 +53,335 @@ func TestDetect_GitHubActions(t *testing.T) {
 	}
 }
 
+func TestDetect_Providers(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Info
+	}{
+		{
+			name: "github actions pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":     "true",
+				"GITHUB_RUN_ID":      "77",
+				"GITHUB_WORKFLOW":    "terraform",
+				"GITHUB_JOB":         "plan",
+				"GITHUB_ACTOR":       "octocat",
+				"GITHUB_REF":         "refs/pull/42/merge",
+				"GITHUB_SERVER_URL":  "https://github.com",
+				"GITHUB_REPOSITORY":  "acme/infra",
+				"GITHUB_RUN_ATTEMPT": "2",
+				"GITHUB_EVENT_NAME":  "pull_request",
+				"GITHUB_HEAD_REF":    "feature/vpc",
+				"GITHUB_BASE_REF":    "main",
+				"GITHUB_SHA":         "0123abcd",
+			},
+			want: Info{
+				Provider:       "github-actions",
+				RunID:          "77",
+				Workflow:       "terraform",
+				Job:            "plan",
+				Attempt:        2,
+				Event:          "pull_request",
+				Actor:          "octocat",
+				PullRequest:    "42",
+				PullRequestURL: "https://github.com/acme/infra/pull/42",
+				SourceBranch:   "feature/vpc",
+				TargetBranch:   "main",
+				Commit:         "0123abcd",
+				URL:            "https://github.com/acme/infra/actions/runs/77",
+			},
+		},
+		{
+			name: "gitlab ci merge request",
+			env: map[string]string{
+				"GITLAB_CI":                           "true",
+				"CI_PIPELINE_ID":                      "900",
+				"CI_JOB_NAME":                         "apply",
+				"GITLAB_USER_LOGIN":                   "alice",
+				"CI_MERGE_REQUEST_IID":                "17",
+				"CI_PIPELINE_URL":                     "https://gitlab.com/acme/infra/-/pipelines/900",
+				"CI_PIPELINE_SOURCE":                  "merge_request_event",
+				"CI_PROJECT_URL":                      "https://gitlab.com/acme/infra",
+				"CI_COMMIT_SHA":                       "feedbeef",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/db",
+				"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "main",
+			},
+			want: Info{
+				Provider:       "gitlab-ci",
+				RunID:          "900",
+				Workflow:       "apply",
+				Job:            "apply",
+				Event:          "merge_request_event",
+				Actor:          "alice",
+				PullRequest:    "17",
+				PullRequestURL: "https://gitlab.com/acme/infra/-/merge_requests/17",
+				SourceBranch:   "feature/db",
+				TargetBranch:   "main",
+				Commit:         "feedbeef",
+				URL:            "https://gitlab.com/acme/infra/-/pipelines/900",
+			},
+		},
+		{
+			name: "jenkins",
+			env: map[string]string{
+				"JENKINS_URL":   "https://jenkins.example.com/",
+				"BUILD_NUMBER":  "311",
+				"JOB_NAME":      "infra/PR-8",
+				"STAGE_NAME":    "Plan",
+				"BUILD_USER_ID": "",
+				"CHANGE_AUTHOR": "bob",
+				"CHANGE_ID":     "8",
+				"BUILD_URL":     "https://jenkins.example.com/job/infra/job/PR-8/311/",
+			},
+			want: Info{
+				Provider:    "jenkins",
+				RunID:       "311",
+				Workflow:    "infra/PR-8",
+				Job:         "Plan",
+				Actor:       "bob",
+				PullRequest: "8",
+				URL:         "https://jenkins.example.com/job/infra/job/PR-8/311/",
+			},
+		},
+		{
+			name: "circleci",
+			env: map[string]string{
+				"CIRCLECI":                "true",
+				"CIRCLE_WORKFLOW_ID":      "wf-1",
+				"CIRCLE_PROJECT_REPONAME": "infra",
+				"CIRCLE_JOB":              "plan",
+				"CIRCLE_USERNAME":         "carol",
+				"CIRCLE_PR_NUMBER":        "",
+				"CIRCLE_PULL_REQUEST":     "https://github.com/acme/infra/pull/5",
+				"CIRCLE_BUILD_URL":        "https://circleci.com/gh/acme/infra/12",
+			},
+			want: Info{
+				Provider:       "circleci",
+				RunID:          "wf-1",
+				Workflow:       "infra",
+				Job:            "plan",
+				Actor:          "carol",
+				PullRequest:    "5",
+				PullRequestURL: "https://github.com/acme/infra/pull/5",
+				URL:            "https://circleci.com/gh/acme/infra/12",
+			},
+		},
+		{
+			name: "buildkite without pull request",
+			env: map[string]string{
+				"BUILDKITE":                     "true",
+				"BUILDKITE_BUILD_NUMBER":        "64",
+				"BUILDKITE_PIPELINE_SLUG":       "infra",
+				"BUILDKITE_LABEL":               ":terraform: plan",
+				"BUILDKITE_BUILD_CREATOR_EMAIL": "dave@example.com",
+				"BUILDKITE_PULL_REQUEST":        "false",
+				"BUILDKITE_BUILD_URL":           "https://buildkite.com/acme/infra/builds/64",
+				"BUILDKITE_RETRY_COUNT":         "1",
+				"BUILDKITE_SOURCE":              "webhook",
+				"BUILDKITE_BRANCH":              "main",
+			},
+			want: Info{
+				Provider:     "buildkite",
+				RunID:        "64",
+				Workflow:     "infra",
+				Job:          ":terraform: plan",
+				Attempt:      2,
+				Event:        "webhook",
+				Actor:        "dave@example.com",
+				SourceBranch: "main",
+				URL:          "https://buildkite.com/acme/infra/builds/64",
+			},
+		},
+		{
+			name: "azure pipelines",
+			env: map[string]string{
+				"TF_BUILD":                             "True",
+				"BUILD_BUILDID":                        "1001",
+				"BUILD_DEFINITIONNAME":                 "infra-ci",
+				"SYSTEM_JOBDISPLAYNAME":                "Terraform Plan",
+				"BUILD_REQUESTEDFOR":                   "Erin",
+				"SYSTEM_PULLREQUEST_PULLREQUESTNUMBER": "",
+				"SYSTEM_PULLREQUEST_PULLREQUESTID":     "23",
+				"SYSTEM_COLLECTIONURI":                 "https://dev.azure.com/acme/",
+				"SYSTEM_TEAMPROJECT":                   "platform",
+				"SYSTEM_JOBATTEMPT":                    "1",
+				"BUILD_REASON":                         "PullRequest",
+				"SYSTEM_PULLREQUEST_SOURCEBRANCH":      "refs/heads/feature/dns",
+				"SYSTEM_PULLREQUEST_TARGETBRANCH":      "refs/heads/main",
+				"BUILD_SOURCEVERSION":                  "cafe1234",
+			},
+			want: Info{
+				Provider:     "azure-pipelines",
+				RunID:        "1001",
+				Workflow:     "infra-ci",
+				Job:          "Terraform Plan",
+				Attempt:      1,
+				Event:        "PullRequest",
+				Actor:        "Erin",
+				PullRequest:  "23",
+				SourceBranch: "feature/dns",
+				TargetBranch: "main",
+				Commit:       "cafe1234",
+				URL:          "https://dev.azure.com/acme/platform/_build/results?buildId=1001",
+			},
+		},
+		{
+			name: "bitbucket pipelines",
+			env: map[string]string{
+				"BITBUCKET_BUILD_NUMBER":        "55",
+				"BITBUCKET_REPO_SLUG":           "infra",
+				"BITBUCKET_REPO_FULL_NAME":      "acme/infra",
+				"BITBUCKET_STEP_UUID":           "{step-1}",
+				"BITBUCKET_STEP_TRIGGERER_UUID": "{user-1}",
+				"BITBUCKET_PR_ID":               "9",
+			},
+			want: Info{
+				Provider:       "bitbucket-pipelines",
+				RunID:          "55",
+				Workflow:       "infra",
+				Job:            "{step-1}",
+				Actor:          "{user-1}",
+				PullRequest:    "9",
+				PullRequestURL: "https://bitbucket.org/acme/infra/pull-requests/9",
+				URL:            "https://bitbucket.org/acme/infra/pipelines/results/55",
+			},
+		},
+		{
+			name: "atlantis",
+			env: map[string]string{
+				"ATLANTIS_TERRAFORM_VERSION": "1.9.0",
+				"PROJECT_NAME":               "prod-vpc",
+				"USER_NAME":                  "frank",
+				"PULL_NUM":                   "101",
+				"PULL_URL":                   "https://github.com/acme/infra/pull/101",
+				"COMMAND_NAME":               "apply",
+				"HEAD_BRANCH_NAME":           "feature/vpc",
+				"BASE_BRANCH_NAME":           "main",
+				"HEAD_COMMIT":                "abcdef01",
+			},
+			want: Info{
+				Provider:       "atlantis",
+				Workflow:       "prod-vpc",
+				Event:          "apply",
+				Actor:          "frank",
+				PullRequest:    "101",
+				PullRequestURL: "https://github.com/acme/infra/pull/101",
+				SourceBranch:   "feature/vpc",
+				TargetBranch:   "main",
+				Commit:         "abcdef01",
+				URL:            "https://github.com/acme/infra/pull/101",
+			},
+		},
+		{
+			name: "spacelift",
+			env: map[string]string{
+				"TF_VAR_spacelift_run_id":       "01HRUN",
+				"TF_VAR_spacelift_stack_id":     "prod-vpc",
+				"TF_VAR_spacelift_account_name": "acme",
+				"TF_VAR_spacelift_run_trigger":  "user/grace",
+			},
+			want: Info{
+				Provider: "spacelift",
+				RunID:    "01HRUN",
+				Workflow: "prod-vpc",
+				Actor:    "user/grace",
+				URL:      "https://acme.app.spacelift.io/stack/prod-vpc/run/01HRUN",
+			},
+		},
+		{
+			name: "atlantis wins over the ci it runs in",
+			env: map[string]string{
+				"ATLANTIS_TERRAFORM_VERSION": "1.9.0",
+				"PULL_NUM":                   "3",
+				"USER_NAME":                  "",
+				"PULL_AUTHOR":                "heidi",
+				"PROJECT_NAME":               "",
+				"PULL_URL":                   "",
+				"JENKINS_URL":                "https://jenkins.example.com/",
+			},
+			want: Info{
+				Provider:    "atlantis",
+				Actor:       "heidi",
+				PullRequest: "3",
+			},
+		},
+		{
+			name: "generic override",
+			env: map[string]string{
+				"TFJOURNAL_CI_PROVIDER": "drone",
+				"TFJOURNAL_CI_RUN_ID":   "12",
+				"TFJOURNAL_CI_WORKFLOW": "deploy",
+				"TFJOURNAL_CI_JOB":      "apply",
+				"TFJOURNAL_CI_ACTOR":    "ivan",
+				"TFJOURNAL_CI_PR":       "4",
+				"TFJOURNAL_CI_URL":      "https://drone.example.com/acme/infra/12",
+				"TFJOURNAL_CI_ATTEMPT":  "3",
+				"TFJOURNAL_CI_EVENT":    "push",
+			},
+			want: Info{
+				Provider:    "drone",
+				RunID:       "12",
+				Workflow:    "deploy",
+				Job:         "apply",
+				Attempt:     3,
+				Event:       "push",
+				Actor:       "ivan",
+				PullRequest: "4",
+				URL:         "https://drone.example.com/acme/infra/12",
+			},
+		},
+		{
+			name: "override on top of detected provider",
+			env: map[string]string{
+				"GITLAB_CI":            "true",
+				"CI_PIPELINE_ID":       "900",
+				"CI_JOB_NAME":          "apply",
+				"GITLAB_USER_LOGIN":    "ci-bot",
+				"CI_MERGE_REQUEST_IID": "",
+				"CI_PIPELINE_URL":      "https://gitlab.com/acme/infra/-/pipelines/900",
+				"TFJOURNAL_CI_ACTOR":   "judy",
+			},
+			want: Info{
+				Provider: "gitlab-ci",
+				RunID:    "900",
+				Workflow: "apply",
+				Job:      "apply",
+				Actor:    "judy",
+				URL:      "https://gitlab.com/acme/infra/-/pipelines/900",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			info := Detect()
+			if info == nil {
+				t.Fatal("expected non-nil info")
+			}
+			if *info != tt.want {
+				t.Errorf("Detect() = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect_OverrideWithoutProvider(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TFJOURNAL_CI_ACTOR", "someone")
+
+	if info := Detect(); info != nil {
+		t.Errorf("expected nil info without TFJOURNAL_CI_PROVIDER, got %+v", info)
+	}
+}
+
 func TestDetect_NoCI(t *testing.T) {
-	t.Setenv("GITHUB_ACTIONS", "")
-	t.Setenv("GITLAB_CI", "")
+	clearEnv(t)
 
 	info := Detect()
 	if info != nil {
@@ -37,8 +390,7 @@ func TestDetect_NoCI(t *testing.T) {
 }
 
 func TestIsCI(t *testing.T) {
-	t.Setenv("GITHUB_ACTIONS", "")
-	t.Setenv("GITLAB_CI", "")
+	clearEnv(t)
 
 	if IsCI() {
 		t.Error("expected IsCI() = false when not in CI")
@@ -50,3 +402,28 @@ func TestIsCI(t *testing.T) {
 		t.Error("expected IsCI() = true when in CI")
 	}
 }
+
+func TestDetect_GitHubPullRequestTarget(t *testing.T) {
+	clearEnv(t)
+	event := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(event, []byte(`{"action": "opened", "number": 7, "pull_request": {"number": 7}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "acme/infra")
+	t.Setenv("GITHUB_EVENT_NAME", "pull_request_target")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_HEAD_REF", "feature/vpc")
+	t.Setenv("GITHUB_EVENT_PATH", event)
+
+	info := Detect()
+	if info == nil || info.PullRequest != "7" || info.PullRequestURL != "https://github.com/acme/infra/pull/7" {
+		t.Fatalf("Detect() = %+v, want pull request 7", info)
+	}
+
+	t.Setenv("GITHUB_HEAD_REF", "")
+	if info := Detect(); info == nil || info.PullRequest != "" {
+		t.Errorf("Detect() on push = %+v, want no pull request", info)
+	}
+}
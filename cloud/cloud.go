@@ -0,0 +1,162 @@
+package cloud
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const _tfVarPrefix = "TF_VAR_"
+
+var DefaultEnv = []string{
+	"AWS_PROFILE",
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+	"GOOGLE_PROJECT",
+	"GOOGLE_CLOUD_PROJECT",
+	"CLOUDSDK_CORE_PROJECT",
+	"GOOGLE_REGION",
+	"ARM_SUBSCRIPTION_ID",
+	"ARM_TENANT_ID",
+	"AZURE_SUBSCRIPTION_ID",
+}
+
+var secretMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "CREDENTIAL", "PRIVATE"}
+
+type Config struct {
+	Env        []string `json:"env,omitempty"`
+	TFVarNames bool     `json:"tf_var_names,omitempty"`
+}
+
+func Detect(cfg Config) *run.CloudInfo {
+	info := &run.CloudInfo{
+		Env:        captureEnv(os.Environ(), cfg.Env),
+		TFVars:     tfVarNames(os.Environ(), cfg.TFVarNames),
+		AWSProfile: os.Getenv("AWS_PROFILE"),
+	}
+	info.AWSRoleARN, info.AWSAccountID = awsIdentity()
+
+	if len(info.Env) == 0 && len(info.TFVars) == 0 && info.AWSRoleARN == "" && info.AWSAccountID == "" && info.AWSProfile == "" {
+		return nil
+	}
+	return info
+}
+
+func captureEnv(environ, allow []string) map[string]string {
+	env := map[string]string{}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || value == "" || strings.HasPrefix(name, _tfVarPrefix) || isSecret(name) {
+			continue
+		}
+		for _, pattern := range allow {
+			if matched, _ := path.Match(pattern, name); matched {
+				env[name] = value
+				break
+			}
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
+
+func tfVarNames(environ []string, enabled bool) []string {
+	if !enabled {
+		return nil
+	}
+	var names []string
+	for _, kv := range environ {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, _tfVarPrefix) {
+			names = append(names, strings.TrimPrefix(name, _tfVarPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isSecret(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range secretMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return strings.HasSuffix(upper, "_KEY") || strings.HasSuffix(upper, "_KEY_ID")
+}
+
+func awsIdentity() (roleARN, accountID string) {
+	roleARN = os.Getenv("AWS_ROLE_ARN")
+	if roleARN == "" {
+		profile := readAWSProfile(awsConfigPath(), os.Getenv("AWS_PROFILE"))
+		roleARN = profile["role_arn"]
+		accountID = profile["sso_account_id"]
+	}
+	if id := AccountFromARN(roleARN); id != "" {
+		accountID = id
+	}
+	return roleARN, accountID
+}
+
+func AccountFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+func awsConfigPath() string {
+	if p := os.Getenv("AWS_CONFIG_FILE"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", "config")
+}
+
+func readAWSProfile(configPath, profile string) map[string]string {
+	if configPath == "" {
+		return nil
+	}
+	f, err := os.Open(configPath)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	section := "profile " + profile
+	if profile == "" || profile == "default" {
+		section = "default"
+	}
+
+	values := map[string]string{}
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			inSection = name == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
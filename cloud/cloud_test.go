@@ -0,0 +1,132 @@
+package cloud
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCaptureEnv(t *testing.T) {
+	environ := []string{
+		"AWS_PROFILE=prod-admin",
+		"AWS_REGION=eu-west-1",
+		"AWS_SECRET_ACCESS_KEY=hunter2",
+		"AWS_SESSION_TOKEN=abc",
+		"ARM_SUBSCRIPTION_ID=0000-1111",
+		"ARM_CLIENT_SECRET=hunter2",
+		"GOOGLE_PROJECT=",
+		"TF_VAR_db_password=hunter2",
+		"HOME=/root",
+	}
+
+	got := captureEnv(environ, []string{"AWS_*", "ARM_*", "GOOGLE_PROJECT", "TF_VAR_*"})
+	want := map[string]string{
+		"AWS_PROFILE":         "prod-admin",
+		"AWS_REGION":          "eu-west-1",
+		"ARM_SUBSCRIPTION_ID": "0000-1111",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("captureEnv() = %v, want %v", got, want)
+	}
+
+	if got := captureEnv(environ, nil); got != nil {
+		t.Errorf("captureEnv() with empty allow-list = %v, want nil", got)
+	}
+}
+
+func TestTFVarNames(t *testing.T) {
+	environ := []string{"TF_VAR_region=us-east-1", "PATH=/bin", "TF_VAR_db_password=hunter2"}
+
+	if got := tfVarNames(environ, true); !slices.Equal(got, []string{"db_password", "region"}) {
+		t.Errorf("tfVarNames() = %v", got)
+	}
+	if got := tfVarNames(environ, false); got != nil {
+		t.Errorf("tfVarNames(disabled) = %v, want nil", got)
+	}
+}
+
+func TestAWSIdentity(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	content := `[default]
+region = us-east-1
+
+[profile prod-admin]
+role_arn = arn:aws:iam::123456789012:role/Admin
+source_profile = default
+
+[profile  sandbox]
+sso_account_id = 210987654321
+sso_role_name = Developer
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_ROLE_ARN", "")
+
+	tests := []struct {
+		profile string
+		role    string
+		account string
+	}{
+		{"prod-admin", "arn:aws:iam::123456789012:role/Admin", "123456789012"},
+		{"sandbox", "", "210987654321"},
+		{"", "", ""},
+		{"missing", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			t.Setenv("AWS_PROFILE", tt.profile)
+			role, account := awsIdentity()
+			if role != tt.role || account != tt.account {
+				t.Errorf("awsIdentity() = %q, %q, want %q, %q", role, account, tt.role, tt.account)
+			}
+		})
+	}
+
+	t.Run("web identity", func(t *testing.T) {
+		t.Setenv("AWS_PROFILE", "sandbox")
+		t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::555555555555:role/ci")
+		role, account := awsIdentity()
+		if role != "arn:aws:iam::555555555555:role/ci" || account != "555555555555" {
+			t.Errorf("awsIdentity() = %q, %q", role, account)
+		}
+	})
+}
+
+func TestDetect(t *testing.T) {
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if info := Detect(Config{Env: DefaultEnv, TFVarNames: true}); info != nil {
+		t.Fatalf("Detect() in empty environment = %+v, want nil", info)
+	}
+
+	t.Setenv("GOOGLE_PROJECT", "acme-prod")
+	t.Setenv("TF_VAR_region", "us-central1")
+	info := Detect(Config{Env: DefaultEnv, TFVarNames: true})
+	if info == nil || info.Env["GOOGLE_PROJECT"] != "acme-prod" || !slices.Equal(info.TFVars, []string{"region"}) {
+		t.Fatalf("Detect() = %+v", info)
+	}
+	if info.Account() != "acme-prod" || !info.Matches("acme-prod") || info.Matches("us-central1") {
+		t.Errorf("Account() = %q", info.Account())
+	}
+
+	t.Setenv("GOOGLE_PROJECT", "")
+	t.Setenv("AWS_PROFILE", "prod-admin")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	info = Detect(Config{Env: DefaultEnv})
+	if info == nil || info.AWSProfile != "prod-admin" {
+		t.Fatalf("Detect() = %+v", info)
+	}
+	if info.Account() != "" || info.Matches("prod-admin") || info.Matches("eu-west-1") {
+		t.Errorf("profile or region reported as account: Account() = %q", info.Account())
+	}
+}
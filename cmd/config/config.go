@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+)
+
+const (
+	_maxValueWidth = 60
+	_masked        = "****"
+)
+
+var jsonOutput bool
+
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect tfjournal configuration",
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long: `Show the effective configuration and where each value came from.
+
+Values are merged from built-in defaults, the user config file
+(~/.config/tfjournal/config.yaml), the nearest .tfjournal.yaml found by
+walking up from the current directory, and TFJOURNAL_* environment
+variables, in that order.`,
+	Args: cobra.NoArgs,
+	RunE: runShow,
+}
+
+func init() {
+	showCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	Cmd.AddCommand(showCmd)
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	entries := cfg.Entries()
+	for i := range entries {
+		entries[i].Value = mask(entries[i].Key, entries[i].Value)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{
+			"files":   cfg.Files,
+			"entries": entries,
+		})
+	}
+
+	if len(cfg.Files) == 0 {
+		fmt.Printf("config files: none (looked for %s and %s)\n\n", config.UserPath(), config.FileName)
+	} else {
+		fmt.Println("config files:")
+		for _, f := range cfg.Files {
+			fmt.Printf("  %s\n", f)
+		}
+		fmt.Println()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "key\tvalue\tsource")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", e.Key, formatValue(e.Value), e.Source)
+	}
+	return w.Flush()
+}
+
+func isSecret(key string) bool {
+	lower := strings.ToLower(key)
+	return strings.Contains(lower, ".headers.") ||
+		strings.HasSuffix(lower, "password") ||
+		strings.HasSuffix(lower, "token")
+}
+
+func mask(key string, v any) any {
+	if isSecret(key) {
+		return _masked
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return v
+	}
+	if isWebhook(key) {
+		return u.Scheme + "://" + u.Host + "/" + _masked
+	}
+	clean := *u
+	clean.User, clean.RawQuery, clean.Fragment = nil, "", ""
+	out := clean.String()
+	if u.User != nil {
+		out = strings.Replace(out, "://", "://"+_masked+"@", 1)
+	}
+	if u.RawQuery != "" {
+		out += "?" + _masked
+	}
+	return out
+}
+
+func isWebhook(key string) bool {
+	parts := strings.Split(key, ".")
+	return len(parts) == 4 && parts[0] == "notifications" && parts[1] == "channels" && parts[3] == "url"
+}
+
+func formatValue(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		s = string(data)
+	}
+	if len(s) > _maxValueWidth {
+		s = s[:_maxValueWidth-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/compare"
+	"github.com/Owloops/tfjournal/config"
+)
+
+var (
+	contextLines int
+	jsonOutput   bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "diff <run-a> <run-b>",
+	Short: "Compare two runs",
+	Long: `Compare two recorded runs, typically of the same workspace: metadata
+(git commit, user, versions), change counts, per-resource actions and
+durations, and a unified diff of the captured outputs.
+
+Timestamps, durations, run IDs, UUIDs and resource IDs are normalized
+before diffing outputs so that only meaningful differences remain.
+
+Example:
+  tfjournal diff run_abc123 run_def456
+  tfjournal diff run_abc123 run_def456 -U 10
+  tfjournal diff run_abc123 run_def456 --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	Cmd.Flags().IntVarP(&contextLines, "context", "U", compare.DefaultContext, "Lines of context in the output diff")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	res, err := compare.Load(store, args[0], args[1], contextLines)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	return res.Write(os.Stdout)
+}
@@ -0,0 +1,171 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/drift"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+var (
+	since       string
+	driftedOnly bool
+	jsonOutput  bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "drift [workspace-pattern]",
+	Short: "Report drift from scheduled plans",
+	Long: `Report which workspaces have drifted, based on runs recorded from
+'plan -detailed-exitcode'. Exit code 2 is recorded as drift and exit
+code 0 as clean; the latest check decides the workspace status.
+
+Example:
+  tfjournal -- terraform plan -detailed-exitcode
+  tfjournal drift
+  tfjournal drift --drifted
+  tfjournal drift production/* --since 30d --json`,
+	RunE: runDrift,
+}
+
+func init() {
+	Cmd.Flags().StringVar(&since, "since", "", "Only consider checks since duration (e.g., 7d, 24h)")
+	Cmd.Flags().BoolVar(&driftedOnly, "drifted", false, "Show only drifted workspaces")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	opts := storage.ListOptions{Action: "plan"}
+
+	if len(args) > 0 {
+		opts.Workspace = args[0]
+		if !strings.Contains(opts.Workspace, "%") {
+			opts.Workspace = strings.ReplaceAll(opts.Workspace, "*", "%")
+		}
+	}
+
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	runs, err := store.ListRuns(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	report := drift.Compute(runs)
+	if driftedOnly {
+		var drifted []drift.Workspace
+		for _, ws := range report.Workspaces {
+			if ws.Status == run.StatusDrift {
+				drifted = append(drifted, ws)
+			}
+		}
+		report.Workspaces = drifted
+		report.Clean = 0
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Workspaces) == 0 {
+		fmt.Println("No drift checks found. Record one with 'tfjournal -- terraform plan -detailed-exitcode'.")
+		return nil
+	}
+
+	printReport(report)
+	return nil
+}
+
+func printReport(report *drift.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "workspace\tstatus\tsince\tlast checked\tchecks\tchanges\trun")
+	for _, ws := range report.Workspaces {
+		status := "✓ clean"
+		driftSince := "-"
+		changes := "-"
+		if ws.Status == run.StatusDrift {
+			status = "⚠ drift"
+			driftSince = fmt.Sprintf("%s (%s)", ws.Since.Format("2006-01-02 15:04"), age(ws.Since))
+		}
+		if ws.Changes != nil {
+			changes = fmt.Sprintf("+%d ~%d -%d", ws.Changes.Add, ws.Changes.Change, ws.Changes.Destroy)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			ws.Workspace,
+			status,
+			driftSince,
+			ws.LastChecked.Format("2006-01-02 15:04"),
+			ws.Checks,
+			changes,
+			ws.RunID,
+		)
+	}
+	_ = w.Flush()
+
+	for _, ws := range report.Workspaces {
+		if len(ws.Resources) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", ws.Workspace)
+		for _, p := range ws.Resources {
+			fmt.Printf("  %s %s\n", actionIcon(p.Action), p.Address)
+		}
+	}
+
+	fmt.Printf("\n%d drifted, %d clean\n", report.Drifted, report.Clean)
+}
+
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
+
+func actionIcon(action string) string {
+	switch action {
+	case "create":
+		return "+"
+	case "update":
+		return "~"
+	case "destroy":
+		return "-"
+	case "replace":
+		return "±"
+	default:
+		return "?"
+	}
+}
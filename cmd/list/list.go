@@ -10,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/Owloops/tfjournal/config"
 	"github.com/Owloops/tfjournal/run"
 	"github.com/Owloops/tfjournal/storage"
 )
@@ -21,6 +22,11 @@ var (
 	program    string
 	action     string
 	branch     string
+	pr         string
+	tfVersion  string
+	provider   string
+	stateID    string
+	account    string
 	hasChanges bool
 	limit      int
 	jsonOutput bool
@@ -38,6 +44,11 @@ Example:
   tfjournal list --program tofu
   tfjournal list --action apply
   tfjournal list --branch main
+  tfjournal list --pr 123
+  tfjournal list --tf-version 1.7
+  tfjournal list --provider aws@5.31
+  tfjournal list --state s3://acme-state/prod/vpc.tfstate
+  tfjournal list --account 123456789012
   tfjournal list --has-changes
   tfjournal list production/*`,
 	RunE: runList,
@@ -46,17 +57,27 @@ Example:
 func init() {
 	Cmd.Flags().StringVar(&since, "since", "", "Show runs since duration (e.g., 7d, 24h)")
 	Cmd.Flags().StringVar(&user, "user", "", "Filter by user")
-	Cmd.Flags().StringVar(&status, "status", "", "Filter by status (success, failed)")
+	Cmd.Flags().StringVar(&status, "status", "", "Filter by status (success, failed, drift, clean)")
 	Cmd.Flags().StringVar(&program, "program", "", "Filter by program (terraform, tofu, terragrunt)")
 	Cmd.Flags().StringVar(&action, "action", "", "Filter by action (plan, apply, destroy, import, taint)")
 	Cmd.Flags().StringVar(&branch, "branch", "", "Filter by git branch")
+	Cmd.Flags().StringVar(&pr, "pr", "", "Filter by CI pull/merge request number")
+	Cmd.Flags().StringVar(&tfVersion, "tf-version", "", "Filter by terraform/tofu version prefix (e.g., 1.7)")
+	Cmd.Flags().StringVar(&provider, "provider", "", "Filter by provider, optionally with a version prefix (e.g., aws@5.31)")
+	Cmd.Flags().StringVar(&stateID, "state", "", "Filter by state identity (e.g., s3://bucket/key, supports *)")
+	Cmd.Flags().StringVar(&account, "account", "", "Filter by cloud account, role ARN, project or subscription")
 	Cmd.Flags().BoolVar(&hasChanges, "has-changes", false, "Show only runs with actual changes")
 	Cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of runs to show")
 	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	store, err := storage.NewFromEnv()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
 	if err != nil {
 		return fmt.Errorf("failed to open storage: %w", err)
 	}
@@ -94,6 +115,21 @@ func runList(cmd *cobra.Command, args []string) error {
 	if branch != "" {
 		opts.Branch = branch
 	}
+	if pr != "" {
+		opts.PR = pr
+	}
+	if tfVersion != "" {
+		opts.Version = tfVersion
+	}
+	if provider != "" {
+		opts.Provider = provider
+	}
+	if stateID != "" {
+		opts.State = stateID
+	}
+	if account != "" {
+		opts.Account = account
+	}
 	if hasChanges {
 		opts.HasChanges = true
 	}
@@ -128,6 +164,9 @@ func printTable(runs []*run.Run) {
 
 	for _, r := range runs {
 		status := statusIcon(r.Status)
+		if r.Attempt > 1 {
+			status += fmt.Sprintf(" (attempt %d)", r.Attempt)
+		}
 		changes := r.ChangeSummary()
 		gitInfo := ""
 		if r.Git != nil {
@@ -157,6 +196,12 @@ func statusIcon(s run.Status) string {
 		return "● running"
 	case run.StatusCanceled:
 		return "○ canceled"
+	case run.StatusBlocked:
+		return "⊘ blocked"
+	case run.StatusDrift:
+		return "⚠ drift"
+	case run.StatusClean:
+		return "✓ clean"
 	default:
 		return string(s)
 	}
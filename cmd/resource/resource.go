@@ -0,0 +1,150 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+var (
+	since      string
+	user       string
+	status     string
+	workspace  string
+	limit      int
+	jsonOutput bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "resource <address>",
+	Short: "Show every run that touched a resource",
+	Long: `Show the history of a resource address across recorded runs, with the
+action taken, its duration and status, and who ran it.
+
+Planned-only changes (plans that were never applied) are marked as planned.
+The address may contain * wildcards.
+
+Example:
+  tfjournal resource aws_instance.web
+  tfjournal resource 'module.vpc.aws_subnet.private[*]'
+  tfjournal resource aws_db_instance.main --since 30d --status failed
+  tfjournal resource aws_instance.web -w production/* --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResource,
+}
+
+func init() {
+	Cmd.Flags().StringVar(&since, "since", "", "Show runs since duration (e.g., 7d, 24h)")
+	Cmd.Flags().StringVar(&user, "user", "", "Filter by user")
+	Cmd.Flags().StringVar(&status, "status", "", "Filter by run status (success, failed)")
+	Cmd.Flags().StringVarP(&workspace, "workspace", "w", "", "Filter by workspace pattern")
+	Cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of events to show")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runResource(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	opts := storage.ListOptions{
+		Limit: limit,
+		User:  user,
+	}
+
+	if workspace != "" {
+		opts.Workspace = workspace
+		if !strings.Contains(opts.Workspace, "%") {
+			opts.Workspace = strings.ReplaceAll(opts.Workspace, "*", "%")
+		}
+	}
+
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	if status != "" {
+		opts.Status = run.Status(status)
+	}
+
+	events, err := store.ResourceHistory(args[0], opts)
+	if err != nil {
+		return fmt.Errorf("failed to load resource history: %w", err)
+	}
+
+	if len(events) == 0 {
+		if jsonOutput {
+			fmt.Println("[]")
+		} else {
+			fmt.Println("No runs found.")
+		}
+		return nil
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	}
+
+	printTable(events, strings.Contains(args[0], "*"))
+	return nil
+}
+
+func printTable(events []storage.ResourceEvent, showAddress bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "timestamp\trun\tworkspace\taction\tstatus\tduration\tuser"
+	if showAddress {
+		header = "address\t" + header
+	}
+	_, _ = fmt.Fprintln(w, header)
+
+	for _, e := range events {
+		action := e.Action
+		if e.Planned {
+			action += " (planned)"
+		}
+		status := e.Status
+		if status == "" {
+			status = string(e.RunStatus)
+		}
+		duration := "-"
+		if e.DurationMs > 0 {
+			duration = (time.Duration(e.DurationMs) * time.Millisecond).Round(100 * time.Millisecond).String()
+		}
+
+		if showAddress {
+			_, _ = fmt.Fprintf(w, "%s\t", e.Address)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Timestamp.Format("2006-01-02 15:04"),
+			e.RunID,
+			e.Workspace,
+			action,
+			status,
+			duration,
+			e.User,
+		)
+	}
+	_ = w.Flush()
+}
@@ -7,9 +7,18 @@ import (
 
 	"github.com/spf13/cobra"
 
+	configcmd "github.com/Owloops/tfjournal/cmd/config"
+	"github.com/Owloops/tfjournal/cmd/diff"
+	"github.com/Owloops/tfjournal/cmd/drift"
 	"github.com/Owloops/tfjournal/cmd/list"
+	"github.com/Owloops/tfjournal/cmd/resource"
+	"github.com/Owloops/tfjournal/cmd/search"
 	"github.com/Owloops/tfjournal/cmd/serve"
 	"github.com/Owloops/tfjournal/cmd/show"
+	"github.com/Owloops/tfjournal/cmd/stats"
+	"github.com/Owloops/tfjournal/cmd/trends"
+	workspacecmd "github.com/Owloops/tfjournal/cmd/workspace"
+	"github.com/Owloops/tfjournal/config"
 	"github.com/Owloops/tfjournal/recorder"
 	"github.com/Owloops/tfjournal/run"
 	"github.com/Owloops/tfjournal/storage"
@@ -27,6 +36,9 @@ var (
 	programFilter string
 	branchFilter  string
 	hasChanges    bool
+	approve       string
+	retryRun      bool
+	maxAttempts   int
 )
 
 var rootCmd = &cobra.Command{
@@ -39,8 +51,17 @@ Usage:
   tfjournal                             Interactive terminal UI
   tfjournal -- terraform apply          Record a terraform run
   tfjournal -w prod -- tofu plan        Record with workspace name
+  tfjournal --retry -- terraform apply  Retry transient failures (state lock, throttling, 5xx)
   tfjournal list                        List recorded runs
   tfjournal show <run-id>               Show run details
+  tfjournal diff <run-a> <run-b>        Compare two runs
+  tfjournal stats --by workspace        Aggregate run statistics
+  tfjournal resource <address>          Show every run that touched a resource
+  tfjournal search "<query>"            Search captured run output
+  tfjournal trends                      Per-resource duration trends and regressions
+  tfjournal drift                       Report drift from scheduled plans
+  tfjournal config show                 Show the effective configuration
+  tfjournal workspace rewrite           Apply workspace naming rules to runs
 
 It captures timestamps, git context, change summaries, and resource-level
 events without modifying your existing workflow.`,
@@ -53,15 +74,26 @@ func init() {
 	rootCmd.Flags().StringVarP(&workspace, "workspace", "w", "", "Workspace name (default: auto-detected)")
 	rootCmd.Flags().StringVar(&since, "since", "", "Show runs since duration (e.g., 7d, 24h)")
 	rootCmd.Flags().IntVarP(&limit, "limit", "n", 100, "Maximum number of runs to show (0 for all)")
-	rootCmd.Flags().StringVar(&statusFilter, "status", "", "Filter by status (success, failed)")
+	rootCmd.Flags().StringVar(&statusFilter, "status", "", "Filter by status (success, failed, drift, clean)")
 	rootCmd.Flags().StringVar(&userFilter, "user", "", "Filter by user")
 	rootCmd.Flags().StringVar(&programFilter, "program", "", "Filter by program (terraform, tofu, terragrunt)")
 	rootCmd.Flags().StringVar(&branchFilter, "branch", "", "Filter by git branch")
 	rootCmd.Flags().BoolVar(&hasChanges, "has-changes", false, "Show only runs with actual changes")
+	rootCmd.Flags().StringVar(&approve, "approve", "", "Approval token for a gated apply (also TFJOURNAL_APPROVE)")
+	rootCmd.Flags().BoolVar(&retryRun, "retry", false, "Re-run the command when it fails with a transient error")
+	rootCmd.Flags().IntVar(&maxAttempts, "max-attempts", 0, "Maximum attempts including the first; above 1 implies --retry (default: retry.attempts or 3)")
 
 	rootCmd.AddCommand(list.Cmd)
 	rootCmd.AddCommand(show.Cmd)
+	rootCmd.AddCommand(diff.Cmd)
 	rootCmd.AddCommand(serve.Cmd)
+	rootCmd.AddCommand(stats.Cmd)
+	rootCmd.AddCommand(resource.Cmd)
+	rootCmd.AddCommand(search.Cmd)
+	rootCmd.AddCommand(drift.Cmd)
+	rootCmd.AddCommand(trends.Cmd)
+	rootCmd.AddCommand(configcmd.Cmd)
+	rootCmd.AddCommand(workspacecmd.Cmd)
 }
 
 func Execute() error {
@@ -74,13 +106,36 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return runTUI()
 	}
+	if cmd.Flags().Changed("max-attempts") && maxAttempts < 1 {
+		return fmt.Errorf("--max-attempts must be at least 1, got %d", maxAttempts)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
 
-	store, err := storage.NewFromEnv()
+	store, err := cfg.OpenStore()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	result, err := recorder.Record(store, workspace, args)
+	if approve == "" {
+		approve = os.Getenv("TFJOURNAL_APPROVE")
+	}
+
+	opts := recorder.Options{Config: cfg, Workspace: workspace, Approve: approve}
+	if retryRun || maxAttempts > 1 {
+		if maxAttempts > 0 {
+			cfg.Retry.Attempts = maxAttempts
+		}
+		if opts.Retry, err = cfg.Retrier(); err != nil {
+			_ = store.Close()
+			return err
+		}
+	}
+
+	result, err := recorder.Record(store, opts, args)
 	if err != nil {
 		_ = store.Close()
 		return err
@@ -94,7 +149,12 @@ func runRoot(cmd *cobra.Command, args []string) error {
 }
 
 func runTUI() error {
-	store, err := storage.NewFromEnv()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
 	if err != nil {
 		return fmt.Errorf("failed to open storage: %w", err)
 	}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+var (
+	since      string
+	user       string
+	status     string
+	workspace  string
+	limit      int
+	jsonOutput bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the captured output of recorded runs",
+	Long: `Search the captured output of recorded runs and show the matching lines
+with their line numbers.
+
+Matching is case-insensitive and every word of the query must appear in a
+run's output; words also match as prefixes ("acquir" finds "acquiring").
+Lines containing all words are shown first, up to five per run.
+
+Example:
+  tfjournal search "state lock"
+  tfjournal search "InvalidClientTokenId" --since 7d
+  tfjournal search "aws_db_instance.main" -w production/* --status failed
+  tfjournal search "provider produced inconsistent" --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	Cmd.Flags().StringVar(&since, "since", "", "Search runs since duration (e.g., 7d, 24h)")
+	Cmd.Flags().StringVar(&user, "user", "", "Filter by user")
+	Cmd.Flags().StringVar(&status, "status", "", "Filter by run status (success, failed)")
+	Cmd.Flags().StringVarP(&workspace, "workspace", "w", "", "Filter by workspace pattern")
+	Cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of runs to show")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	opts := storage.ListOptions{
+		Limit: limit,
+		User:  user,
+	}
+
+	if workspace != "" {
+		opts.Workspace = workspace
+		if !strings.Contains(opts.Workspace, "%") {
+			opts.Workspace = strings.ReplaceAll(opts.Workspace, "*", "%")
+		}
+	}
+
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	if status != "" {
+		opts.Status = run.Status(status)
+	}
+
+	results, err := store.Search(strings.Join(args, " "), opts)
+	if err != nil {
+		return fmt.Errorf("failed to search outputs: %w", err)
+	}
+
+	if len(results) == 0 {
+		if jsonOutput {
+			fmt.Println("[]")
+		} else {
+			fmt.Println("No matches found.")
+		}
+		return nil
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	printResults(results)
+	return nil
+}
+
+func printResults(results []storage.SearchResult) {
+	for i, res := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s  %s  %s  %s  %s  %s\n",
+			res.RunID,
+			res.Timestamp.Format("2006-01-02 15:04"),
+			res.Workspace,
+			res.Action,
+			res.Status,
+			res.User,
+		)
+
+		width := len(fmt.Sprint(res.Matches[len(res.Matches)-1].Line))
+		for _, m := range res.Matches {
+			fmt.Printf("  %*d: %s\n", width, m.Line, m.Text)
+		}
+		if more := res.MatchCount - len(res.Matches); more > 0 {
+			fmt.Printf("  ... %d more matching lines\n", more)
+		}
+	}
+}
@@ -4,22 +4,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Owloops/tfjournal/config"
 	"github.com/Owloops/tfjournal/server"
-	"github.com/Owloops/tfjournal/storage"
-)
-
-const (
-	_defaultPort = 8080
-	_defaultBind = "127.0.0.1"
 )
 
 var (
-	_port     int
-	_bindAddr string
+	_port            int
+	_bindAddr        string
+	_metricsInterval time.Duration
 )
 
 var Cmd = &cobra.Command{
@@ -30,8 +26,9 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
-	Cmd.Flags().IntVarP(&_port, "port", "p", _defaultPort, "Port to listen on")
-	Cmd.Flags().StringVarP(&_bindAddr, "bind", "b", _defaultBind, "Address to bind to")
+	Cmd.Flags().IntVarP(&_port, "port", "p", config.DefaultPort, "Port to listen on")
+	Cmd.Flags().StringVarP(&_bindAddr, "bind", "b", config.DefaultBind, "Address to bind to")
+	Cmd.Flags().DurationVar(&_metricsInterval, "metrics-interval", 30*time.Second, "How often /metrics picks up new runs (0 to load once)")
 }
 
 func SetVersion(v string) {
@@ -39,30 +36,34 @@ func SetVersion(v string) {
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	store, err := storage.NewFromEnv()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	defer func() { _ = store.Close() }()
 
-	port := _port
-	bind := _bindAddr
+	port := cfg.Serve.Port
+	if cmd.Flags().Changed("port") {
+		port = _port
+	}
 
-	if !cmd.Flags().Changed("port") {
-		if envPort := os.Getenv("TFJOURNAL_PORT"); envPort != "" {
-			if p, err := strconv.Atoi(envPort); err == nil {
-				port = p
-			}
-		}
+	bind := cfg.Serve.Bind
+	if cmd.Flags().Changed("bind") {
+		bind = _bindAddr
 	}
 
-	if !cmd.Flags().Changed("bind") {
-		if envBind := os.Getenv("TFJOURNAL_BIND"); envBind != "" {
-			bind = envBind
-		}
+	metricsInterval := cfg.MetricsInterval()
+	if cmd.Flags().Changed("metrics-interval") {
+		metricsInterval = _metricsInterval
 	}
 
 	srv := server.New(store)
+	go srv.WatchMetrics(cmd.Context(), metricsInterval)
 	addr := fmt.Sprintf("%s:%d", bind, port)
 
 	username := os.Getenv("TFJOURNAL_USERNAME")
@@ -2,19 +2,25 @@ package show
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/lock"
 	"github.com/Owloops/tfjournal/run"
 	"github.com/Owloops/tfjournal/storage"
 )
 
 var (
 	showOutput bool
+	showDiff   bool
 	jsonOutput bool
 )
 
@@ -25,18 +31,25 @@ var Cmd = &cobra.Command{
 
 Example:
   tfjournal show run_abc123
-  tfjournal show run_abc123 --output`,
+  tfjournal show run_abc123 --output
+  tfjournal show run_abc123 --diff`,
 	Args: cobra.ExactArgs(1),
 	RunE: runShow,
 }
 
 func init() {
 	Cmd.Flags().BoolVar(&showOutput, "output", false, "Show captured output")
+	Cmd.Flags().BoolVar(&showDiff, "diff", false, "Show captured git diff of uncommitted changes")
 	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
 }
 
 func runShow(cmd *cobra.Command, args []string) error {
-	store, err := storage.NewFromEnv()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
 	if err != nil {
 		return fmt.Errorf("failed to open storage: %w", err)
 	}
@@ -57,24 +70,63 @@ func runShow(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if showDiff {
+		diff, err := store.GetDiff(runID)
+		if errors.Is(err, storage.ErrDiffNotFound) {
+			return fmt.Errorf("no diff recorded for run %s", runID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read diff: %w", err)
+		}
+		fmt.Print(string(diff))
+		if r.Git != nil && r.Git.DiffTruncated {
+			fmt.Fprintln(os.Stderr, "(diff truncated)")
+		}
+		return nil
+	}
+
 	if jsonOutput {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		return enc.Encode(r)
 	}
 
-	printRun(r)
+	var retries []*run.Run
+	if r.ParentID == "" {
+		retries, err = store.ListRuns(storage.ListOptions{Parent: r.ID, Since: r.Timestamp})
+		if err != nil {
+			return fmt.Errorf("failed to list retries: %w", err)
+		}
+		slices.Reverse(retries)
+	}
+
+	lockReport, err := lock.Lookup(store, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: failed to look up lock holder: %v\n", err)
+		lockReport = &lock.Report{Lock: r.Lock}
+	}
+
+	printRun(r, retries, lockReport)
 	return nil
 }
 
-func printRun(r *run.Run) {
+func printRun(r *run.Run, retries []*run.Run, lockReport *lock.Report) {
 	width := 70
 	border := strings.Repeat("─", width)
 
 	fmt.Printf("┌%s┐\n", border)
 	fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("run: %s", r.ID))
 	fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("workspace: %s", r.Workspace))
+	if r.Environment != "" || r.Component != "" {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("env/comp:  %s / %s", r.Environment, r.Component))
+	}
 	fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("status: %s", statusString(r.Status)))
+	if r.ParentID != "" {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("attempt: %d, retry of %s", r.Attempt, r.ParentID))
+	}
+	for _, retry := range retries {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("retry:  attempt %d %s %s", retry.Attempt, retry.ID, statusString(retry.Status)))
+	}
 	fmt.Printf("├%s┤\n", border)
 
 	fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("started:   %s", r.Timestamp.Format("2006-01-02 15:04:05")))
@@ -84,11 +136,95 @@ func printRun(r *run.Run) {
 	fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("user:      %s", r.User))
 
 	if r.Git != nil {
-		gitLine := fmt.Sprintf("%s (%s)", r.Git.Commit, r.Git.Branch)
-		if r.Git.Dirty {
-			gitLine += " [dirty]"
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("git:       %s", r.Git.Summary()))
+		if author := r.Git.AuthorSummary(); author != "" {
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("author:    %s", author))
+		}
+		if len(r.Git.DirtyFiles) > 0 {
+			fmt.Printf("│  %-*s│\n", width-2, "dirty:")
+		}
+		for _, f := range r.Git.DirtyFiles {
+			line := "    " + f
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+		if r.Git.DiffBytes > 0 {
+			diff := fmt.Sprintf("%d bytes (show --diff)", r.Git.DiffBytes)
+			if r.Git.DiffTruncated {
+				diff = fmt.Sprintf("%d bytes, truncated (show --diff)", r.Git.DiffBytes)
+			}
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("diff:      %s", diff))
+		}
+	}
+
+	if r.Cloud != nil {
+		if account := r.Cloud.Account(); account != "" {
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("account:   %s", account))
+		}
+		if r.Cloud.AWSProfile != "" {
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("profile:   %s", r.Cloud.AWSProfile))
+		}
+		if r.Cloud.AWSRoleARN != "" {
+			line := fmt.Sprintf("role:      %s", r.Cloud.AWSRoleARN)
+			if len(line) > width-2 {
+				line = line[:width-5] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+		for _, k := range slices.Sorted(maps.Keys(r.Cloud.Env)) {
+			line := fmt.Sprintf("    %s=%s", k, r.Cloud.Env[k])
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+		if len(r.Cloud.TFVars) > 0 {
+			line := fmt.Sprintf("tf vars:   %s", strings.Join(r.Cloud.TFVars, ", "))
+			if len(line) > width-2 {
+				line = line[:width-5] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+	}
+
+	if r.State != nil {
+		line := fmt.Sprintf("state:     %s (%s)", r.State.Summary(), r.State.Backend)
+		if len(line) > width-2 {
+			line = line[:width-5] + "..."
+		}
+		fmt.Printf("│  %-*s│\n", width-2, line)
+	}
+
+	if r.Versions != nil {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("versions:  %s", r.Versions.Summary()))
+		if len(r.Versions.Providers) > 0 {
+			fmt.Printf("│  %-*s│\n", width-2, "providers:")
+		}
+		for _, p := range r.Versions.Providers {
+			line := fmt.Sprintf("    %s %s", p.Name(), p.Version)
+			if p.Constraints != "" {
+				line += fmt.Sprintf(" (%s)", p.Constraints)
+			}
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+		if len(r.Versions.Modules) > 0 {
+			fmt.Printf("│  %-*s│\n", width-2, "modules:")
+		}
+		for _, m := range r.Versions.Modules {
+			line := fmt.Sprintf("    %s %s", m.Key, m.Source)
+			if m.Version != "" {
+				line += " " + m.Version
+			}
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
 		}
-		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("git:       %s", gitLine))
 	}
 
 	if r.CI != nil {
@@ -96,6 +232,79 @@ func printRun(r *run.Run) {
 		if r.CI.Workflow != "" {
 			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("workflow:  %s", r.CI.Workflow))
 		}
+		if r.CI.Job != "" {
+			job := r.CI.Job
+			if r.CI.Attempt > 1 {
+				job += fmt.Sprintf(" (attempt %d)", r.CI.Attempt)
+			}
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("job:       %s", job))
+		}
+		if r.CI.Event != "" {
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("event:     %s", r.CI.Event))
+		}
+		if r.CI.PullRequest != "" {
+			fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("pr:        %s", prLine(r.CI)))
+		}
+	}
+
+	if r.Policy != nil {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("policy:    %s", r.Policy.Decision))
+		for _, v := range r.Policy.Violations {
+			line := fmt.Sprintf("    %s (%s): %s", v.Rule, v.Effect, v.Message)
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+	}
+
+	if r.Approval != nil {
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("approval:  %s", approvalString(r.Approval)))
+		for _, reason := range r.Approval.Reasons {
+			line := "    " + reason
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+	}
+
+	if r.Failure != nil {
+		category := r.Failure.Category
+		if r.Failure.Transient {
+			category += " (transient)"
+		}
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("failure:   %s", category))
+		if r.Failure.Message != "" {
+			line := "    " + r.Failure.Message
+			if len(line) > width-4 {
+				line = line[:width-7] + "..."
+			}
+			fmt.Printf("│  %-*s│\n", width-2, line)
+		}
+	}
+
+	if lockReport != nil {
+		l := lockReport.Lock
+		held := "held"
+		if l.Who != "" {
+			held += " by " + l.Who
+		}
+		if l.Operation != "" {
+			held += " (" + l.Operation + ")"
+		}
+		if !l.Created.IsZero() {
+			held += " since " + l.Created.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("│  %-*s│\n", width-2, fmt.Sprintf("lock:      %s", held))
+		if l.ID != "" {
+			fmt.Printf("│  %-*s│\n", width-2, "    id: "+l.ID)
+		}
+		if lockReport.Holder != nil {
+			for _, line := range wrap(lockReport.Holder.Message, width-8) {
+				fmt.Printf("│  %-*s│\n", width-2, "    "+line)
+			}
+		}
 	}
 
 	fmt.Printf("├%s┤\n", border)
@@ -117,6 +326,25 @@ func printRun(r *run.Run) {
 	fmt.Printf("└%s┘\n", border)
 }
 
+func wrap(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 func statusString(s run.Status) string {
 	switch s {
 	case run.StatusSuccess:
@@ -127,11 +355,28 @@ func statusString(s run.Status) string {
 		return "● running"
 	case run.StatusCanceled:
 		return "○ canceled"
+	case run.StatusBlocked:
+		return "⊘ blocked"
+	case run.StatusDrift:
+		return "⚠ drift"
+	case run.StatusClean:
+		return "✓ clean"
 	default:
 		return string(s)
 	}
 }
 
+func approvalString(a *run.Approval) string {
+	s := a.Decision
+	if a.Approver != "" {
+		s += " by " + a.Approver
+	}
+	if a.Method != "" {
+		s += " (" + a.Method + ")"
+	}
+	return s
+}
+
 func actionIcon(action string) string {
 	switch action {
 	case "create":
@@ -144,3 +389,11 @@ func actionIcon(action string) string {
 		return "?"
 	}
 }
+
+func prLine(ci *run.CIInfo) string {
+	line := "#" + ci.PullRequest
+	if ci.SourceBranch != "" && ci.TargetBranch != "" {
+		line += fmt.Sprintf(" (%s → %s)", ci.SourceBranch, ci.TargetBranch)
+	}
+	return line
+}
@@ -0,0 +1,266 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/failure"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/stats"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+var (
+	groupBy    string
+	since      string
+	user       string
+	status     string
+	program    string
+	action     string
+	branch     string
+	tfVersion  string
+	provider   string
+	stateID    string
+	account    string
+	hasChanges bool
+	failures   bool
+	jsonOutput bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "stats [workspace-pattern]",
+	Short: "Aggregate statistics over recorded runs",
+	Long: `Aggregate recorded runs and report counts, success rate, duration
+percentiles and total changes.
+
+Group by one or more of: workspace, environment, component, user, program, action, branch, version, state, account, failure, day, week, month.
+Use provider:<name> (e.g. provider:aws) to group by the locked version of a provider.
+
+With --failures, report failed runs by category (state_lock, auth, throttling,
+provider_bug, config, timeout, unknown) and the error signatures that recur per
+workspace instead.
+
+Example:
+  tfjournal stats --since 30d
+  tfjournal stats --by workspace --action apply --status failed --since 30d
+  tfjournal stats production/* --by month --action apply
+  tfjournal stats --by workspace,user --json
+  tfjournal stats --by version,provider:aws --action apply
+  tfjournal stats --by workspace,failure --status failed
+  tfjournal stats --failures --since 30d`,
+	RunE: runStats,
+}
+
+func init() {
+	Cmd.Flags().StringVar(&groupBy, "by", "", "Group by dimensions (comma-separated)")
+	Cmd.Flags().StringVar(&since, "since", "", "Only runs since duration (e.g., 7d, 24h)")
+	Cmd.Flags().StringVar(&user, "user", "", "Filter by user")
+	Cmd.Flags().StringVar(&status, "status", "", "Filter by status (success, failed, drift, clean)")
+	Cmd.Flags().StringVar(&program, "program", "", "Filter by program (terraform, tofu, terragrunt)")
+	Cmd.Flags().StringVar(&action, "action", "", "Filter by action (plan, apply, destroy, import, taint)")
+	Cmd.Flags().StringVar(&branch, "branch", "", "Filter by git branch")
+	Cmd.Flags().StringVar(&tfVersion, "tf-version", "", "Filter by terraform/tofu version prefix (e.g., 1.7)")
+	Cmd.Flags().StringVar(&provider, "provider", "", "Filter by provider, optionally with a version prefix (e.g., aws@5.31)")
+	Cmd.Flags().StringVar(&stateID, "state", "", "Filter by state identity (e.g., s3://bucket/key, supports *)")
+	Cmd.Flags().StringVar(&account, "account", "", "Filter by cloud account, role ARN, project or subscription")
+	Cmd.Flags().BoolVar(&hasChanges, "has-changes", false, "Only runs with actual changes")
+	Cmd.Flags().BoolVar(&failures, "failures", false, "Report failure categories and recurring error signatures")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	dims, err := stats.ParseDimensions(groupBy)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	opts := storage.ListOptions{
+		User:       user,
+		Program:    program,
+		Action:     action,
+		Branch:     branch,
+		Version:    tfVersion,
+		Provider:   provider,
+		State:      stateID,
+		Account:    account,
+		HasChanges: hasChanges,
+	}
+
+	if len(args) > 0 {
+		opts.Workspace = args[0]
+		if !strings.Contains(opts.Workspace, "%") {
+			opts.Workspace = strings.ReplaceAll(opts.Workspace, "*", "%")
+		}
+	}
+
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	if status != "" {
+		opts.Status = run.Status(status)
+		if !opts.Status.Valid() {
+			return fmt.Errorf("invalid status %q: must be one of running, success, failed, canceled, blocked, drift, clean", status)
+		}
+	}
+
+	runs, err := store.ListRuns(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	if failures || slices.Contains(dims, stats.ByFailure) {
+		if err := failure.Fill(store, runs); err != nil {
+			return fmt.Errorf("failed to classify failures: %w", err)
+		}
+	}
+
+	if failures {
+		report := stats.Failures(runs)
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+		printFailures(report)
+		return nil
+	}
+
+	report := stats.Compute(runs, dims)
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	printReport(report)
+	return nil
+}
+
+func printReport(report *stats.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	var header []string
+	for _, d := range report.GroupBy {
+		header = append(header, string(d))
+	}
+	header = append(header, "runs", "success", "p50", "p95", "max", "changes")
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, g := range report.Groups {
+		var row []string
+		for _, d := range report.GroupBy {
+			v := g.Key[string(d)]
+			if v == "" {
+				v = "-"
+			}
+			row = append(row, v)
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(append(row, groupColumns(g)...), "\t"))
+	}
+
+	var total []string
+	for i := range report.GroupBy {
+		if i == 0 {
+			total = append(total, "total")
+		} else {
+			total = append(total, "")
+		}
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(append(total, groupColumns(report.Total)...), "\t"))
+	_ = w.Flush()
+}
+
+func printFailures(report *stats.FailureReport) {
+	if report.Failed == 0 {
+		fmt.Println("No failed runs found.")
+		return
+	}
+
+	categories := make([]string, 0, len(report.Categories))
+	for c := range report.Categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if report.Categories[categories[i]] != report.Categories[categories[j]] {
+			return report.Categories[categories[i]] > report.Categories[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "category\truns\tshare")
+	for _, c := range categories {
+		n := report.Categories[c]
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%.0f%%\n", c, n, float64(n)/float64(report.Failed)*100)
+	}
+	_ = w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "workspace\tcategory\tcount\tlast seen\tsignature")
+	for _, s := range report.Signatures {
+		category := s.Category
+		if s.Transient {
+			category += "*"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+			s.Workspace,
+			category,
+			s.Count,
+			s.LastSeen.Format("2006-01-02 15:04"),
+			s.Signature,
+		)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%d failed, %d transient (*)", report.Failed, report.Transient)
+	if report.Unclassified > 0 {
+		fmt.Printf(", %d without captured output", report.Unclassified)
+	}
+	fmt.Println()
+}
+
+func groupColumns(g *stats.Group) []string {
+	count := fmt.Sprintf("%d", g.Count)
+	if g.Running > 0 {
+		count += fmt.Sprintf(" (%d running)", g.Running)
+	}
+	return []string{
+		count,
+		fmt.Sprintf("%.0f%%", g.SuccessRate*100),
+		formatMs(g.DurationP50Ms),
+		formatMs(g.DurationP95Ms),
+		formatMs(g.DurationMaxMs),
+		fmt.Sprintf("+%d ~%d -%d", g.Add, g.Change, g.Destroy),
+	}
+}
+
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+}
@@ -0,0 +1,179 @@
+package trends
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/stats"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const _sparkPoints = 12
+
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+var (
+	groupBy       string
+	action        string
+	since         string
+	threshold     int
+	recent        int
+	regressedOnly bool
+	limit         int
+	jsonOutput    bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "trends [workspace-pattern]",
+	Short: "Show per-resource duration trends",
+	Long: `Show how long resources take to create, update and destroy across runs,
+grouped by resource type or address, and flag create/update durations that
+regressed: the median of the most recent samples exceeds the median of the
+earlier ones by more than the threshold.
+
+Example:
+  tfjournal trends
+  tfjournal trends --by address --action update
+  tfjournal trends production/* --since 30d --regressed
+  tfjournal trends --threshold 100 --recent 5 --json`,
+	RunE: runTrends,
+}
+
+func init() {
+	Cmd.Flags().StringVar(&groupBy, "by", stats.ByType, "Group by resource type or address")
+	Cmd.Flags().StringVar(&action, "action", "", "Only include resource actions (create, update, destroy)")
+	Cmd.Flags().StringVar(&since, "since", "", "Only include runs since duration (e.g., 7d, 24h)")
+	Cmd.Flags().IntVar(&threshold, "threshold", int(stats.DefaultThreshold*100), "Regression threshold in percent")
+	Cmd.Flags().IntVar(&recent, "recent", stats.DefaultRecent, "Number of most recent samples compared against the baseline")
+	Cmd.Flags().BoolVar(&regressedOnly, "regressed", false, "Show only regressed resources")
+	Cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of rows to show (0 for all)")
+	Cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+}
+
+func runTrends(cmd *cobra.Command, args []string) error {
+	if err := stats.ValidateTrendBy(groupBy); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	opts := storage.ListOptions{}
+
+	if len(args) > 0 {
+		opts.Workspace = args[0]
+		if !strings.Contains(opts.Workspace, "%") {
+			opts.Workspace = strings.ReplaceAll(opts.Workspace, "*", "%")
+		}
+	}
+
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	runs, err := store.ListRuns(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	report := stats.ResourceTrends(runs, stats.TrendOptions{
+		By:        groupBy,
+		Action:    action,
+		Threshold: float64(threshold) / 100,
+		Recent:    recent,
+	})
+	if regressedOnly {
+		report.Trends = report.Trends[:report.Regressed]
+	}
+	if limit > 0 && len(report.Trends) > limit {
+		report.Trends = report.Trends[:limit]
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Trends) == 0 {
+		fmt.Println("No resource durations found.")
+		return nil
+	}
+
+	printTable(report)
+	return nil
+}
+
+func printTable(report *stats.TrendReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "%s\taction\tsamples\tp50\tp95\tbaseline\trecent\tchange\ttrend\t\n", report.By)
+	for _, t := range report.Trends {
+		change, flag := "-", ""
+		if t.BaselineMs > 0 {
+			change = fmt.Sprintf("%+.0f%%", t.Change*100)
+		}
+		if t.Regressed {
+			flag = "▲ regressed"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.Key,
+			t.Action,
+			t.Samples,
+			formatMs(t.P50Ms),
+			formatMs(t.P95Ms),
+			formatMs(t.BaselineMs),
+			formatMs(t.RecentMs),
+			change,
+			sparkline(t.Points),
+			flag,
+		)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%d regressed (more than %.0f%% slower over the last %d samples)\n", report.Regressed, report.Threshold*100, report.Recent)
+}
+
+func sparkline(points []stats.TrendPoint) string {
+	points = points[max(0, len(points)-_sparkPoints):]
+	lo, hi := points[0].DurationMs, points[0].DurationMs
+	for _, p := range points {
+		lo, hi = min(lo, p.DurationMs), max(hi, p.DurationMs)
+	}
+
+	var sb strings.Builder
+	for _, p := range points {
+		idx := 0
+		if hi > lo {
+			idx = int((p.DurationMs - lo) * int64(len(sparkChars)-1) / (hi - lo))
+		}
+		sb.WriteRune(sparkChars[idx])
+	}
+	return sb.String()
+}
+
+func formatMs(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
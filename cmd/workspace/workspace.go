@@ -0,0 +1,114 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+var (
+	since  string
+	dryRun bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage workspace names of recorded runs",
+}
+
+var rewriteCmd = &cobra.Command{
+	Use:   "rewrite [workspace-pattern]",
+	Short: "Apply workspace naming rules to existing runs",
+	Long: `Re-apply the workspace aliases and rules from the configuration file to
+runs that were already recorded.
+
+Runs keep their original name in raw_workspace, so rules can be changed and
+re-applied later.
+
+Example:
+  tfjournal workspace rewrite --dry-run
+  tfjournal workspace rewrite "live/*" --since 30d`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRewrite,
+}
+
+func init() {
+	rewriteCmd.Flags().StringVar(&since, "since", "", "Only runs since duration (e.g., 7d, 24h)")
+	rewriteCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without saving")
+	Cmd.AddCommand(rewriteCmd)
+}
+
+func runRewrite(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.OpenStore()
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	var opts storage.ListOptions
+	if len(args) > 0 {
+		opts.Workspace = args[0]
+	}
+	if since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	runs, err := store.ListRuns(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "id\tfrom\tto\tenvironment\tcomponent")
+
+	changed := 0
+	for _, r := range runs {
+		from := r.Workspace
+		if !cfg.Naming().Apply(r) {
+			continue
+		}
+		changed++
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, from, r.Workspace, r.Environment, r.Component)
+
+		if dryRun {
+			continue
+		}
+		if err := saveRun(store, r); err != nil {
+			_ = w.Flush()
+			return fmt.Errorf("failed to save %s: %w", r.ID, err)
+		}
+	}
+	_ = w.Flush()
+
+	verb := "rewrote"
+	if dryRun {
+		verb = "would rewrite"
+	}
+	fmt.Fprintf(os.Stderr, "\n%s %d of %d runs\n", verb, changed, len(runs))
+	return nil
+}
+
+func saveRun(store storage.Store, r *run.Run) error {
+	status := r.SyncStatus
+	r.SyncStatus = ""
+	if h, ok := store.(*storage.HybridStore); ok && status == run.SyncStatusRemote {
+		return h.SaveS3Run(r)
+	}
+	return store.SaveRun(r)
+}
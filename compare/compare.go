@@ -0,0 +1,326 @@
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const DefaultContext = 3
+
+var normalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`), ""},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<timestamp>"},
+	{regexp.MustCompile(`run_\d{8}T\d{6}_[0-9a-f]+`), "<run-id>"},
+	{regexp.MustCompile(`\[id=[^\]]*\]`), "[id=<id>]"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`complete after [0-9hms]+`), "complete after <duration>"},
+	{regexp.MustCompile(`\[[0-9hms]+ elapsed\]`), "[<duration> elapsed]"},
+}
+
+type Field struct {
+	Name    string `json:"name"`
+	A       string `json:"a"`
+	B       string `json:"b"`
+	Changed bool   `json:"changed"`
+}
+
+type ResourceState struct {
+	Action     string `json:"action"`
+	Planned    bool   `json:"planned,omitempty"`
+	Status     string `json:"status,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+type ResourceDiff struct {
+	Address string         `json:"address"`
+	A       *ResourceState `json:"a,omitempty"`
+	B       *ResourceState `json:"b,omitempty"`
+	Changed bool           `json:"changed"`
+}
+
+type Result struct {
+	A             string         `json:"a"`
+	B             string         `json:"b"`
+	TimestampA    time.Time      `json:"timestamp_a"`
+	TimestampB    time.Time      `json:"timestamp_b"`
+	DurationMsA   int64          `json:"duration_ms_a"`
+	DurationMsB   int64          `json:"duration_ms_b"`
+	SameWorkspace bool           `json:"same_workspace"`
+	Fields        []Field        `json:"fields"`
+	ChangesA      *run.Changes   `json:"changes_a,omitempty"`
+	ChangesB      *run.Changes   `json:"changes_b,omitempty"`
+	Resources     []ResourceDiff `json:"resources,omitempty"`
+	Output        string         `json:"output,omitempty"`
+	OutputMissing bool           `json:"output_missing,omitempty"`
+}
+
+func Load(store storage.Store, idA, idB string, context int) (*Result, error) {
+	a, err := store.GetRun(idA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := store.GetRun(idB)
+	if err != nil {
+		return nil, err
+	}
+
+	res := Runs(a, b)
+	outA, errA := store.GetOutput(a.ID)
+	outB, errB := store.GetOutput(b.ID)
+	for _, err := range []error{errA, errB} {
+		if err != nil && !errors.Is(err, storage.ErrOutputNotFound) {
+			return nil, err
+		}
+	}
+	if errA != nil || errB != nil {
+		res.OutputMissing = true
+		return res, nil
+	}
+	res.Output = Unified(Normalize(outA), Normalize(outB), a.ID, b.ID, context)
+	return res, nil
+}
+
+func Runs(a, b *run.Run) *Result {
+	res := &Result{
+		A:             a.ID,
+		B:             b.ID,
+		TimestampA:    a.Timestamp,
+		TimestampB:    b.Timestamp,
+		DurationMsA:   a.DurationMs,
+		DurationMsB:   b.DurationMs,
+		SameWorkspace: a.Workspace == b.Workspace,
+		ChangesA:      a.Changes,
+		ChangesB:      b.Changes,
+		Resources:     resources(a, b),
+	}
+
+	add := func(name, va, vb string) {
+		if va == "" && vb == "" {
+			return
+		}
+		res.Fields = append(res.Fields, Field{Name: name, A: va, B: vb, Changed: va != vb})
+	}
+	add("workspace", a.Workspace, b.Workspace)
+	add("status", string(a.Status), string(b.Status))
+	add("exit code", strconv.Itoa(a.ExitCode), strconv.Itoa(b.ExitCode))
+	add("command", strings.Join(a.Command, " "), strings.Join(b.Command, " "))
+	add("user", a.User, b.User)
+	add("git commit", gitCommit(a), gitCommit(b))
+	add("git branch", gitBranch(a), gitBranch(b))
+	add("dirty", gitDirty(a), gitDirty(b))
+	add("version", versionSummary(a), versionSummary(b))
+	for _, name := range providerNames(a, b) {
+		add("provider "+name, providerVersion(a, name), providerVersion(b, name))
+	}
+	add("state", stateID(a), stateID(b))
+	add("account", account(a), account(b))
+	add("changes", a.ChangeSummary(), b.ChangeSummary())
+	return res
+}
+
+func Normalize(output []byte) []string {
+	text := strings.TrimRight(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		for _, n := range normalizers {
+			line = n.re.ReplaceAllString(line, n.repl)
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func (r *Result) Write(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "Comparing %s → %s\n", r.A, r.B)
+	_, _ = fmt.Fprintf(w, "a: %s, took %s\n", r.TimestampA.Format(time.DateTime), time.Duration(r.DurationMsA)*time.Millisecond)
+	_, _ = fmt.Fprintf(w, "b: %s, took %s\n", r.TimestampB.Format(time.DateTime), time.Duration(r.DurationMsB)*time.Millisecond)
+	if !r.SameWorkspace {
+		_, _ = fmt.Fprintln(w, "warning: runs are from different workspaces")
+	}
+	_, _ = fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\ta\tb\t")
+	for _, f := range r.Fields {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Name, orDash(f.A), orDash(f.B), changedMark(f.Changed))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Resources) > 0 {
+		_, _ = fmt.Fprintln(w, "\nResources")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "address\ta\tb\t")
+		for _, d := range r.Resources {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Address, d.A.String(), d.B.String(), changedMark(d.Changed))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, "\nOutput")
+	switch {
+	case r.OutputMissing:
+		_, _ = fmt.Fprintln(w, "(output not captured for both runs)")
+	case r.Output == "":
+		_, _ = fmt.Fprintln(w, "(identical after normalization)")
+	default:
+		_, _ = fmt.Fprint(w, r.Output)
+	}
+	return nil
+}
+
+func (s *ResourceState) String() string {
+	if s == nil {
+		return "-"
+	}
+	parts := []string{s.Action}
+	if s.Planned {
+		parts = append(parts, "(planned)")
+	}
+	if s.DurationMs > 0 {
+		parts = append(parts, (time.Duration(s.DurationMs) * time.Millisecond).Round(100*time.Millisecond).String())
+	}
+	if s.Status != "" && s.Status != "success" {
+		parts = append(parts, s.Status)
+	}
+	return strings.Join(parts, " ")
+}
+
+func resources(a, b *run.Run) []ResourceDiff {
+	statesA, statesB := resourceStates(a), resourceStates(b)
+
+	addresses := make([]string, 0, len(statesA)+len(statesB))
+	for address := range statesA {
+		addresses = append(addresses, address)
+	}
+	for address := range statesB {
+		if _, ok := statesA[address]; !ok {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	diffs := make([]ResourceDiff, 0, len(addresses))
+	for _, address := range addresses {
+		sa, sb := statesA[address], statesB[address]
+		changed := sa == nil || sb == nil || sa.Action != sb.Action || sa.Planned != sb.Planned || sa.Status != sb.Status
+		diffs = append(diffs, ResourceDiff{Address: address, A: sa, B: sb, Changed: changed})
+	}
+	return diffs
+}
+
+func resourceStates(r *run.Run) map[string]*ResourceState {
+	states := make(map[string]*ResourceState)
+	for _, res := range r.Resources {
+		states[res.Address] = &ResourceState{Action: res.Action, Status: res.Status, DurationMs: res.DurationMs}
+	}
+	for _, p := range r.Planned {
+		if _, ok := states[p.Address]; !ok {
+			states[p.Address] = &ResourceState{Action: p.Action, Planned: true}
+		}
+	}
+	return states
+}
+
+func providerNames(a, b *run.Run) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range []*run.Run{a, b} {
+		if r.Versions == nil {
+			continue
+		}
+		for _, p := range r.Versions.Providers {
+			if !seen[p.Source] {
+				seen[p.Source] = true
+				names = append(names, p.Source)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func providerVersion(r *run.Run, source string) string {
+	if r.Versions == nil {
+		return ""
+	}
+	if p := r.Versions.Provider(source); p != nil {
+		return p.Version
+	}
+	return ""
+}
+
+func gitCommit(r *run.Run) string {
+	if r.Git == nil {
+		return ""
+	}
+	return r.Git.Commit
+}
+
+func gitBranch(r *run.Run) string {
+	if r.Git == nil {
+		return ""
+	}
+	return r.Git.Branch
+}
+
+func gitDirty(r *run.Run) string {
+	if r.Git == nil {
+		return ""
+	}
+	return strconv.FormatBool(r.Git.Dirty)
+}
+
+func versionSummary(r *run.Run) string {
+	if r.Versions == nil {
+		return ""
+	}
+	return r.Versions.Summary()
+}
+
+func stateID(r *run.Run) string {
+	if r.State == nil {
+		return ""
+	}
+	return r.State.Summary()
+}
+
+func account(r *run.Run) string {
+	if r.Cloud == nil {
+		return ""
+	}
+	return r.Cloud.Account()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func changedMark(changed bool) string {
+	if changed {
+		return "*"
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package compare
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+func TestNormalize(t *testing.T) {
+	output := "\x1b[1maws_instance.web: Creating...\x1b[0m\r\n" +
+		"aws_instance.web: Still creating... [10s elapsed]\n" +
+		"aws_instance.web: Creation complete after 45s [id=i-0abc123]\n" +
+		"Lock Info:\n  ID: 0b6f8c1e-2d7a-4c8e-9f3a-5b1c2d3e4f60\n" +
+		"2025-01-23T10:30:00.123Z [INFO] run_20250123T103000_a1b2c3d4 started\n"
+
+	want := []string{
+		"aws_instance.web: Creating...",
+		"aws_instance.web: Still creating... [<duration> elapsed]",
+		"aws_instance.web: Creation complete after <duration> [id=<id>]",
+		"Lock Info:",
+		"  ID: <uuid>",
+		"<timestamp> [INFO] <run-id> started",
+	}
+	if got := Normalize([]byte(output)); !slices.Equal(got, want) {
+		t.Errorf("Normalize() =\n%q\nwant\n%q", got, want)
+	}
+	if got := Normalize(nil); got != nil {
+		t.Errorf("Normalize(nil) = %q, want nil", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: "",
+		},
+		{
+			name: "change in the middle",
+			a:    []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+			b:    []string{"1", "2", "3", "4", "five", "6", "7", "8", "9"},
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			a:    []string{"x", "1", "2", "3", "4", "5", "6", "7", "y"},
+			b:    []string{"X", "1", "2", "3", "4", "5", "6", "7", "Y"},
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-x\n+X\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-y\n+Y\n",
+		},
+		{
+			name: "insert into empty",
+			a:    nil,
+			b:    []string{"new"},
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+new\n",
+		},
+		{
+			name: "interleaved",
+			a:    []string{"a", "b", "c", "d"},
+			b:    []string{"a", "c", "e", "d"},
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n a\n-b\n c\n+e\n d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.a, tt.b, "a", "b", DefaultContext); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuns(t *testing.T) {
+	ts := time.Date(2025, 1, 23, 10, 30, 0, 0, time.UTC)
+	a := &run.Run{
+		ID:        "run_a",
+		Workspace: "production/web",
+		Timestamp: ts,
+		Status:    run.StatusSuccess,
+		User:      "alice",
+		Git:       &run.GitInfo{Commit: "abc1234", Branch: "main"},
+		Versions: &run.Versions{Binary: "terraform", Version: "1.7.5", Providers: []run.ProviderVersion{
+			{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0"},
+		}},
+		Changes: &run.Changes{Add: 1},
+		Resources: []run.Resource{
+			{Address: "aws_instance.web", Action: "create", DurationMs: 45000, Status: "success"},
+		},
+	}
+	b := &run.Run{
+		ID:        "run_b",
+		Workspace: "production/web",
+		Timestamp: ts.Add(time.Hour),
+		Status:    run.StatusFailed,
+		User:      "alice",
+		Git:       &run.GitInfo{Commit: "def5678", Branch: "main"},
+		Versions: &run.Versions{Binary: "terraform", Version: "1.7.5", Providers: []run.ProviderVersion{
+			{Source: "registry.terraform.io/hashicorp/aws", Version: "5.40.0"},
+		}},
+		Changes: &run.Changes{Change: 1},
+		Resources: []run.Resource{
+			{Address: "aws_instance.web", Action: "update", DurationMs: 3000, Status: "failed"},
+		},
+		Planned: []run.Planned{{Address: "aws_eip.web", Action: "create"}},
+	}
+
+	res := Runs(a, b)
+	if !res.SameWorkspace {
+		t.Error("SameWorkspace = false, want true")
+	}
+
+	changed := map[string]bool{}
+	for _, f := range res.Fields {
+		changed[f.Name] = f.Changed
+	}
+	for name, want := range map[string]bool{
+		"workspace":  false,
+		"user":       false,
+		"status":     true,
+		"git commit": true,
+		"git branch": false,
+		"version":    false,
+		"provider registry.terraform.io/hashicorp/aws": true,
+		"changes": true,
+	} {
+		if got, ok := changed[name]; !ok || got != want {
+			t.Errorf("field %q changed = %v (present %v), want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"timestamp", "duration"} {
+		if _, ok := changed[name]; ok {
+			t.Errorf("field %q listed, want it only in the header", name)
+		}
+	}
+
+	if len(res.Resources) != 2 {
+		t.Fatalf("got %d resource diffs, want 2", len(res.Resources))
+	}
+	if d := res.Resources[0]; d.Address != "aws_eip.web" || d.A != nil || !d.B.Planned || !d.Changed {
+		t.Errorf("Resources[0] = %+v", d)
+	}
+	if d := res.Resources[1]; d.A.Action != "create" || d.B.Action != "update" || d.B.DurationMs != 3000 || !d.Changed {
+		t.Errorf("Resources[1] = %+v", d)
+	}
+
+	res.OutputMissing = true
+	var sb strings.Builder
+	if err := res.Write(&sb); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"Comparing run_a → run_b", "a: 2025-01-23 10:30:00, took 0s", "b: 2025-01-23 11:30:00", "create 45s", "update 3s failed", "create (planned)", "(output not captured for both runs)"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, sb.String())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	ts := time.Now().Truncate(time.Second)
+	a := &run.Run{ID: run.GenerateID(ts), Workspace: "web", Timestamp: ts, Status: run.StatusSuccess}
+	b := &run.Run{ID: run.GenerateID(ts.Add(time.Minute)), Workspace: "web", Timestamp: ts.Add(time.Minute), Status: run.StatusSuccess}
+	for _, r := range []*run.Run{a, b} {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+
+	res, err := Load(store, a.ID, b.ID, DefaultContext)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !res.OutputMissing || res.Output != "" {
+		t.Errorf("Load() without outputs = %+v", res)
+	}
+
+	if err := store.SaveOutput(a.ID, []byte("Apply complete! Resources: 1 added.\nwaited [id=i-1]\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveOutput(b.ID, []byte("Apply complete! Resources: 0 added.\nwaited [id=i-2]\n")); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Load(store, a.ID, b.ID, DefaultContext)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := "--- " + a.ID + "\n+++ " + b.ID + "\n@@ -1,2 +1,2 @@\n-Apply complete! Resources: 1 added.\n+Apply complete! Resources: 0 added.\n waited [id=<id>]\n"
+	if res.OutputMissing || res.Output != want {
+		t.Errorf("Load().Output =\n%s\nwant\n%s", res.Output, want)
+	}
+
+	if _, err := Load(store, a.ID, "run_missing", DefaultContext); !errors.Is(err, storage.ErrRunNotFound) {
+		t.Errorf("Load() with missing run error = %v, want ErrRunNotFound", err)
+	}
+}
@@ -0,0 +1,197 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _maxEditDistance = 2000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type edit struct {
+	op   opKind
+	line string
+	a, b int
+}
+
+func Unified(a, b []string, nameA, nameB string, context int) string {
+	edits := diffLines(a, b)
+
+	var sb strings.Builder
+	for start := 0; start < len(edits); {
+		for start < len(edits) && edits[start].op == opEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		first := max(0, start-context)
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].op != opEqual {
+				end = i + 1
+				continue
+			}
+			if i-end >= 2*context {
+				break
+			}
+		}
+		last := min(len(edits), end+context)
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&sb, edits[first:last])
+		start = last
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, edits []edit) {
+	var countA, countB int
+	for _, e := range edits {
+		if e.op != opInsert {
+			countA++
+		}
+		if e.op != opDelete {
+			countB++
+		}
+	}
+	startA, startB := edits[0].a, edits[0].b
+	if countA > 0 {
+		startA++
+	}
+	if countB > 0 {
+		startB++
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+	for _, e := range edits {
+		sb.WriteByte(byte(e.op))
+		sb.WriteString(e.line)
+		sb.WriteByte('\n')
+	}
+}
+
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for i := range prefix {
+		edits = append(edits, edit{opEqual, a[i], i, i})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := range suffix {
+		ia, ib := len(a)-suffix+i, len(b)-suffix+i
+		edits = append(edits, edit{opEqual, a[ia], ia, ib})
+	}
+	return edits
+}
+
+func myers(a, b []string, offset int) []edit {
+	n, m := len(a), len(b)
+	limit := min(n+m, _maxEditDistance)
+
+	var trace [][]int
+	v := map[int]int{1: 0}
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1] < v[k+1]) {
+				x = v[k+1]
+			} else {
+				x = v[k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, snapshot(v, d))
+				return backtrack(a, b, trace, offset)
+			}
+		}
+		trace = append(trace, snapshot(v, d))
+	}
+	return replaceAll(a, b, offset)
+}
+
+func snapshot(v map[int]int, d int) []int {
+	s := make([]int, 2*d+1)
+	for k := -d; k <= d; k++ {
+		s[k+d] = v[k]
+	}
+	return s
+}
+
+func backtrack(a, b []string, trace [][]int, offset int) []edit {
+	x, y := len(a), len(b)
+	var reversed []edit
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{opEqual, a[x], offset + x, offset + y})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, edit{opInsert, b[y], offset + x, offset + y})
+		} else {
+			x--
+			reversed = append(reversed, edit{opDelete, a[x], offset + x, offset + y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, edit{opEqual, a[x], offset + x, offset + y})
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+func replaceAll(a, b []string, offset int) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for i, line := range a {
+		edits = append(edits, edit{opDelete, line, offset + i, offset})
+	}
+	for i, line := range b {
+		edits = append(edits, edit{opInsert, line, offset + len(a), offset + i})
+	}
+	return edits
+}
@@ -0,0 +1,549 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Owloops/tfjournal/approval"
+	"github.com/Owloops/tfjournal/cloud"
+	"github.com/Owloops/tfjournal/naming"
+	"github.com/Owloops/tfjournal/notify"
+	"github.com/Owloops/tfjournal/otlp"
+	"github.com/Owloops/tfjournal/policy"
+	"github.com/Owloops/tfjournal/prcomment"
+	"github.com/Owloops/tfjournal/retry"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const (
+	FileName = ".tfjournal.yaml"
+
+	DefaultPort            = 8080
+	DefaultBind            = "127.0.0.1"
+	DefaultMetricsInterval = "30s"
+	DefaultMaxDiffBytes    = 256 * 1024
+
+	SourceDefault = "default"
+)
+
+var diffRedact = []*regexp.Regexp{
+	regexp.MustCompile(`(?im)^[-+ ][ \t]*"?[\w.-]*(?:password|passwd|secret|token|api_?key|private_?key|access_?key|credentials?)[\w.-]*"?[ \t]*[=:][ \t]*(.+?)[ \t]*$`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----(.*?)(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`),
+}
+
+type Config struct {
+	Storage       Storage           `json:"storage"`
+	Serve         Serve             `json:"serve"`
+	Workspace     Workspace         `json:"workspace"`
+	Git           Git               `json:"git"`
+	Cloud         cloud.Config      `json:"cloud"`
+	Redact        []string          `json:"redact,omitempty"`
+	Retention     Retention         `json:"retention"`
+	Tracing       otlp.Config       `json:"tracing"`
+	Notifications *notify.Config    `json:"notifications,omitempty"`
+	Policies      []policy.Rule     `json:"policies,omitempty"`
+	Approval      []approval.Rule   `json:"approval,omitempty"`
+	Comments      *prcomment.Config `json:"comments,omitempty"`
+	Retry         retry.Config      `json:"retry"`
+
+	Files   []string          `json:"-"`
+	Sources map[string]string `json:"-"`
+
+	values          map[string]any
+	naming          *naming.Mapper
+	redact          []*regexp.Regexp
+	maxAge          time.Duration
+	metricsInterval time.Duration
+}
+
+type Storage struct {
+	Path string `json:"path,omitempty"`
+	S3   S3     `json:"s3"`
+}
+
+type S3 struct {
+	Bucket  string `json:"bucket,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+type Serve struct {
+	Port            int    `json:"port,omitempty"`
+	Bind            string `json:"bind,omitempty"`
+	MetricsInterval string `json:"metrics_interval,omitempty"`
+	URL             string `json:"url,omitempty"`
+}
+
+type Workspace struct {
+	Name    string            `json:"name,omitempty"`
+	Aliases map[string]string `json:"aliases,omitempty"`
+	Rules   []naming.Rule     `json:"rules,omitempty"`
+}
+
+type Git struct {
+	CaptureDiff  bool `json:"capture_diff,omitempty"`
+	MaxDiffBytes int  `json:"max_diff_bytes,omitempty"`
+}
+
+type Retention struct {
+	MaxAge  string `json:"max_age,omitempty"`
+	MaxRuns int    `json:"max_runs,omitempty"`
+}
+
+type envVar struct {
+	name  string
+	key   string
+	parse func(string) (any, error)
+}
+
+var envVars = []envVar{
+	{"TFJOURNAL_STORAGE_PATH", "storage.path", nil},
+	{"TFJOURNAL_S3_BUCKET", "storage.s3.bucket", nil},
+	{"TFJOURNAL_S3_REGION", "storage.s3.region", nil},
+	{"TFJOURNAL_S3_PREFIX", "storage.s3.prefix", nil},
+	{"TFJOURNAL_PORT", "serve.port", parseInt},
+	{"TFJOURNAL_BIND", "serve.bind", nil},
+	{"TFJOURNAL_METRICS_INTERVAL", "serve.metrics_interval", nil},
+	{"TFJOURNAL_URL", "serve.url", nil},
+	{"TFJOURNAL_CAPTURE_DIFF", "git.capture_diff", parseBool},
+	{"TFJOURNAL_CLOUD_ENV", "cloud.env", parseList},
+	{"TFJOURNAL_OTLP_ENDPOINT", "tracing.endpoint", nil},
+	{"TFJOURNAL_OTLP_HEADERS", "tracing.headers", parseHeaders},
+	{"TFJOURNAL_NOTIFY_CONFIG", "notifications", parseFile("")},
+	{"TFJOURNAL_POLICY_CONFIG", "policies", parseFile("rules")},
+	{"TFJOURNAL_APPROVAL_CONFIG", "approval", parseFile("rules")},
+	{"TFJOURNAL_PR_COMMENTS", "comments.enabled", parseBool},
+	{"TFJOURNAL_PR_COMMENT_TOKEN", "comments.token", nil},
+	{"TFJOURNAL_RETRY_ATTEMPTS", "retry.attempts", parseInt},
+}
+
+func Load() (*Config, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return LoadFrom(cwd)
+}
+
+func LoadFrom(dir string) (*Config, error) {
+	values := map[string]any{}
+	sources := map[string]string{}
+
+	defaults := map[string]any{
+		"storage": map[string]any{"path": storage.DefaultPath()},
+		"serve": map[string]any{
+			"port":             DefaultPort,
+			"bind":             DefaultBind,
+			"metrics_interval": DefaultMetricsInterval,
+		},
+		"git":   map[string]any{"max_diff_bytes": DefaultMaxDiffBytes},
+		"cloud": map[string]any{"env": cloud.DefaultEnv, "tf_var_names": true},
+	}
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		defaults["storage"].(map[string]any)["s3"] = map[string]any{"profile": profile}
+	}
+	merge(values, defaults, "", SourceDefault, sources)
+
+	var files []string
+	for _, path := range []string{UserPath(), findRepoFile(dir)} {
+		if path == "" {
+			continue
+		}
+		layer, err := readFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, path)
+		merge(values, layer, "", path, sources)
+	}
+
+	for _, ev := range envVars {
+		raw := os.Getenv(ev.name)
+		if raw == "" {
+			continue
+		}
+		var v any = raw
+		if ev.parse != nil {
+			parsed, err := ev.parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", ev.name, err)
+			}
+			v = parsed
+		}
+		merge(values, nest(ev.key, v), "", "env "+ev.name, sources)
+	}
+
+	cfg, err := decode(values)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Files = files
+	cfg.Sources = sources
+	cfg.values = values
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func UserPath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tfjournal", "config.yaml")
+	}
+
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "tfjournal", "config.yaml")
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "tfjournal", "config.yaml")
+}
+
+func findRepoFile(dir string) string {
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var layer map[string]any
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if layer == nil {
+		return map[string]any{}, nil
+	}
+
+	if _, err := decode(layer); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if st, ok := layer["storage"].(map[string]any); ok {
+		if p, ok := st["path"].(string); ok && p != "" {
+			st["path"] = resolvePath(p, filepath.Dir(path))
+		}
+	}
+	return layer, nil
+}
+
+func resolvePath(p, base string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+func decode(values map[string]any) (*Config, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &cfg, nil
+}
+
+func merge(dst, src map[string]any, prefix, source string, sources map[string]string) {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if sm, ok := v.(map[string]any); ok {
+			dm, ok := dst[k].(map[string]any)
+			if !ok {
+				dm = map[string]any{}
+				dst[k] = dm
+				clearSources(sources, key)
+			}
+			merge(dm, sm, key, source, sources)
+			continue
+		}
+
+		clearSources(sources, key)
+		dst[k] = v
+		sources[key] = source
+	}
+}
+
+func clearSources(sources map[string]string, key string) {
+	for k := range sources {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(sources, k)
+		}
+	}
+}
+
+func nest(key string, v any) map[string]any {
+	parts := strings.Split(key, ".")
+	m := map[string]any{parts[len(parts)-1]: v}
+	for i := len(parts) - 2; i >= 0; i-- {
+		m = map[string]any{parts[i]: m}
+	}
+	return m
+}
+
+func (c *Config) validate() error {
+	mapper, err := naming.New(naming.Config{Aliases: c.Workspace.Aliases, Rules: c.Workspace.Rules})
+	if err != nil {
+		return err
+	}
+	c.naming = mapper
+
+	for _, p := range c.Redact {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		c.redact = append(c.redact, re)
+	}
+
+	if c.Retention.MaxAge != "" {
+		d, err := run.ParseDuration(c.Retention.MaxAge)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid retention.max_age %q", c.Retention.MaxAge)
+		}
+		c.maxAge = d
+	}
+	if c.Retention.MaxRuns < 0 {
+		return fmt.Errorf("invalid retention.max_runs %d", c.Retention.MaxRuns)
+	}
+	if c.Git.MaxDiffBytes < 0 {
+		return fmt.Errorf("invalid git.max_diff_bytes %d", c.Git.MaxDiffBytes)
+	}
+
+	d, err := time.ParseDuration(c.Serve.MetricsInterval)
+	if err != nil {
+		return fmt.Errorf("invalid serve.metrics_interval %q", c.Serve.MetricsInterval)
+	}
+	c.metricsInterval = d
+
+	return nil
+}
+
+func (c *Config) Naming() *naming.Mapper {
+	return c.naming
+}
+
+func (c *Config) MetricsInterval() time.Duration {
+	return c.metricsInterval
+}
+
+func (c *Config) MaxAge() time.Duration {
+	return c.maxAge
+}
+
+func (c *Config) StorageConfig() storage.Config {
+	return storage.Config{
+		LocalPath:  c.Storage.Path,
+		S3Bucket:   c.Storage.S3.Bucket,
+		S3Region:   c.Storage.S3.Region,
+		S3Prefix:   c.Storage.S3.Prefix,
+		AWSProfile: c.Storage.S3.Profile,
+	}
+}
+
+func (c *Config) OpenStore() (storage.Store, error) {
+	return storage.NewFromConfig(c.StorageConfig())
+}
+
+func (c *Config) Exporter() *otlp.Exporter {
+	if c.Tracing.Endpoint == "" {
+		return nil
+	}
+	return otlp.New(c.Tracing)
+}
+
+func (c *Config) Notifier() (*notify.Notifier, error) {
+	if c.Notifications == nil {
+		return nil, nil
+	}
+	cfg := *c.Notifications
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = c.Serve.URL
+	}
+	return notify.New(cfg)
+}
+
+func (c *Config) Commenter() (*prcomment.Commenter, error) {
+	if c.Comments == nil || !c.Comments.Enabled {
+		return nil, nil
+	}
+	cfg := *c.Comments
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = c.Serve.URL
+	}
+	return prcomment.New(cfg)
+}
+
+func (c *Config) PolicyEngine() (*policy.Engine, error) {
+	if len(c.Policies) == 0 {
+		return nil, nil
+	}
+	return policy.New(policy.Config{Rules: c.Policies})
+}
+
+func (c *Config) ApprovalGate() (*approval.Gate, error) {
+	if len(c.Approval) == 0 {
+		return nil, nil
+	}
+	return approval.New(approval.Config{Rules: c.Approval})
+}
+
+func (c *Config) Retrier() (*retry.Retrier, error) {
+	return retry.New(c.Retry)
+}
+
+func (c *Config) RedactString(s string) string {
+	for _, re := range c.redact {
+		s = redact(re, s)
+	}
+	return s
+}
+
+func (c *Config) RedactDiff(s string) string {
+	for _, re := range diffRedact {
+		s = redact(re, s)
+	}
+	return c.RedactString(s)
+}
+
+func redact(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllString(s, "[REDACTED]")
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		for i := 2; i < len(m); i += 2 {
+			if m[i] < 0 || m[i] < last {
+				continue
+			}
+			b.WriteString(s[last:m[i]])
+			b.WriteString("[REDACTED]")
+			last = m[i+1]
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+type Entry struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+func (c *Config) Entries() []Entry {
+	entries := make([]Entry, 0, len(c.Sources))
+	for key, source := range c.Sources {
+		entries = append(entries, Entry{Key: key, Value: lookup(c.values, key), Source: source})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return entries
+}
+
+func lookup(values map[string]any, key string) any {
+	var v any = values
+	for part := range strings.SplitSeq(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func parseInt(s string) (any, error) {
+	return strconv.Atoi(s)
+}
+
+func parseBool(s string) (any, error) {
+	return strconv.ParseBool(s)
+}
+
+func parseList(s string) (any, error) {
+	var list []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+func parseHeaders(s string) (any, error) {
+	headers := map[string]any{}
+	for k, v := range otlp.ParseHeaders(s) {
+		headers[k] = v
+	}
+	return headers, nil
+}
+
+func parseFile(field string) func(string) (any, error) {
+	return func(path string) (any, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var v map[string]any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if field == "" {
+			return v, nil
+		}
+		return v[field], nil
+	}
+}
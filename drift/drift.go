@@ -0,0 +1,82 @@
+package drift
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type Workspace struct {
+	Workspace   string        `json:"workspace"`
+	Status      run.Status    `json:"status"`
+	Since       time.Time     `json:"since,omitzero"`
+	LastChecked time.Time     `json:"last_checked"`
+	LastClean   time.Time     `json:"last_clean,omitzero"`
+	RunID       string        `json:"run_id"`
+	Checks      int           `json:"checks"`
+	Changes     *run.Changes  `json:"changes,omitempty"`
+	Resources   []run.Planned `json:"resources,omitempty"`
+}
+
+type Report struct {
+	Workspaces []Workspace `json:"workspaces"`
+	Drifted    int         `json:"drifted"`
+	Clean      int         `json:"clean"`
+}
+
+func IsCheck(r *run.Run) bool {
+	return r.Status == run.StatusDrift || r.Status == run.StatusClean
+}
+
+func Compute(runs []*run.Run) *Report {
+	byWorkspace := make(map[string][]*run.Run)
+	for _, r := range runs {
+		if IsCheck(r) {
+			byWorkspace[r.Workspace] = append(byWorkspace[r.Workspace], r)
+		}
+	}
+
+	report := &Report{Workspaces: []Workspace{}}
+	for name, checks := range byWorkspace {
+		sort.Slice(checks, func(i, j int) bool {
+			return checks[i].Timestamp.After(checks[j].Timestamp)
+		})
+
+		latest := checks[0]
+		ws := Workspace{
+			Workspace:   name,
+			Status:      latest.Status,
+			LastChecked: latest.Timestamp,
+			RunID:       latest.ID,
+			Checks:      len(checks),
+		}
+		for _, r := range checks {
+			if r.Status == run.StatusClean {
+				ws.LastClean = r.Timestamp
+				break
+			}
+			ws.Since = r.Timestamp
+		}
+		if latest.Status == run.StatusDrift {
+			ws.Changes = latest.Changes
+			ws.Resources = latest.Planned
+			report.Drifted++
+		} else {
+			report.Clean++
+		}
+		report.Workspaces = append(report.Workspaces, ws)
+	}
+
+	sort.Slice(report.Workspaces, func(i, j int) bool {
+		a, b := report.Workspaces[i], report.Workspaces[j]
+		if a.Status != b.Status {
+			return a.Status == run.StatusDrift
+		}
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		return a.Workspace < b.Workspace
+	})
+	return report
+}
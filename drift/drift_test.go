@@ -0,0 +1,67 @@
+package drift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestCompute(t *testing.T) {
+	base := time.Date(2025, 1, 20, 2, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	check := func(ws string, offset time.Duration, status run.Status, planned ...run.Planned) *run.Run {
+		ts := base.Add(offset)
+		return &run.Run{
+			ID:        run.GenerateID(ts),
+			Workspace: ws,
+			Timestamp: ts,
+			Status:    status,
+			Command:   []string{"terraform", "plan", "-detailed-exitcode"},
+			Planned:   planned,
+		}
+	}
+
+	runs := []*run.Run{
+		check("prod/vpc", 0, run.StatusClean),
+		check("prod/vpc", day, run.StatusDrift, run.Planned{Address: "aws_route.a", Action: "update"}),
+		check("prod/vpc", 2*day, run.StatusDrift, run.Planned{Address: "aws_route.a", Action: "update"}, run.Planned{Address: "aws_route.b", Action: "delete"}),
+		check("prod/db", 2*day, run.StatusDrift, run.Planned{Address: "aws_db_instance.main", Action: "update"}),
+		check("prod/web", day, run.StatusDrift),
+		check("prod/web", 2*day, run.StatusClean),
+		{ID: "run_apply", Workspace: "prod/api", Timestamp: base, Status: run.StatusSuccess, Command: []string{"terraform", "apply"}},
+	}
+
+	report := Compute(runs)
+	if report.Drifted != 2 || report.Clean != 1 || len(report.Workspaces) != 3 {
+		t.Fatalf("Compute() = drifted %d, clean %d, workspaces %d", report.Drifted, report.Clean, len(report.Workspaces))
+	}
+
+	vpc := report.Workspaces[0]
+	if vpc.Workspace != "prod/vpc" || vpc.Status != run.StatusDrift || vpc.Checks != 3 {
+		t.Errorf("Workspaces[0] = %+v", vpc)
+	}
+	if !vpc.Since.Equal(base.Add(day)) || !vpc.LastChecked.Equal(base.Add(2*day)) || !vpc.LastClean.Equal(base) {
+		t.Errorf("prod/vpc since = %v, last checked = %v, last clean = %v", vpc.Since, vpc.LastChecked, vpc.LastClean)
+	}
+	if len(vpc.Resources) != 2 {
+		t.Errorf("prod/vpc resources = %v, want 2 from the latest check", vpc.Resources)
+	}
+
+	db := report.Workspaces[1]
+	if db.Workspace != "prod/db" || !db.Since.Equal(base.Add(2*day)) || !db.LastClean.IsZero() {
+		t.Errorf("Workspaces[1] = %+v", db)
+	}
+
+	web := report.Workspaces[2]
+	if web.Workspace != "prod/web" || web.Status != run.StatusClean || !web.Since.IsZero() || web.Resources != nil {
+		t.Errorf("Workspaces[2] = %+v", web)
+	}
+}
+
+func TestCompute_Empty(t *testing.T) {
+	report := Compute(nil)
+	if report.Workspaces == nil || len(report.Workspaces) != 0 || report.Drifted != 0 {
+		t.Errorf("Compute(nil) = %+v", report)
+	}
+}
@@ -0,0 +1,235 @@
+package failure
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const (
+	_maxMessage   = 200
+	_maxSignature = 120
+	_tailLines    = 20
+)
+
+var transient = []string{run.FailureStateLock, run.FailureThrottling, run.FailureServerError, run.FailureTimeout}
+
+var rules = []struct {
+	category string
+	patterns []string
+}{
+	{run.FailureProviderBug, []string{
+		"provider produced inconsistent",
+		"provider produced an unexpected",
+		"produced an invalid new value",
+		"this is a bug in the provider",
+		"plugin did not respond",
+		"the plugin encountered an error",
+		"plugin exited",
+		"panic:",
+	}},
+	{run.FailureConfig, []string{
+		"unsupported argument",
+		"unsupported attribute",
+		"unsupported block type",
+		"missing required argument",
+		"missing required provider",
+		"invalid reference",
+		"reference to undeclared",
+		"invalid value for",
+		"invalid function argument",
+		"argument or block definition required",
+		"module not installed",
+		"inconsistent dependency lock file",
+		"no value for required variable",
+		"variables not allowed",
+		"duplicate resource",
+		"incorrect attribute value type",
+		"invalid expression",
+		"failed to query available provider packages",
+	}},
+	{run.FailureStateLock, []string{
+		"error acquiring the state lock",
+		"error locking state",
+		"error releasing the state lock",
+		"lock info:",
+	}},
+	{run.FailureThrottling, []string{
+		"throttling",
+		"throttled",
+		"rate exceeded",
+		"rate limit",
+		"ratelimit",
+		"too many requests",
+		"requestlimitexceeded",
+		"slowdown",
+		"status code: 429",
+		"statuscode: 429",
+		"quota exceeded",
+	}},
+	{run.FailureServerError, []string{
+		"internal server error",
+		"internalerror",
+		"bad gateway",
+		"service unavailable",
+		"serviceunavailable",
+		"gateway timeout",
+		"status code: 500",
+		"status code: 502",
+		"status code: 503",
+		"status code: 504",
+		"statuscode: 500",
+		"statuscode: 502",
+		"statuscode: 503",
+		"statuscode: 504",
+	}},
+	{run.FailureAuth, []string{
+		"no valid credential",
+		"accessdenied",
+		"access denied",
+		"unauthorized",
+		"not authorized",
+		"expiredtoken",
+		"invalidclienttokenid",
+		"security token",
+		"authentication failed",
+		"authorizationfailed",
+		"permission denied",
+		"forbidden",
+		"status code: 403",
+		"statuscode: 403",
+		"could not find default credentials",
+		"failed to refresh cached credentials",
+	}},
+	{run.FailureTimeout, []string{
+		"timeout while waiting",
+		"context deadline exceeded",
+		"timed out",
+		"i/o timeout",
+		"deadline exceeded",
+		"timeout",
+	}},
+}
+
+var (
+	boxPrefix   = regexp.MustCompile(`^[│╷╵\s]*`)
+	errorLine   = regexp.MustCompile(`^(?:Error|ERROR):\s*(.+)$`)
+	sourceLine  = regexp.MustCompile(`^(?:on \S+ line \d+\b|\d+:\s)`)
+	quoted      = regexp.MustCompile(`"[^"]*"|'[^']*'|` + "`[^`]*`")
+	identifiers = regexp.MustCompile(`(?i)\b(?:arn:[^\s,]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[a-z]+-[0-9a-f]{8,}|\d[\d.:]*)\b`)
+	whitespace  = regexp.MustCompile(`\s+`)
+)
+
+func Classify(output string) *run.Failure {
+	text, message := errorBlock(output)
+	f := &run.Failure{
+		Category:  category(strings.ToLower(text)),
+		Message:   truncate(message, _maxMessage),
+		Signature: Signature(message),
+	}
+	f.Transient = IsTransient(f.Category)
+	return f
+}
+
+func Signature(message string) string {
+	s := quoted.ReplaceAllString(message, `"…"`)
+	s = identifiers.ReplaceAllString(s, "N")
+	s = whitespace.ReplaceAllString(strings.TrimSpace(s), " ")
+	return truncate(s, _maxSignature)
+}
+
+func IsTransient(category string) bool {
+	return slices.Contains(transient, category)
+}
+
+func Fill(store storage.Store, runs []*run.Run) error {
+	for _, r := range runs {
+		if r.Status != run.StatusFailed || r.Failure != nil {
+			continue
+		}
+		output, err := store.GetOutput(r.ID)
+		if errors.Is(err, storage.ErrOutputNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		r.Failure = Classify(string(output))
+	}
+	return nil
+}
+
+func category(output string) string {
+	for _, rule := range rules {
+		for _, p := range rule.patterns {
+			if strings.Contains(output, p) {
+				return rule.category
+			}
+		}
+	}
+	return run.FailureUnknown
+}
+
+func errorBlock(output string) (string, string) {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(boxPrefix.ReplaceAllString(line, ""))
+	}
+
+	start := max(0, len(lines)-_tailLines)
+	message := lastLine(lines)
+	for i, line := range lines {
+		if m := errorLine.FindStringSubmatch(line); m != nil {
+			start, message = i, m[1]
+			break
+		}
+	}
+	return diagnostics(lines[start:]), message
+}
+
+func diagnostics(lines []string) string {
+	var text []string
+	warning, values := false, false
+	for _, line := range lines {
+		switch {
+		case errorLine.MatchString(line):
+			warning = false
+		case strings.HasPrefix(line, "Warning:"):
+			warning = true
+		case line == "":
+			values = false
+		case strings.HasPrefix(line, "├"):
+			values = true
+		}
+		if warning || values || line == "" || strings.HasPrefix(line, "├") || sourceLine.MatchString(line) {
+			continue
+		}
+		text = append(text, line)
+	}
+	return strings.Join(text, "\n")
+}
+
+func Diagnostics(output string) string {
+	text, _ := errorBlock(output)
+	return text
+}
+
+func lastLine(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i] != "" {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
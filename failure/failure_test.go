@@ -0,0 +1,217 @@
+package failure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		category  string
+		transient bool
+		message   string
+	}{
+		{
+			name: "state lock",
+			output: `Acquiring state lock. This may take a few moments...
+╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        1c2d3e4f-0000-1111-2222-333344445555
+╵`,
+			category:  run.FailureStateLock,
+			transient: true,
+			message:   "Error acquiring the state lock",
+		},
+		{
+			name: "throttling",
+			output: `aws_instance.web: Creating...
+╷
+│ Error: creating EC2 Instance: operation error EC2: RunInstances, https response error StatusCode: 400, RequestID: 5f6a, api error RequestLimitExceeded: Request limit exceeded.
+╵`,
+			category:  run.FailureThrottling,
+			transient: true,
+		},
+		{
+			name: "server error",
+			output: `╷
+│ Error: reading S3 Bucket (logs): operation error S3: HeadBucket, https response error StatusCode: 503, RequestID: 7c1d
+╵`,
+			category:  run.FailureServerError,
+			transient: true,
+		},
+		{
+			name: "auth",
+			output: `╷
+│ Error: No valid credential sources found
+│
+│ Please see https://registry.terraform.io/providers/hashicorp/aws
+╵`,
+			category: run.FailureAuth,
+			message:  "No valid credential sources found",
+		},
+		{
+			name: "provider bug",
+			output: `╷
+│ Error: Provider produced inconsistent result after apply
+│
+│ When applying changes to aws_s3_bucket.logs, provider produced an unexpected new value.
+╵`,
+			category: run.FailureProviderBug,
+		},
+		{
+			name: "config",
+			output: `╷
+│ Error: Unsupported argument
+│
+│   on main.tf line 12, in resource "aws_instance" "web":
+│   12:   instance_typo = "t3.micro"
+╵`,
+			category: run.FailureConfig,
+			message:  "Unsupported argument",
+		},
+		{
+			name: "config with transient-looking source",
+			output: `╷
+│ Error: Unsupported argument
+│
+│   on lambda.tf line 8, in resource "aws_lambda_function" "throttle_handler":
+│    8:   timeout_secs = 30
+│
+│ An argument named "timeout_secs" is not expected here.
+╵`,
+			category: run.FailureConfig,
+			message:  "Unsupported argument",
+		},
+		{
+			name: "quoted values are ignored",
+			output: `╷
+│ Error: Invalid count argument
+│
+│   on main.tf line 3, in resource "aws_instance" "web":
+│    3:   count = var.forbidden_count
+│     ├────────────────
+│     │ var.forbidden_count is "permission denied"
+│
+│ The "count" value depends on resource attributes that cannot be determined until apply.
+╵`,
+			category: run.FailureUnknown,
+			message:  "Invalid count argument",
+		},
+		{
+			name: "warnings are ignored",
+			output: `╷
+│ Error: Invalid for_each argument
+╵
+╷
+│ Warning: Argument is deprecated
+│
+│ Use timeout instead; requests may be throttled.
+╵`,
+			category: run.FailureUnknown,
+			message:  "Invalid for_each argument",
+		},
+		{
+			name: "timeout",
+			output: `╷
+│ Error: waiting for RDS DB Instance (prod-db) create: timeout while waiting for state to become 'available' (last state: 'creating', timeout: 40m0s)
+╵`,
+			category:  run.FailureTimeout,
+			transient: true,
+		},
+		{
+			name:     "unknown without diagnostics",
+			output:   "Plan: 1 to add, 0 to change, 0 to destroy.\nsegmentation fault\n",
+			category: run.FailureUnknown,
+			message:  "segmentation fault",
+		},
+		{
+			name: "timeout attribute in plan is ignored",
+			output: `  + resource "aws_lambda_function" "fn" {
+      + timeout = 30
+    }
+╷
+│ Error: Missing required argument
+╵`,
+			category: run.FailureConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Classify(tt.output)
+			if f.Category != tt.category || f.Transient != tt.transient {
+				t.Errorf("Classify() = %s (transient %v), want %s (transient %v)", f.Category, f.Transient, tt.category, tt.transient)
+			}
+			if tt.message != "" && f.Message != tt.message {
+				t.Errorf("Classify() message = %q, want %q", f.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	output := `aws_lambda_function.throttle_handler: Creating...
+╷
+│ Error: Unsupported argument
+│
+│   on lambda.tf line 8, in resource "aws_lambda_function" "throttle_handler":
+│    8:   timeout_secs = 30
+│
+│ An argument named "timeout_secs" is not expected here.
+╵`
+	got := Diagnostics(output)
+	if !strings.Contains(got, "Unsupported argument") || strings.Contains(got, "throttle_handler") {
+		t.Errorf("Diagnostics() = %q", got)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	a := Signature(`deleting EC2 Instance (i-0abc1234def567890): operation error EC2: TerminateInstances, StatusCode: 400, RequestID: "5f6a-77"`)
+	b := Signature(`deleting EC2 Instance (i-0fff9999aaa000111): operation error EC2: TerminateInstances, StatusCode: 503, RequestID: "a1b2-99"`)
+	if a != b {
+		t.Errorf("Signature() differs for the same error:\n%s\n%s", a, b)
+	}
+	if c := Signature("Unsupported argument"); c == a {
+		t.Errorf("Signature() collides for different errors: %s", c)
+	}
+}
+
+func TestFill(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	failed := &run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusFailed}
+	noOutput := &run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusFailed}
+	classified := &run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusFailed, Failure: &run.Failure{Category: run.FailureAuth}}
+	succeeded := &run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusSuccess}
+	for _, r := range []*run.Run{failed, classified, succeeded} {
+		if err := store.SaveOutput(r.ID, []byte("Error: Error acquiring the state lock\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Fill(store, []*run.Run{failed, noOutput, classified, succeeded}); err != nil {
+		t.Fatalf("Fill() error = %v", err)
+	}
+	if failed.Failure == nil || failed.Failure.Category != run.FailureStateLock {
+		t.Errorf("failed run = %+v, want state_lock", failed.Failure)
+	}
+	if noOutput.Failure != nil || succeeded.Failure != nil {
+		t.Errorf("Fill() classified runs without output or failure")
+	}
+	if classified.Failure.Category != run.FailureAuth {
+		t.Errorf("Fill() overwrote an existing classification")
+	}
+}
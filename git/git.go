@@ -1,89 +1,287 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Owloops/tfjournal/run"
 )
 
-const _gitTimeout = 2 * time.Second
+const (
+	_gitTimeout       = 2 * time.Second
+	_maxMessageLength = 72
+	_maxDirtyFiles    = 50
+)
 
-func GetInfo() *run.GitInfo {
-	if !isGitRepo() {
+func GetInfo(dir string) *run.GitInfo {
+	if !isGitRepo(dir) {
 		return nil
 	}
 
 	info := &run.GitInfo{
-		Commit:  getCommit(),
-		Branch:  getBranch(),
-		Dirty:   isDirty(),
-		Remote:  getRemote(),
-		Message: getMessage(),
+		Commit: getCommit(dir),
+		Branch: getBranch(dir),
+		Dirty:  isDirty(dir),
+		Tags:   getTags(dir),
 	}
 
 	if info.Commit == "" {
 		return nil
 	}
 
+	readCommit(dir, info)
+	info.Remote, info.RemoteURL = NormalizeRemote(runGit(dir, "remote", "get-url", "origin"))
+	if info.Dirty {
+		info.DirtyFiles = getDirtyFiles(dir)
+	}
+	if upstream := runGit(dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); upstream != "" {
+		info.Upstream = upstream
+		info.Ahead, info.Behind = parseAheadBehind(runGit(dir, "rev-list", "--left-right", "--count", "HEAD...@{upstream}"))
+	}
+
 	return info
 }
 
 func GetUser() (name, email string) {
-	name = runGit("config", "user.name")
-	email = runGit("config", "user.email")
+	name = runGit("", "config", "user.name")
+	email = runGit("", "config", "user.email")
 	return
 }
 
-func isGitRepo() bool {
-	return runGit("rev-parse", "--git-dir") != ""
+func isGitRepo(dir string) bool {
+	return runGit(dir, "rev-parse", "--git-dir") != ""
+}
+
+func getCommit(dir string) string {
+	return runGit(dir, "rev-parse", "--short", "HEAD")
+}
+
+func getBranch(dir string) string {
+	return runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func isDirty(dir string) bool {
+	return runGit(dir, "status", "--porcelain") != ""
+}
+
+func getTags(dir string) []string {
+	out := runGit(dir, "tag", "--points-at", "HEAD")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
 }
 
-func getCommit() string {
-	return runGit("rev-parse", "--short", "HEAD")
+func readCommit(dir string, info *run.GitInfo) {
+	out := runGit(dir, "log", "-1", "--format=%H%x00%an%x00%ae%x00%cn%x00%ce%x00%cI%x00%s")
+	fields := strings.Split(out, "\x00")
+	if len(fields) != 7 {
+		return
+	}
+
+	info.SHA = fields[0]
+	info.Author, info.AuthorEmail = fields[1], fields[2]
+	info.Committer, info.CommitterEmail = fields[3], fields[4]
+	if t, err := time.Parse(time.RFC3339, fields[5]); err == nil {
+		info.CommitTime = t
+	}
+	info.Message = fields[6]
+	if len(info.Message) > _maxMessageLength {
+		info.Message = info.Message[:_maxMessageLength] + "..."
+	}
 }
 
-func getBranch() string {
-	return runGit("rev-parse", "--abbrev-ref", "HEAD")
+func getDirtyFiles(dir string) []string {
+	return parsePorcelain(runGitRaw(dir, "status", "--porcelain", "-z", "--", "."))
 }
 
-func isDirty() bool {
-	return runGit("status", "--porcelain") != ""
+func parsePorcelain(out string) []string {
+	var files []string
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+		if len(files) == _maxDirtyFiles {
+			files = append(files, "...")
+			break
+		}
+		files = append(files, entry[3:])
+	}
+	return files
 }
 
-func getRemote() string {
-	remote := runGit("remote", "get-url", "origin")
-	remote = strings.TrimPrefix(remote, "git@github.com:")
-	remote = strings.TrimPrefix(remote, "https://github.com/")
-	remote = strings.TrimSuffix(remote, ".git")
-	return remote
+func parseAheadBehind(out string) (ahead, behind int) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	ahead, _ = strconv.Atoi(fields[0])
+	behind, _ = strconv.Atoi(fields[1])
+	return ahead, behind
 }
 
-func getMessage() string {
-	msg := runGit("log", "-1", "--format=%s")
-	if len(msg) > 72 {
-		return msg[:72] + "..."
+func NormalizeRemote(raw string) (remote, webURL string) {
+	if raw == "" {
+		return "", ""
+	}
+
+	var host, path string
+	u, err := url.Parse(raw)
+	if err == nil && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+		if u.Scheme == "https" || u.Scheme == "http" {
+			host = u.Host
+		}
+	} else if err == nil && u.Scheme == "file" {
+		return raw, ""
+	} else if at := strings.Index(raw, ":"); at > 0 && !strings.Contains(raw[:at], "/") {
+		host, path = raw[:at], raw[at+1:]
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+	} else {
+		return raw, ""
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+
+	switch {
+	case host == "ssh.dev.azure.com" || host == "vs-ssh.visualstudio.com":
+		parts := strings.Split(strings.TrimPrefix(path, "v3/"), "/")
+		if len(parts) != 3 {
+			return path, ""
+		}
+		if host == "vs-ssh.visualstudio.com" {
+			return strings.Join(parts, "/"), "https://" + parts[0] + ".visualstudio.com/" + parts[1] + "/_git/" + parts[2]
+		}
+		return strings.Join(parts, "/"), "https://dev.azure.com/" + strings.Join(parts[:2], "/") + "/_git/" + parts[2]
+	case host == "dev.azure.com":
+		return strings.Replace(path, "/_git/", "/", 1), "https://" + host + "/" + path
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		org := strings.TrimSuffix(host, ".visualstudio.com")
+		project := strings.TrimPrefix(path, "DefaultCollection/")
+		return org + "/" + strings.Replace(project, "/_git/", "/", 1), "https://" + host + "/" + path
 	}
-	return msg
+
+	return path, "https://" + host + "/" + path
+}
+
+func Diff(dir string, maxBytes int) ([]byte, bool) {
+	limit := int64(-1)
+	if maxBytes > 0 {
+		limit = int64(maxBytes)
+	}
+
+	var b bytes.Buffer
+	out, full := runGitLimited(dir, limit, "diff", "--no-color", "--no-ext-diff", "HEAD", "--", ".")
+	b.Write(out)
+	for _, path := range untrackedFiles(dir) {
+		if full {
+			break
+		}
+		remaining := limit
+		if limit >= 0 {
+			remaining -= int64(b.Len())
+		}
+		out, full = runGitLimited(dir, remaining, "diff", "--no-color", "--no-ext-diff", "--no-index", "--", os.DevNull, path)
+		b.Write(out)
+	}
+
+	diff := b.Bytes()
+	if full {
+		if i := bytes.LastIndexByte(diff, '\n'); i >= 0 {
+			diff = diff[:i+1]
+		}
+	}
+	return diff, full
+}
+
+func untrackedFiles(dir string) []string {
+	var files []string
+	for _, path := range strings.Split(runGitRaw(dir, "ls-files", "--others", "--exclude-standard", "-z", "--", "."), "\x00") {
+		if path == "" {
+			continue
+		}
+		if len(files) == _maxDirtyFiles {
+			break
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+func runGitLimited(dir string, limit int64, args ...string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), _gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", gitArgs(dir, args)...)
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, false
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, false
+	}
+
+	var r io.Reader = stdout
+	if limit >= 0 {
+		r = io.LimitReader(stdout, limit+1)
+	}
+	out, _ := io.ReadAll(r)
+	full := limit >= 0 && int64(len(out)) > limit
+	if full {
+		out = out[:limit]
+		_ = cmd.Process.Kill()
+	}
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if !full && err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != 1) {
+		return nil, false
+	}
+	return out, full
 }
 
 func GetRepoRoot() string {
-	return runGit("rev-parse", "--show-toplevel")
+	return runGit("", "rev-parse", "--show-toplevel")
+}
+
+func runGit(dir string, args ...string) string {
+	return strings.TrimSpace(runGitRaw(dir, args...))
+}
+
+func gitArgs(dir string, args []string) []string {
+	if dir == "" {
+		return args
+	}
+	return append([]string{"-C", dir}, args...)
 }
 
-func runGit(args ...string) string {
+func runGitRaw(dir string, args ...string) string {
 	ctx, cancel := context.WithTimeout(context.Background(), _gitTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd := exec.CommandContext(ctx, "git", gitArgs(dir, args)...)
 	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
 
 	out, err := cmd.Output()
 	if err != nil {
 		return ""
 	}
-	return strings.TrimSpace(string(out))
+	return string(out)
 }
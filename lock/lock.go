@@ -0,0 +1,137 @@
+package lock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const (
+	HolderRunning = "running"
+	HolderStale   = "stale"
+	HolderUnknown = "unknown"
+
+	_lookback = 24 * time.Hour
+	_skew     = time.Minute
+)
+
+type Holder struct {
+	State     string     `json:"state"`
+	RunID     string     `json:"run_id,omitempty"`
+	User      string     `json:"user,omitempty"`
+	Workspace string     `json:"workspace,omitempty"`
+	Status    run.Status `json:"status,omitempty"`
+	Started   time.Time  `json:"started,omitzero"`
+	Finished  time.Time  `json:"finished,omitzero"`
+	Message   string     `json:"message"`
+}
+
+type Report struct {
+	Lock   *run.LockInfo `json:"lock"`
+	Holder *Holder       `json:"holder"`
+}
+
+func Lookup(store storage.Store, r *run.Run) (*Report, error) {
+	if r.Lock == nil {
+		return nil, nil
+	}
+
+	since := r.Timestamp
+	if !r.Lock.Created.IsZero() {
+		since = r.Lock.Created
+	}
+	candidates, err := store.ListRuns(storage.ListOptions{Since: since.Add(-_lookback)})
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Lock: r.Lock, Holder: Find(r, candidates)}, nil
+}
+
+func Find(r *run.Run, candidates []*run.Run) *Holder {
+	var best *run.Run
+	var bestGap time.Duration
+	for _, c := range candidates {
+		if c.ID == r.ID || c.Lock != nil || !holds(r, c) {
+			continue
+		}
+		gap := r.Lock.Created.Sub(c.Timestamp).Abs()
+		if best == nil || gap < bestGap {
+			best, bestGap = c, gap
+		}
+	}
+
+	if best == nil {
+		return &Holder{
+			State:   HolderUnknown,
+			Message: fmt.Sprintf("no recorded run holds this lock; %s ran without tfjournal or outside this journal", who(r.Lock)),
+		}
+	}
+
+	h := &Holder{
+		RunID:     best.ID,
+		User:      best.User,
+		Workspace: best.Workspace,
+		Status:    best.Status,
+		Started:   best.Timestamp,
+	}
+	if best.Status != run.StatusRunning {
+		h.Finished = best.Timestamp.Add(best.Duration())
+	}
+
+	switch {
+	case best.Status == run.StatusRunning:
+		h.State = HolderRunning
+		h.Message = fmt.Sprintf("locked by run %s from %s that is still running", best.ID, holderUser(best, r.Lock))
+	case h.Finished.After(r.Timestamp):
+		h.State = HolderRunning
+		h.Message = fmt.Sprintf("locked by run %s from %s that was still running at the time; it finished %s (%s)",
+			best.ID, holderUser(best, r.Lock), h.Finished.Format("2006-01-02 15:04:05"), best.Status)
+	default:
+		h.State = HolderStale
+		h.Message = fmt.Sprintf("locked by run %s from %s that ended %s (%s) without releasing the lock; it likely crashed or was killed",
+			best.ID, holderUser(best, r.Lock), h.Finished.Format("2006-01-02 15:04:05"), best.Status)
+	}
+	return h
+}
+
+func holds(r, c *run.Run) bool {
+	if !samePath(r, c) {
+		return false
+	}
+	created := r.Lock.Created
+	if created.IsZero() {
+		return c.Status == run.StatusRunning && c.Timestamp.Before(r.Timestamp)
+	}
+	if c.Timestamp.After(created.Add(_skew)) {
+		return false
+	}
+	return c.Status == run.StatusRunning || !c.Timestamp.Add(c.Duration()).Before(created.Add(-_skew))
+}
+
+func samePath(r, c *run.Run) bool {
+	if r.Lock.Path == "" || c.State == nil || c.State.ID == "" {
+		return c.Workspace == r.Workspace
+	}
+	id := c.State.ID
+	if _, rest, ok := strings.Cut(id, "://"); ok {
+		id = rest
+	}
+	return id == r.Lock.Path || strings.HasSuffix(id, "/"+r.Lock.Path) || strings.HasSuffix(r.Lock.Path, "/"+id)
+}
+
+func holderUser(c *run.Run, lock *run.LockInfo) string {
+	if c.User != "" {
+		return c.User
+	}
+	return who(lock)
+}
+
+func who(lock *run.LockInfo) string {
+	if lock.Who != "" {
+		return lock.Who
+	}
+	return "the holder"
+}
@@ -0,0 +1,80 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestFind(t *testing.T) {
+	created := time.Date(2025, 1, 24, 2, 0, 5, 0, time.UTC)
+	state := &run.StateInfo{Backend: "s3", ID: "s3://acme-state/prod/vpc.tfstate"}
+	blocked := &run.Run{
+		ID:        "run_blocked",
+		Workspace: "prod/vpc",
+		Timestamp: created.Add(10 * time.Minute),
+		Status:    run.StatusFailed,
+		Lock:      &run.LockInfo{ID: "6f1c", Path: "acme-state/prod/vpc.tfstate", Who: "alice@laptop", Created: created},
+	}
+	holder := func(id string, start time.Time, d time.Duration, status run.Status) *run.Run {
+		return &run.Run{ID: id, Workspace: "prod/vpc", User: "alice", Timestamp: start, DurationMs: d.Milliseconds(), Status: status, State: state}
+	}
+
+	tests := []struct {
+		name       string
+		candidates []*run.Run
+		state      string
+		runID      string
+	}{
+		{
+			name:       "still running when blocked",
+			candidates: []*run.Run{holder("run_a", created.Add(-5*time.Second), time.Hour, run.StatusSuccess)},
+			state:      HolderRunning,
+			runID:      "run_a",
+		},
+		{
+			name:       "ended before the blocked run",
+			candidates: []*run.Run{holder("run_a", created.Add(-5*time.Second), 2*time.Minute, run.StatusCanceled)},
+			state:      HolderStale,
+			runID:      "run_a",
+		},
+		{
+			name: "closest start wins",
+			candidates: []*run.Run{
+				holder("run_old", created.Add(-time.Hour), 2*time.Hour, run.StatusSuccess),
+				holder("run_new", created.Add(-2*time.Second), time.Hour, run.StatusSuccess),
+			},
+			state: HolderRunning,
+			runID: "run_new",
+		},
+		{
+			name: "other state, later start and failed lock attempts are ignored",
+			candidates: []*run.Run{
+				{ID: "run_other", Workspace: "prod/db", Timestamp: created.Add(-time.Second), DurationMs: time.Hour.Milliseconds(), State: &run.StateInfo{ID: "s3://acme-state/prod/db.tfstate"}},
+				holder("run_later", created.Add(5*time.Minute), time.Hour, run.StatusSuccess),
+				{ID: "run_waiting", Workspace: "prod/vpc", Timestamp: created, DurationMs: 1000, State: state, Lock: &run.LockInfo{}},
+				blocked,
+			},
+			state: HolderUnknown,
+		},
+		{
+			name:       "workspace fallback without state",
+			candidates: []*run.Run{{ID: "run_a", Workspace: "prod/vpc", Timestamp: created.Add(-time.Second), DurationMs: time.Hour.Milliseconds()}},
+			state:      HolderRunning,
+			runID:      "run_a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Find(blocked, tt.candidates)
+			if h.State != tt.state || h.RunID != tt.runID {
+				t.Errorf("Find() = %s %q, want %s %q", h.State, h.RunID, tt.state, tt.runID)
+			}
+			if h.Message == "" {
+				t.Error("Find() returned no message")
+			}
+		})
+	}
+}
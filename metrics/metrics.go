@@ -0,0 +1,316 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const _fullRefresh = 10 * time.Minute
+
+var (
+	runDurationBuckets      = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+	resourceDurationBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 600, 1800}
+)
+
+type Registry struct {
+	mu sync.Mutex
+
+	runs              *counterVec
+	runDurations      *histogramVec
+	resourceDurations *histogramVec
+	syncErrors        *counterVec
+	refreshErrors     *counterVec
+
+	counted   map[string]time.Time
+	refreshed time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		runs: newCounterVec("tfjournal_runs_total",
+			"Recorded runs by status, action, workspace and program.",
+			"status", "action", "workspace", "program"),
+		runDurations: newHistogramVec("tfjournal_run_duration_seconds",
+			"Duration of recorded runs.",
+			runDurationBuckets, "status", "action", "workspace", "program"),
+		resourceDurations: newHistogramVec("tfjournal_resource_operation_duration_seconds",
+			"Duration of resource operations parsed from run output.",
+			resourceDurationBuckets, "action", "type", "workspace"),
+		syncErrors: newCounterVec("tfjournal_sync_errors_total",
+			"Runs that failed to sync to remote storage."),
+		refreshErrors: newCounterVec("tfjournal_store_refresh_errors_total",
+			"Failed attempts to load runs from the store."),
+		counted: make(map[string]time.Time),
+	}
+}
+
+func (m *Registry) observeRun(r *run.Run) bool {
+	if r.Status == run.StatusRunning {
+		return false
+	}
+	if _, ok := m.counted[r.ID]; ok {
+		return false
+	}
+	m.counted[r.ID] = r.Timestamp
+
+	labels := []string{string(r.Status), r.Action(), r.Workspace, r.Program}
+	m.runs.add(1, labels...)
+	m.runDurations.observe(r.Duration().Seconds(), labels...)
+
+	for _, res := range r.Resources {
+		if res.DurationMs <= 0 {
+			continue
+		}
+		seconds := (time.Duration(res.DurationMs) * time.Millisecond).Seconds()
+		m.resourceDurations.observe(seconds, res.Action, res.Type(), r.Workspace)
+	}
+	return true
+}
+
+func (m *Registry) ObserveSync(result *storage.SyncResult, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err != nil:
+		m.syncErrors.add(1)
+	case result != nil && result.Errors > 0:
+		m.syncErrors.add(float64(result.Errors))
+	}
+}
+
+func (m *Registry) Refresh(store storage.Store) (int, error) {
+	runs, err := store.ListRunsLocal(storage.ListOptions{})
+	if err != nil {
+		m.refreshFailed()
+		return 0, err
+	}
+
+	m.mu.Lock()
+	due := time.Since(m.refreshed) >= _fullRefresh
+	m.mu.Unlock()
+
+	var all []*run.Run
+	if due {
+		if all, err = store.ListRuns(storage.ListOptions{}); err != nil {
+			m.refreshFailed()
+			all = nil
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	added := 0
+	for _, r := range append(runs, all...) {
+		if m.observeRun(r) {
+			added++
+		}
+	}
+	if all != nil {
+		m.forget(all)
+		m.refreshed = time.Now()
+	}
+	return added, nil
+}
+
+func (m *Registry) forget(all []*run.Run) {
+	if len(all) == 0 {
+		return
+	}
+	present := make(map[string]bool, len(all))
+	oldest := all[0].Timestamp
+	for _, r := range all {
+		present[r.ID] = true
+		if r.Timestamp.Before(oldest) {
+			oldest = r.Timestamp
+		}
+	}
+	for id, ts := range m.counted {
+		if !present[id] && ts.Before(oldest) {
+			delete(m.counted, id)
+		}
+	}
+}
+
+func (m *Registry) refreshFailed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshErrors.add(1)
+}
+
+func (m *Registry) Watch(ctx context.Context, store storage.Store, interval time.Duration) {
+	_, _ = m.Refresh(store)
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = m.Refresh(store)
+		}
+	}
+}
+
+func (m *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+func (m *Registry) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	m.runs.write(bw)
+	m.runDurations.write(bw)
+	m.resourceDurations.write(bw)
+	m.syncErrors.write(bw)
+	m.refreshErrors.write(bw)
+	return bw.Flush()
+}
+
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	series map[string]*counter
+}
+
+type counter struct {
+	labelValues []string
+	value       float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, series: make(map[string]*counter)}
+}
+
+func (c *counterVec) add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+	s, ok := c.series[key]
+	if !ok {
+		s = &counter{labelValues: labelValues}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	if len(c.labels) == 0 {
+		var v float64
+		if s, ok := c.series[""]; ok {
+			v = s.value
+		}
+		_, _ = fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(v))
+		return
+	}
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		_, _ = fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, s.labelValues), formatFloat(s.value))
+	}
+}
+
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	bucketLabels := append(append([]string{}, h.labels...), "le")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			values := append(append([]string{}, s.labelValues...), formatFloat(upper))
+			_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(bucketLabels, values), s.counts[i])
+		}
+		values := append(append([]string{}, s.labelValues...), "+Inf")
+		_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(bucketLabels, values), s.count)
+		_, _ = fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labelValues), formatFloat(s.sum))
+		_, _ = fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labelValues), s.count)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
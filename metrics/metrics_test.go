@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+func TestRegistry_ObserveRun(t *testing.T) {
+	m := NewRegistry()
+	ts := time.Now()
+
+	r := &run.Run{
+		ID:         run.GenerateID(ts),
+		Workspace:  "prod",
+		Timestamp:  ts,
+		DurationMs: 45000,
+		Status:     run.StatusSuccess,
+		Program:    "terraform",
+		Command:    []string{"terraform", "apply"},
+		Resources: []run.Resource{
+			{Address: "module.vpc.aws_subnet.a", Action: "create", DurationMs: 3000},
+			{Address: "aws_instance.web", Action: "update"},
+		},
+	}
+
+	m.observeRun(r)
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		"# TYPE tfjournal_runs_total counter",
+		`tfjournal_runs_total{status="success",action="apply",workspace="prod",program="terraform"} 1`,
+		"# TYPE tfjournal_run_duration_seconds histogram",
+		`tfjournal_run_duration_seconds_bucket{status="success",action="apply",workspace="prod",program="terraform",le="30"} 0`,
+		`tfjournal_run_duration_seconds_bucket{status="success",action="apply",workspace="prod",program="terraform",le="60"} 1`,
+		`tfjournal_run_duration_seconds_bucket{status="success",action="apply",workspace="prod",program="terraform",le="+Inf"} 1`,
+		`tfjournal_run_duration_seconds_sum{status="success",action="apply",workspace="prod",program="terraform"} 45`,
+		`tfjournal_resource_operation_duration_seconds_count{action="create",type="aws_subnet",workspace="prod"} 1`,
+		"tfjournal_sync_errors_total 0",
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `type="aws_instance"`) {
+		t.Error("resources without duration should not be observed")
+	}
+}
+
+func TestRegistry_ObserveSync(t *testing.T) {
+	m := NewRegistry()
+	m.ObserveSync(&storage.SyncResult{Uploaded: 3, Errors: 2}, nil)
+	m.ObserveSync(nil, errors.New("boom"))
+	m.ObserveSync(&storage.SyncResult{Uploaded: 1}, nil)
+
+	var buf bytes.Buffer
+	_ = m.Write(&buf)
+	if !strings.Contains(buf.String(), "tfjournal_sync_errors_total 3\n") {
+		t.Errorf("expected 3 sync errors, got:\n%s", buf.String())
+	}
+}
+
+func TestRegistry_Refresh(t *testing.T) {
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	ts := time.Now().Add(-time.Hour)
+	first := &run.Run{ID: run.GenerateID(ts), Workspace: "dev", Timestamp: ts, Status: run.StatusFailed}
+	running := &run.Run{ID: run.GenerateID(time.Now()), Workspace: "dev", Timestamp: time.Now(), Status: run.StatusRunning}
+	for _, r := range []*run.Run{first, running} {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+
+	m := NewRegistry()
+	if added, err := m.Refresh(store); err != nil || added != 1 {
+		t.Fatalf("Refresh() = %d, %v, want 1, nil", added, err)
+	}
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	late := &run.Run{ID: run.GenerateID(old), Workspace: "dev", Timestamp: old, Status: run.StatusFailed}
+	running.Status = run.StatusSuccess
+	first.Workspace = "prod"
+	for _, r := range []*run.Run{late, running, first} {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+	if added, err := m.Refresh(store); err != nil || added != 2 {
+		t.Fatalf("Refresh() = %d, %v, want 2, nil", added, err)
+	}
+
+	if err := store.DeleteRun(late.ID); err != nil {
+		t.Fatal(err)
+	}
+	m.refreshed = time.Time{}
+	if added, err := m.Refresh(store); err != nil || added != 0 {
+		t.Fatalf("Refresh() = %d, %v, want 0, nil", added, err)
+	}
+	if len(m.counted) != 2 {
+		t.Errorf("counted = %d runs, want the deleted run forgotten", len(m.counted))
+	}
+
+	var buf bytes.Buffer
+	_ = m.Write(&buf)
+	out := buf.String()
+	want := []string{
+		`tfjournal_runs_total{status="failed",action="",workspace="dev",program=""} 2`,
+		`tfjournal_runs_total{status="success",action="",workspace="dev",program=""} 1`,
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `status="running"`) || strings.Contains(out, `workspace="prod"`) {
+		t.Errorf("running runs and relabels should not be counted:\n%s", out)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("escapeLabel() = %s", got)
+	}
+}
@@ -0,0 +1,114 @@
+package naming
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_environmentGroup = "environment"
+	_componentGroup   = "component"
+)
+
+type Config struct {
+	Aliases map[string]string `json:"aliases,omitempty"`
+	Rules   []Rule            `json:"rules,omitempty"`
+}
+
+type Rule struct {
+	Match       string `json:"match"`
+	Replace     string `json:"replace,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Component   string `json:"component,omitempty"`
+
+	re *regexp.Regexp
+}
+
+type Result struct {
+	Workspace   string
+	Environment string
+	Component   string
+}
+
+type Mapper struct {
+	aliases map[string]string
+	rules   []Rule
+}
+
+func New(cfg Config) (*Mapper, error) {
+	rules := make([]Rule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("workspace rule %d: missing match", i+1)
+		}
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("workspace rule %d: invalid match %q: %w", i+1, rule.Match, err)
+		}
+		rule.re = re
+		rules[i] = rule
+	}
+	return &Mapper{aliases: cfg.Aliases, rules: rules}, nil
+}
+
+func (m *Mapper) Map(name string) Result {
+	res := Result{Workspace: name}
+	if m == nil {
+		return res
+	}
+
+	if alias, ok := m.aliases[name]; ok {
+		res.Workspace = alias
+	}
+
+	for _, rule := range m.rules {
+		match := rule.re.FindStringSubmatchIndex(res.Workspace)
+		if match == nil {
+			continue
+		}
+
+		src := res.Workspace
+		if rule.Replace != "" {
+			res.Workspace = src[:match[0]] + string(rule.re.ExpandString(nil, rule.Replace, src, match)) + src[match[1]:]
+		}
+		res.Environment = extract(rule.re, rule.Environment, _environmentGroup, src, match)
+		res.Component = extract(rule.re, rule.Component, _componentGroup, src, match)
+		break
+	}
+
+	return res
+}
+
+func extract(re *regexp.Regexp, template, group, src string, match []int) string {
+	if template != "" {
+		return string(re.ExpandString(nil, template, src, match))
+	}
+	if idx := re.SubexpIndex(group); idx >= 0 && match[2*idx] >= 0 {
+		return src[match[2*idx]:match[2*idx+1]]
+	}
+	return ""
+}
+
+func (m *Mapper) Apply(r *run.Run) bool {
+	raw := r.RawWorkspace
+	if raw == "" {
+		raw = r.Workspace
+	}
+
+	res := m.Map(raw)
+	if raw == res.Workspace {
+		raw = ""
+	}
+	if r.Workspace == res.Workspace && r.Environment == res.Environment &&
+		r.Component == res.Component && r.RawWorkspace == raw {
+		return false
+	}
+
+	r.Workspace = res.Workspace
+	r.Environment = res.Environment
+	r.Component = res.Component
+	r.RawWorkspace = raw
+	return true
+}
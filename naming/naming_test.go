@@ -0,0 +1,83 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestMapper_Map(t *testing.T) {
+	m, err := New(Config{
+		Aliases: map[string]string{
+			"vpc":  "prod/vpc",
+			"prod": "prod/vpc",
+		},
+		Rules: []Rule{
+			{Match: `^live/(?P<environment>[^/]+)/(?P<component>.+)$`, Replace: "${environment}/${component}"},
+			{Match: `^stacks/([^-]+)-(.+)$`, Replace: "$2/$1", Environment: "$2", Component: "$1"},
+			{Match: `^(?P<environment>prod|staging|dev)/(?P<component>.+)$`},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want Result
+	}{
+		{"live/prod/vpc", Result{"prod/vpc", "prod", "vpc"}},
+		{"vpc", Result{"prod/vpc", "prod", "vpc"}},
+		{"prod", Result{"prod/vpc", "prod", "vpc"}},
+		{"stacks/db-staging", Result{"staging/db", "staging", "db"}},
+		{"dev/network/subnets", Result{"dev/network/subnets", "dev", "network/subnets"}},
+		{"sandbox", Result{"sandbox", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Map(tt.name); got != tt.want {
+				t.Errorf("Map(%q) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapper_Nil(t *testing.T) {
+	var m *Mapper
+	if got := m.Map("prod"); got != (Result{Workspace: "prod"}) {
+		t.Errorf("Map() = %+v", got)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{Rules: []Rule{{Match: "("}}}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	if _, err := New(Config{Rules: []Rule{{Replace: "x"}}}); err == nil {
+		t.Error("expected error for missing match")
+	}
+}
+
+func TestMapper_Apply(t *testing.T) {
+	m, err := New(Config{Rules: []Rule{{Match: `^live/(?P<environment>[^/]+)/(?P<component>.+)$`, Replace: "${environment}/${component}"}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := &run.Run{Workspace: "live/prod/vpc"}
+	if !m.Apply(r) {
+		t.Fatal("expected Apply() to change the run")
+	}
+	if r.Workspace != "prod/vpc" || r.RawWorkspace != "live/prod/vpc" || r.Environment != "prod" || r.Component != "vpc" {
+		t.Errorf("run = %+v", r)
+	}
+	if m.Apply(r) {
+		t.Error("Apply() should be idempotent")
+	}
+
+	unchanged := &run.Run{Workspace: "sandbox"}
+	if m.Apply(unchanged) || unchanged.RawWorkspace != "" {
+		t.Errorf("unmatched run changed: %+v", unchanged)
+	}
+}
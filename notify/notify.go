@@ -0,0 +1,335 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+
+	_defaultAttempts = 3
+	_defaultBackoff  = time.Second
+	_requestTimeout  = 10 * time.Second
+
+	_defaultTemplate = `{{.Icon}} {{.Run.Program}} {{.Action}} {{.Run.Status}} in {{.Run.Workspace}} ({{.Changes}}){{if .Run.User}} by {{.Run.User}}{{end}}`
+)
+
+type Config struct {
+	BaseURL  string             `json:"base_url,omitempty"`
+	Channels map[string]Channel `json:"channels"`
+	Rules    []Rule             `json:"rules"`
+	Retry    Retry              `json:"retry"`
+}
+
+type Channel struct {
+	Type     string            `json:"type"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Template string            `json:"template,omitempty"`
+}
+
+type Rule struct {
+	Name       string       `json:"name"`
+	Workspaces []string     `json:"workspaces,omitempty"`
+	Actions    []string     `json:"actions,omitempty"`
+	Statuses   []run.Status `json:"statuses,omitempty"`
+	MinAdd     int          `json:"min_add,omitempty"`
+	MinChange  int          `json:"min_change,omitempty"`
+	MinDestroy int          `json:"min_destroy,omitempty"`
+	Channels   []string     `json:"channels"`
+	Template   string       `json:"template,omitempty"`
+}
+
+type Retry struct {
+	Attempts int    `json:"attempts,omitempty"`
+	Backoff  string `json:"backoff,omitempty"`
+}
+
+type Notifier struct {
+	cfg     Config
+	backoff time.Duration
+	client  *http.Client
+	sleep   func(context.Context, time.Duration) error
+}
+
+type Message struct {
+	Run     *run.Run
+	Rule    string
+	Action  string
+	Changes string
+	Icon    string
+	URL     string
+}
+
+func New(cfg Config) (*Notifier, error) {
+	if cfg.Retry.Attempts <= 0 {
+		cfg.Retry.Attempts = _defaultAttempts
+	}
+
+	backoff := _defaultBackoff
+	if cfg.Retry.Backoff != "" {
+		d, err := time.ParseDuration(cfg.Retry.Backoff)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry backoff %q: %w", cfg.Retry.Backoff, err)
+		}
+		backoff = d
+	}
+
+	for name, ch := range cfg.Channels {
+		switch ch.Type {
+		case ChannelWebhook, ChannelSlack, ChannelTeams:
+		default:
+			return nil, fmt.Errorf("channel %q: unknown type %q (must be webhook, slack or teams)", name, ch.Type)
+		}
+		if ch.URL == "" {
+			return nil, fmt.Errorf("channel %q: missing url", name)
+		}
+		if _, err := parseTemplate(ch.Template); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", name, err)
+		}
+	}
+
+	for i, rule := range cfg.Rules {
+		if len(rule.Channels) == 0 {
+			return nil, fmt.Errorf("rule %q: no channels", ruleName(rule, i))
+		}
+		for _, name := range rule.Channels {
+			if _, ok := cfg.Channels[name]; !ok {
+				return nil, fmt.Errorf("rule %q: unknown channel %q", ruleName(rule, i), name)
+			}
+		}
+		if _, err := parseTemplate(rule.Template); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", ruleName(rule, i), err)
+		}
+	}
+
+	return &Notifier{
+		cfg:     cfg,
+		backoff: backoff,
+		client:  &http.Client{Timeout: _requestTimeout},
+		sleep:   sleepContext,
+	}, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, r *run.Run) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i, rule := range n.cfg.Rules {
+		if !rule.Matches(r) {
+			continue
+		}
+		for _, name := range rule.Channels {
+			wg.Go(func() {
+				if err := n.deliver(ctx, n.cfg.Channels[name], rule, i, r); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("notification %q to %s: %w", ruleName(rule, i), name, err))
+					mu.Unlock()
+				}
+			})
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (rule Rule) Matches(r *run.Run) bool {
+	if len(rule.Workspaces) > 0 && !slices.ContainsFunc(rule.Workspaces, func(p string) bool {
+		matched, _ := path.Match(p, r.Workspace)
+		return matched
+	}) {
+		return false
+	}
+	if len(rule.Actions) > 0 && !slices.Contains(rule.Actions, r.Action()) {
+		return false
+	}
+	if len(rule.Statuses) > 0 && !slices.Contains(rule.Statuses, r.Status) {
+		return false
+	}
+
+	var add, change, destroy int
+	if r.Changes != nil {
+		add, change, destroy = r.Changes.Add, r.Changes.Change, r.Changes.Destroy
+	}
+	return add >= rule.MinAdd && change >= rule.MinChange && destroy >= rule.MinDestroy
+}
+
+func (n *Notifier) deliver(ctx context.Context, ch Channel, rule Rule, idx int, r *run.Run) error {
+	msg := n.message(rule, idx, r)
+
+	tmplText := rule.Template
+	if tmplText == "" {
+		tmplText = ch.Template
+	}
+	tmpl, err := parseTemplate(tmplText)
+	if err != nil {
+		return err
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	body, err := json.Marshal(payload(ch.Type, text.String(), msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	var lastErr error
+	backoff := n.backoff
+	for attempt := 1; attempt <= n.cfg.Retry.Attempts; attempt++ {
+		retry, err := n.post(ctx, ch, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == n.cfg.Retry.Attempts {
+			break
+		}
+		if err := n.sleep(ctx, backoff); err != nil {
+			break
+		}
+		backoff *= 2
+	}
+	return lastErr
+}
+
+func (n *Notifier) post(ctx context.Context, ch Channel, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, _requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range ch.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("endpoint returned %s", resp.Status)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (n *Notifier) message(rule Rule, idx int, r *run.Run) Message {
+	msg := Message{
+		Run:     r,
+		Rule:    ruleName(rule, idx),
+		Action:  r.Action(),
+		Changes: r.ChangeSummary(),
+		Icon:    "✓",
+	}
+	switch {
+	case r.Status == run.StatusDrift:
+		msg.Icon = "⚠"
+	case !r.Status.Succeeded():
+		msg.Icon = "✗"
+	}
+	if n.cfg.BaseURL != "" {
+		msg.URL = strings.TrimSuffix(n.cfg.BaseURL, "/") + "/?run=" + url.QueryEscape(r.ID)
+	}
+	return msg
+}
+
+func payload(channelType, text string, msg Message) any {
+	switch channelType {
+	case ChannelSlack:
+		if msg.URL != "" {
+			text += fmt.Sprintf(" <%s|%s>", msg.URL, msg.Run.ID)
+		}
+		return map[string]string{"text": text}
+	case ChannelTeams:
+		card := map[string]any{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    text,
+			"title":      "tfjournal: " + msg.Run.Workspace,
+			"text":       text,
+			"themeColor": themeColor(msg.Run.Status),
+		}
+		if msg.URL != "" {
+			card["potentialAction"] = []map[string]any{{
+				"@type":   "OpenUri",
+				"name":    "View run",
+				"targets": []map[string]string{{"os": "default", "uri": msg.URL}},
+			}}
+		}
+		return card
+	default:
+		return map[string]any{
+			"event":   "run.completed",
+			"rule":    msg.Rule,
+			"message": text,
+			"url":     msg.URL,
+			"run":     msg.Run,
+		}
+	}
+}
+
+func themeColor(s run.Status) string {
+	switch {
+	case s == run.StatusDrift:
+		return "dbab09"
+	case s.Succeeded():
+		return "2ea44f"
+	}
+	return "d73a49"
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = _defaultTemplate
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func ruleName(rule Rule, idx int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("rule %d", idx+1)
+}
@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type hookStub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   []map[string]any
+	statuses []int
+}
+
+func newHookStub(t *testing.T, statuses ...int) *hookStub {
+	h := &hookStub{statuses: statuses}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.bodies = append(h.bodies, body)
+
+		status := http.StatusOK
+		if len(h.statuses) > 0 {
+			status = h.statuses[0]
+			h.statuses = h.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(h.Close)
+	return h
+}
+
+func prodDestroy() *run.Run {
+	return &run.Run{
+		ID:        run.GenerateID(time.Now()),
+		Workspace: "prod/db",
+		Status:    run.StatusFailed,
+		Program:   "terraform",
+		Command:   []string{"terraform", "apply"},
+		User:      "alice",
+		Changes:   &run.Changes{Add: 1, Destroy: 2},
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"empty rule", Rule{}, true},
+		{"workspace glob", Rule{Workspaces: []string{"prod/*"}}, true},
+		{"workspace miss", Rule{Workspaces: []string{"dev/*"}}, false},
+		{"action", Rule{Actions: []string{"apply", "destroy"}}, true},
+		{"action miss", Rule{Actions: []string{"plan"}}, false},
+		{"status", Rule{Statuses: []run.Status{run.StatusFailed}}, true},
+		{"status miss", Rule{Statuses: []run.Status{run.StatusSuccess}}, false},
+		{"min destroy", Rule{MinDestroy: 1}, true},
+		{"min destroy miss", Rule{MinDestroy: 3}, false},
+		{"min add and destroy", Rule{MinAdd: 1, MinDestroy: 2}, true},
+		{"min change miss", Rule{MinChange: 1}, false},
+	}
+
+	r := prodDestroy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(r); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown type", Config{Channels: map[string]Channel{"x": {Type: "email", URL: "http://x"}}}},
+		{"missing url", Config{Channels: map[string]Channel{"x": {Type: ChannelSlack}}}},
+		{"unknown channel", Config{Rules: []Rule{{Channels: []string{"nope"}}}}},
+		{"no channels", Config{Rules: []Rule{{Name: "empty"}}}},
+		{"bad template", Config{Channels: map[string]Channel{"x": {Type: ChannelSlack, URL: "http://x", Template: "{{.Nope"}}}},
+		{"bad backoff", Config{Retry: Retry{Backoff: "soon"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestNotifier_Payloads(t *testing.T) {
+	slack := newHookStub(t)
+	teams := newHookStub(t)
+	webhook := newHookStub(t)
+
+	n, err := New(Config{
+		BaseURL: "https://tfjournal.example.com/",
+		Channels: map[string]Channel{
+			"slack":   {Type: ChannelSlack, URL: slack.URL},
+			"teams":   {Type: ChannelTeams, URL: teams.URL},
+			"webhook": {Type: ChannelWebhook, URL: webhook.URL, Template: "{{.Run.Workspace}} destroyed {{.Run.Changes.Destroy}}"},
+		},
+		Rules: []Rule{
+			{Name: "prod destroys", Workspaces: []string{"prod/*"}, MinDestroy: 1, Channels: []string{"slack", "teams", "webhook"}},
+			{Name: "dev only", Workspaces: []string{"dev/*"}, Channels: []string{"slack"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := prodDestroy()
+	if err := n.Notify(context.Background(), r); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if len(slack.bodies) != 1 {
+		t.Fatalf("slack got %d requests, want 1", len(slack.bodies))
+	}
+	text, _ := slack.bodies[0]["text"].(string)
+	if !strings.HasPrefix(text, "✗ terraform apply failed in prod/db (+1 ~0 -2) by alice") {
+		t.Errorf("slack text = %q", text)
+	}
+	if !strings.Contains(text, "https://tfjournal.example.com/?run="+r.ID) {
+		t.Errorf("slack text missing run link: %q", text)
+	}
+
+	if len(teams.bodies) != 1 || teams.bodies[0]["@type"] != "MessageCard" || teams.bodies[0]["themeColor"] != "d73a49" {
+		t.Errorf("teams body = %v", teams.bodies)
+	}
+
+	if len(webhook.bodies) != 1 {
+		t.Fatalf("webhook got %d requests, want 1", len(webhook.bodies))
+	}
+	if webhook.bodies[0]["message"] != "prod/db destroyed 2" {
+		t.Errorf("webhook message = %v", webhook.bodies[0]["message"])
+	}
+	if webhook.bodies[0]["rule"] != "prod destroys" {
+		t.Errorf("webhook rule = %v", webhook.bodies[0]["rule"])
+	}
+	runBody, _ := webhook.bodies[0]["run"].(map[string]any)
+	if runBody["id"] != r.ID {
+		t.Errorf("webhook run = %v", runBody)
+	}
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	hook := newHookStub(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+
+	n, err := New(Config{
+		Channels: map[string]Channel{"hook": {Type: ChannelWebhook, URL: hook.URL}},
+		Rules:    []Rule{{Channels: []string{"hook"}}},
+		Retry:    Retry{Attempts: 3, Backoff: "10ms"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var waits []time.Duration
+	n.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	if err := n.Notify(context.Background(), prodDestroy()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(hook.bodies) != 3 {
+		t.Errorf("got %d attempts, want 3", len(hook.bodies))
+	}
+	if len(waits) != 2 || waits[0] != 10*time.Millisecond || waits[1] != 20*time.Millisecond {
+		t.Errorf("backoff = %v, want [10ms 20ms]", waits)
+	}
+}
+
+func TestNotifier_NoRetryOnClientError(t *testing.T) {
+	hook := newHookStub(t, http.StatusBadRequest)
+
+	n, err := New(Config{
+		Channels: map[string]Channel{"hook": {Type: ChannelSlack, URL: hook.URL}},
+		Rules:    []Rule{{Channels: []string{"hook"}}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	n.sleep = func(context.Context, time.Duration) error { return nil }
+
+	if err := n.Notify(context.Background(), prodDestroy()); err == nil {
+		t.Error("expected error for 400 response")
+	}
+	if len(hook.bodies) != 1 {
+		t.Errorf("got %d attempts, want 1", len(hook.bodies))
+	}
+}
+
+func TestNotifier_Deadline(t *testing.T) {
+	hook := newHookStub(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	n, err := New(Config{
+		Channels: map[string]Channel{"hook": {Type: ChannelWebhook, URL: hook.URL}},
+		Rules:    []Rule{{Channels: []string{"hook"}}},
+		Retry:    Retry{Attempts: 3, Backoff: "1m"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := n.Notify(ctx, prodDestroy()); err == nil {
+		t.Error("expected error when the deadline expires")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify() took %v, want it bounded by the deadline", elapsed)
+	}
+}
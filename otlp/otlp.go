@@ -0,0 +1,320 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_tracesPath     = "/v1/traces"
+	_defaultTimeout = 5 * time.Second
+	_serviceName    = "tfjournal"
+
+	_spanKindInternal = 1
+	_statusCodeOK     = 1
+	_statusCodeError  = 2
+)
+
+type Config struct {
+	Endpoint    string            `json:"endpoint,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ServiceName string            `json:"service_name,omitempty"`
+	Timeout     time.Duration     `json:"-"`
+}
+
+type Exporter struct {
+	cfg    Config
+	client *http.Client
+}
+
+func New(cfg Config) *Exporter {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = _serviceName
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = _defaultTimeout
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if !strings.HasSuffix(cfg.Endpoint, _tracesPath) {
+		cfg.Endpoint += _tracesPath
+	}
+	return &Exporter{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (e *Exporter) Export(r *run.Run) error {
+	body, err := json.Marshal(BuildTraces(r, e.cfg.ServiceName))
+	if err != nil {
+		return fmt.Errorf("failed to marshal traces: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export traces: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export traces: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+type TracesData struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+type Scope struct {
+	Name string `json:"name"`
+}
+
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+func BuildTraces(r *run.Run, serviceName string) *TracesData {
+	traceID := TraceID(r.ID)
+	rootID := spanID(r.ID, "")
+	start := r.Timestamp
+	end := start.Add(r.Duration())
+
+	root := Span{
+		TraceID:           traceID,
+		SpanID:            rootID,
+		Name:              strings.TrimSpace(r.Program + " " + r.Action()),
+		Kind:              _spanKindInternal,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        runAttributes(r),
+		Status:            runStatus(r),
+	}
+
+	spans := []Span{root}
+	for _, res := range r.Resources {
+		resStart := start.Add(time.Duration(res.StartTime.UnixNano()))
+		resEnd := end
+		if res.DurationMs > 0 {
+			resEnd = resStart.Add(time.Duration(res.DurationMs) * time.Millisecond)
+		}
+
+		status := Status{Code: _statusCodeOK}
+		if res.Status != "success" {
+			status = Status{Code: _statusCodeError, Message: "resource operation did not complete"}
+		}
+
+		spans = append(spans, Span{
+			TraceID:           traceID,
+			SpanID:            spanID(r.ID, res.Address),
+			ParentSpanID:      rootID,
+			Name:              res.Action + " " + res.Address,
+			Kind:              _spanKindInternal,
+			StartTimeUnixNano: unixNano(resStart),
+			EndTimeUnixNano:   unixNano(resEnd),
+			Attributes: []KeyValue{
+				stringAttr("tfjournal.resource.address", res.Address),
+				stringAttr("tfjournal.resource.type", res.Type()),
+				stringAttr("tfjournal.resource.action", res.Action),
+				stringAttr("tfjournal.resource.status", res.Status),
+			},
+			Status: status,
+		})
+	}
+
+	return &TracesData{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{Attributes: []KeyValue{stringAttr("service.name", serviceName)}},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: "github.com/Owloops/tfjournal"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func TraceID(runID string) string {
+	sum := sha256.Sum256([]byte(runID))
+	return hex.EncodeToString(sum[:16])
+}
+
+func spanID(runID, address string) string {
+	sum := sha256.Sum256([]byte(runID + "\x00" + address))
+	return hex.EncodeToString(sum[:8])
+}
+
+func runAttributes(r *run.Run) []KeyValue {
+	attrs := []KeyValue{
+		stringAttr("tfjournal.run_id", r.ID),
+		stringAttr("tfjournal.workspace", r.Workspace),
+		stringAttr("tfjournal.program", r.Program),
+		stringAttr("tfjournal.action", r.Action()),
+		stringAttr("tfjournal.command", strings.Join(r.Command, " ")),
+		stringAttr("tfjournal.status", string(r.Status)),
+		intAttr("tfjournal.exit_code", int64(r.ExitCode)),
+		stringAttr("tfjournal.user", r.User),
+	}
+
+	if r.Changes != nil {
+		attrs = append(attrs,
+			intAttr("tfjournal.changes.add", int64(r.Changes.Add)),
+			intAttr("tfjournal.changes.change", int64(r.Changes.Change)),
+			intAttr("tfjournal.changes.destroy", int64(r.Changes.Destroy)),
+		)
+	}
+
+	if r.Git != nil {
+		attrs = append(attrs,
+			stringAttr("tfjournal.git.commit", r.Git.Commit),
+			stringAttr("tfjournal.git.branch", r.Git.Branch),
+			boolAttr("tfjournal.git.dirty", r.Git.Dirty),
+		)
+		for _, kv := range []struct{ key, value string }{
+			{"tfjournal.git.sha", r.Git.SHA},
+			{"tfjournal.git.remote", r.Git.Remote},
+			{"tfjournal.git.author", r.Git.Author},
+		} {
+			if kv.value != "" {
+				attrs = append(attrs, stringAttr(kv.key, kv.value))
+			}
+		}
+	}
+
+	if r.Cloud != nil {
+		for _, kv := range []struct{ key, value string }{
+			{"tfjournal.cloud.account", r.Cloud.Account()},
+			{"tfjournal.cloud.aws_role_arn", r.Cloud.AWSRoleARN},
+		} {
+			if kv.value != "" {
+				attrs = append(attrs, stringAttr(kv.key, kv.value))
+			}
+		}
+	}
+
+	if r.State != nil {
+		attrs = append(attrs, stringAttr("tfjournal.state.backend", r.State.Backend))
+		if r.State.ID != "" {
+			attrs = append(attrs, stringAttr("tfjournal.state.id", r.State.ID))
+		}
+	}
+
+	if r.Versions != nil && r.Versions.Version != "" {
+		attrs = append(attrs, stringAttr("tfjournal.version", r.Versions.Binary+" "+r.Versions.Version))
+	}
+
+	if r.CI != nil {
+		attrs = append(attrs, stringAttr("tfjournal.ci.provider", r.CI.Provider))
+		for _, kv := range []struct{ key, value string }{
+			{"tfjournal.ci.run_id", r.CI.RunID},
+			{"tfjournal.ci.workflow", r.CI.Workflow},
+			{"tfjournal.ci.job", r.CI.Job},
+			{"tfjournal.ci.event", r.CI.Event},
+			{"tfjournal.ci.actor", r.CI.Actor},
+			{"tfjournal.ci.pull_request", r.CI.PullRequest},
+			{"tfjournal.ci.source_branch", r.CI.SourceBranch},
+			{"tfjournal.ci.target_branch", r.CI.TargetBranch},
+			{"tfjournal.ci.url", r.CI.URL},
+		} {
+			if kv.value != "" {
+				attrs = append(attrs, stringAttr(kv.key, kv.value))
+			}
+		}
+	}
+
+	return attrs
+}
+
+func runStatus(r *run.Run) Status {
+	if r.Status.Succeeded() {
+		return Status{Code: _statusCodeOK}
+	}
+	return Status{Code: _statusCodeError, Message: fmt.Sprintf("%s (exit code %d)", r.Status, r.ExitCode)}
+}
+
+func stringAttr(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) KeyValue {
+	s := strconv.FormatInt(value, 10)
+	return KeyValue{Key: key, Value: AnyValue{IntValue: &s}}
+}
+
+func boolAttr(key string, value bool) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{BoolValue: &value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
@@ -0,0 +1,137 @@
+package otlp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type collectorStub struct {
+	*httptest.Server
+	requests []*TracesData
+	headers  []http.Header
+	status   int
+}
+
+func newCollectorStub(t *testing.T) *collectorStub {
+	c := &collectorStub{status: http.StatusOK}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %s, want /v1/traces", r.URL.Path)
+		}
+		var data TracesData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		c.requests = append(c.requests, &data)
+		c.headers = append(c.headers, r.Header.Clone())
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func testRun() *run.Run {
+	ts := time.Date(2025, 1, 26, 14, 30, 0, 0, time.UTC)
+	return &run.Run{
+		ID:         run.GenerateID(ts),
+		Workspace:  "prod/vpc",
+		Timestamp:  ts,
+		DurationMs: 60000,
+		Status:     run.StatusFailed,
+		ExitCode:   1,
+		Program:    "terraform",
+		Command:    []string{"terraform", "apply"},
+		User:       "alice",
+		Git:        &run.GitInfo{Commit: "abc1234", Branch: "main"},
+		CI:         &run.CIInfo{Provider: "github-actions", RunID: "42"},
+		Changes:    &run.Changes{Add: 1, Destroy: 1},
+		Resources: []run.Resource{
+			{Address: "aws_subnet.a", Action: "create", StartTime: time.Unix(0, 0), DurationMs: 5000, Status: "success"},
+			{Address: "aws_instance.web", Action: "destroy", StartTime: time.Unix(5, 0), Status: "in_progress"},
+		},
+	}
+}
+
+func TestExporter_Export(t *testing.T) {
+	collector := newCollectorStub(t)
+	exp := New(Config{Endpoint: collector.URL, Headers: map[string]string{"X-Api-Key": "secret"}})
+
+	r := testRun()
+	if err := exp.Export(r); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(collector.requests) != 1 {
+		t.Fatalf("collector got %d requests, want 1", len(collector.requests))
+	}
+	if got := collector.headers[0].Get("X-Api-Key"); got != "secret" {
+		t.Errorf("X-Api-Key = %q, want secret", got)
+	}
+
+	spans := collector.requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+
+	root := spans[0]
+	if root.Name != "terraform apply" {
+		t.Errorf("root name = %q, want terraform apply", root.Name)
+	}
+	if root.TraceID != TraceID(r.ID) || len(root.TraceID) != 32 {
+		t.Errorf("root traceId = %q", root.TraceID)
+	}
+	if root.Status.Code != _statusCodeError {
+		t.Errorf("root status = %d, want error", root.Status.Code)
+	}
+	if got := attr(root, "tfjournal.git.commit"); got != "abc1234" {
+		t.Errorf("git commit attribute = %q, want abc1234", got)
+	}
+	if got := attr(root, "tfjournal.workspace"); got != "prod/vpc" {
+		t.Errorf("workspace attribute = %q, want prod/vpc", got)
+	}
+
+	child := spans[1]
+	if child.ParentSpanID != root.SpanID {
+		t.Errorf("child parent = %q, want %q", child.ParentSpanID, root.SpanID)
+	}
+	wantStart := r.Timestamp.UnixNano()
+	wantEnd := r.Timestamp.Add(5 * time.Second).UnixNano()
+	if child.StartTimeUnixNano != unixNano(time.Unix(0, wantStart)) || child.EndTimeUnixNano != unixNano(time.Unix(0, wantEnd)) {
+		t.Errorf("child span = %s..%s, want %d..%d", child.StartTimeUnixNano, child.EndTimeUnixNano, wantStart, wantEnd)
+	}
+	if got := attr(child, "tfjournal.resource.type"); got != "aws_subnet" {
+		t.Errorf("resource type attribute = %q, want aws_subnet", got)
+	}
+
+	unfinished := spans[2]
+	if unfinished.Status.Code != _statusCodeError {
+		t.Errorf("unfinished resource status = %d, want error", unfinished.Status.Code)
+	}
+	if unfinished.EndTimeUnixNano != root.EndTimeUnixNano {
+		t.Errorf("unfinished resource should end with the run")
+	}
+}
+
+func TestExporter_CollectorError(t *testing.T) {
+	collector := newCollectorStub(t)
+	collector.status = http.StatusServiceUnavailable
+
+	exp := New(Config{Endpoint: collector.URL + "/v1/traces"})
+	if err := exp.Export(testRun()); err == nil {
+		t.Error("expected error when collector rejects request")
+	}
+}
+
+func attr(s Span, key string) string {
+	for _, kv := range s.Attributes {
+		if kv.Key == key && kv.Value.StringValue != nil {
+			return *kv.Value.StringValue
+		}
+	}
+	return ""
+}
@@ -20,8 +20,15 @@ var (
 
 	resourceStartRegex = regexp.MustCompile(`^(.+): (Creating|Modifying|Destroying)\.\.\.`)
 	resourceEndRegex   = regexp.MustCompile(`^(.+): (Creation|Modifications?|Destruction) complete after ([0-9a-z]+)`)
+
+	plannedRegex = regexp.MustCompile(`^\s*# (.+?) (?:is tainted, so )?(will be created|will be updated in-place|will be destroyed|must be replaced)`)
+
+	lockInfoRegex  = regexp.MustCompile(`(?m)^[│\s]*Lock Info:\s*$`)
+	lockFieldRegex = regexp.MustCompile(`^[│\s]*(ID|Path|Operation|Who|Version|Created|Info):\s*(.*?)\s*$`)
 )
 
+const _lockTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
 func StripAnsi(s string) string {
 	return ansiRegex.ReplaceAllString(s, "")
 }
@@ -29,6 +36,8 @@ func StripAnsi(s string) string {
 type Result struct {
 	Changes   *run.Changes
 	Resources []run.Resource
+	Planned   []run.Planned
+	Lock      *run.LockInfo
 }
 
 func Parse(output string) Result {
@@ -36,6 +45,8 @@ func Parse(output string) Result {
 	return Result{
 		Changes:   parseChanges(clean),
 		Resources: parseResources(clean),
+		Planned:   parsePlanned(clean),
+		Lock:      parseLock(clean),
 	}
 }
 
@@ -117,6 +128,71 @@ func parseResources(output string) []run.Resource {
 	return result
 }
 
+func parsePlanned(output string) []run.Planned {
+	var planned []run.Planned
+	seen := make(map[string]bool)
+
+	for line := range strings.SplitSeq(output, "\n") {
+		m := plannedRegex.FindStringSubmatch(line)
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		planned = append(planned, run.Planned{Address: m[1], Action: normalizePlannedAction(m[2])})
+	}
+	return planned
+}
+
+func parseLock(output string) *run.LockInfo {
+	loc := lockInfoRegex.FindStringIndex(output)
+	if loc == nil {
+		return nil
+	}
+
+	lock := &run.LockInfo{}
+	for line := range strings.SplitSeq(output[loc[1]:], "\n") {
+		m := lockFieldRegex.FindStringSubmatch(line)
+		if m == nil {
+			if strings.TrimSpace(strings.Trim(line, "│")) == "" {
+				continue
+			}
+			break
+		}
+		switch m[1] {
+		case "ID":
+			lock.ID = m[2]
+		case "Path":
+			lock.Path = m[2]
+		case "Operation":
+			lock.Operation = strings.ToLower(strings.TrimPrefix(m[2], "OperationType"))
+		case "Who":
+			lock.Who = m[2]
+		case "Version":
+			lock.Version = m[2]
+		case "Created":
+			if t, err := time.Parse(_lockTimeLayout, m[2]); err == nil {
+				lock.Created = t.UTC()
+			}
+		case "Info":
+			lock.Info = m[2]
+		}
+	}
+	return lock
+}
+
+func normalizePlannedAction(action string) string {
+	switch action {
+	case "will be created":
+		return "create"
+	case "will be updated in-place":
+		return "update"
+	case "will be destroyed":
+		return "destroy"
+	default:
+		return "replace"
+	}
+}
+
 func normalizeAction(action string) string {
 	switch action {
 	case "Creating", "Creation":
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"testing"
+	"time"
 )
 
 func TestParseChanges(t *testing.T) {
@@ -124,3 +125,78 @@ aws_security_group.legacy: Modification complete after 1s [id=sg-old]`
 		t.Errorf("resource duration = %d, want 1000", result.Resources[0].DurationMs)
 	}
 }
+
+func TestParsePlanned(t *testing.T) {
+	output := `Terraform will perform the following actions:
+
+  # aws_instance.web will be created
+  + resource "aws_instance" "web" {
+    }
+
+  # module.db.aws_db_instance.main must be replaced
+-/+ resource "aws_db_instance" "main" {
+    }
+
+  # aws_s3_bucket.logs["a b"] will be destroyed
+  # (because aws_s3_bucket.logs is not in configuration)
+  - resource "aws_s3_bucket" "logs" {
+    }
+
+  # aws_security_group.app will be updated in-place
+  # aws_instance.old is tainted, so must be replaced
+
+Plan: 2 to add, 1 to change, 2 to destroy.`
+
+	want := []struct{ address, action string }{
+		{"aws_instance.web", "create"},
+		{"module.db.aws_db_instance.main", "replace"},
+		{`aws_s3_bucket.logs["a b"]`, "destroy"},
+		{"aws_security_group.app", "update"},
+		{"aws_instance.old", "replace"},
+	}
+
+	result := Parse(output)
+	if len(result.Planned) != len(want) {
+		t.Fatalf("expected %d planned resources, got %d: %+v", len(want), len(result.Planned), result.Planned)
+	}
+	for i, w := range want {
+		if result.Planned[i].Address != w.address || result.Planned[i].Action != w.action {
+			t.Errorf("planned[%d] = %+v, want %s %s", i, result.Planned[i], w.action, w.address)
+		}
+	}
+}
+
+func TestParseLock(t *testing.T) {
+	output := `Acquiring state lock. This may take a few moments...
+╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        6f1c8a3e-8a52-4c1b-9d0e-1a2b3c4d5e6f
+│   Path:      acme-state/prod/vpc.tfstate
+│   Operation: OperationTypeApply
+│   Who:       alice@laptop
+│   Version:   1.7.5
+│   Created:   2025-01-24 02:00:01.123456789 +0000 UTC
+│   Info:
+│
+│
+│ Terraform acquires a state lock to protect the state from being written
+│ by multiple users at the same time.
+╵`
+
+	lock := Parse(output).Lock
+	if lock == nil {
+		t.Fatal("expected lock info")
+	}
+	want := time.Date(2025, 1, 24, 2, 0, 1, 123456789, time.UTC)
+	if lock.ID != "6f1c8a3e-8a52-4c1b-9d0e-1a2b3c4d5e6f" || lock.Path != "acme-state/prod/vpc.tfstate" ||
+		lock.Operation != "apply" || lock.Who != "alice@laptop" || lock.Version != "1.7.5" || !lock.Created.Equal(want) {
+		t.Errorf("lock = %+v", lock)
+	}
+
+	if Parse("Error: Error acquiring the state lock\n").Lock != nil {
+		t.Error("expected no lock info without a Lock Info block")
+	}
+}
@@ -0,0 +1,154 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+const (
+	EffectBlock   = "block"
+	EffectConfirm = "confirm"
+)
+
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name              string   `json:"name"`
+	Workspaces        []string `json:"workspaces,omitempty"`
+	Actions           []string `json:"actions,omitempty"`
+	Effect            string   `json:"effect,omitempty"`
+	Message           string   `json:"message,omitempty"`
+	DenyDirty         bool     `json:"deny_dirty,omitempty"`
+	AllowBranches     []string `json:"allow_branches,omitempty"`
+	RequireCI         bool     `json:"require_ci,omitempty"`
+	RequirePlanWithin string   `json:"require_plan_within,omitempty"`
+
+	planWithin time.Duration
+}
+
+type Engine struct {
+	rules []Rule
+	now   func() time.Time
+}
+
+func New(cfg Config) (*Engine, error) {
+	rules := make([]Rule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		switch rule.Effect {
+		case "":
+			rule.Effect = EffectBlock
+		case EffectBlock, EffectConfirm:
+		default:
+			return nil, fmt.Errorf("policy %q: unknown effect %q (must be block or confirm)", rule.Name, rule.Effect)
+		}
+		if rule.RequirePlanWithin != "" {
+			d, err := run.ParseDuration(rule.RequirePlanWithin)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("policy %q: invalid require_plan_within %q", rule.Name, rule.RequirePlanWithin)
+			}
+			rule.planWithin = d
+		}
+		if !rule.DenyDirty && len(rule.AllowBranches) == 0 && !rule.RequireCI && rule.planWithin == 0 {
+			return nil, fmt.Errorf("policy %q: no conditions", rule.Name)
+		}
+		rules[i] = rule
+	}
+	return &Engine{rules: rules, now: time.Now}, nil
+}
+
+func (e *Engine) Evaluate(r *run.Run, store storage.Store) *run.Policy {
+	decision := &run.Policy{Decision: run.PolicyAllowed}
+
+	for _, rule := range e.rules {
+		if !rule.applies(r) {
+			continue
+		}
+		for _, msg := range e.check(rule, r, store) {
+			if rule.Message != "" {
+				msg = rule.Message
+			}
+			decision.Violations = append(decision.Violations, run.PolicyViolation{
+				Rule:    rule.Name,
+				Effect:  rule.Effect,
+				Message: msg,
+			})
+			if rule.Effect == EffectBlock {
+				decision.Decision = run.PolicyBlocked
+			}
+		}
+	}
+
+	return decision
+}
+
+func NeedsConfirmation(p *run.Policy) bool {
+	return p != nil && p.Decision == run.PolicyAllowed && len(p.Violations) > 0
+}
+
+func (rule Rule) applies(r *run.Run) bool {
+	if len(rule.Workspaces) > 0 && !slices.ContainsFunc(rule.Workspaces, func(p string) bool {
+		matched, _ := path.Match(p, r.Workspace)
+		return matched
+	}) {
+		return false
+	}
+	return len(rule.Actions) == 0 || slices.Contains(rule.Actions, r.Action())
+}
+
+func (e *Engine) check(rule Rule, r *run.Run, store storage.Store) []string {
+	var violations []string
+
+	if rule.DenyDirty && r.Git != nil && r.Git.Dirty {
+		violations = append(violations, "git working tree has uncommitted changes")
+	}
+
+	if len(rule.AllowBranches) > 0 {
+		branch := ""
+		if r.Git != nil {
+			branch = r.Git.Branch
+		}
+		if !slices.Contains(rule.AllowBranches, branch) {
+			violations = append(violations, fmt.Sprintf("branch %q is not one of %s", branch, strings.Join(rule.AllowBranches, ", ")))
+		}
+	}
+
+	if rule.RequireCI && r.CI == nil {
+		violations = append(violations, "must run in CI")
+	}
+
+	if rule.planWithin > 0 && !e.hasRecentPlan(r, store, rule.planWithin) {
+		violations = append(violations, fmt.Sprintf("no successful plan recorded for %s in the last %s", r.Workspace, rule.RequirePlanWithin))
+	}
+
+	return violations
+}
+
+func (e *Engine) hasRecentPlan(r *run.Run, store storage.Store, within time.Duration) bool {
+	if store == nil {
+		return false
+	}
+	runs, err := store.ListRuns(storage.ListOptions{
+		Since:  e.now().Add(-within),
+		Action: "plan",
+	})
+	if err != nil {
+		return false
+	}
+	for _, p := range runs {
+		if p.Workspace == r.Workspace && p.ID != r.ID && p.Status.Succeeded() {
+			return true
+		}
+	}
+	return false
+}
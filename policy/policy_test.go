@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+func applyRun(workspace, branch string, dirty bool, ci *run.CIInfo) *run.Run {
+	return &run.Run{
+		ID:        run.GenerateID(time.Now()),
+		Workspace: workspace,
+		Timestamp: time.Now(),
+		Command:   []string{"terraform", "apply"},
+		Git:       &run.GitInfo{Commit: "abc1234", Branch: branch, Dirty: dirty},
+		CI:        ci,
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown effect", Rule{Effect: "warn", DenyDirty: true}},
+		{"bad window", Rule{RequirePlanWithin: "soon"}},
+		{"no conditions", Rule{Actions: []string{"apply"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(Config{Rules: []Rule{tt.rule}}); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine, err := New(Config{Rules: []Rule{
+		{Name: "clean prod", Workspaces: []string{"prod*"}, Actions: []string{"apply"}, DenyDirty: true},
+		{Name: "main only", Actions: []string{"apply"}, AllowBranches: []string{"main"}, Effect: EffectConfirm},
+		{Name: "destroy in ci", Actions: []string{"destroy"}, RequireCI: true, Message: "destroy only from the pipeline"},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		run            *run.Run
+		wantDecision   string
+		wantViolations int
+	}{
+		{"clean main apply", applyRun("prod", "main", false, nil), run.PolicyAllowed, 0},
+		{"dirty prod apply", applyRun("prod", "main", true, nil), run.PolicyBlocked, 1},
+		{"dirty dev apply", applyRun("dev", "main", true, nil), run.PolicyAllowed, 0},
+		{"feature branch apply", applyRun("dev", "feature", false, nil), run.PolicyAllowed, 1},
+		{"dirty prod feature branch", applyRun("prod", "feature", true, nil), run.PolicyBlocked, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.Evaluate(tt.run, nil)
+			if got.Decision != tt.wantDecision {
+				t.Errorf("Decision = %s, want %s", got.Decision, tt.wantDecision)
+			}
+			if len(got.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d: %+v", len(got.Violations), tt.wantViolations, got.Violations)
+			}
+		})
+	}
+
+	t.Run("destroy outside ci", func(t *testing.T) {
+		r := applyRun("prod", "main", false, nil)
+		r.Command = []string{"terraform", "destroy"}
+		got := engine.Evaluate(r, nil)
+		if got.Decision != run.PolicyBlocked {
+			t.Errorf("Decision = %s, want blocked", got.Decision)
+		}
+		if len(got.Violations) != 1 || got.Violations[0].Message != "destroy only from the pipeline" {
+			t.Errorf("Violations = %+v", got.Violations)
+		}
+
+		r.CI = &run.CIInfo{Provider: "github-actions"}
+		if got := engine.Evaluate(r, nil); got.Decision != run.PolicyAllowed {
+			t.Errorf("Decision in CI = %s, want allowed", got.Decision)
+		}
+	})
+}
+
+func TestNeedsConfirmation(t *testing.T) {
+	if NeedsConfirmation(&run.Policy{Decision: run.PolicyAllowed}) {
+		t.Error("no violations should not need confirmation")
+	}
+	if !NeedsConfirmation(&run.Policy{Decision: run.PolicyAllowed, Violations: []run.PolicyViolation{{Effect: EffectConfirm}}}) {
+		t.Error("confirm violations should need confirmation")
+	}
+	if NeedsConfirmation(&run.Policy{Decision: run.PolicyBlocked, Violations: []run.PolicyViolation{{Effect: EffectBlock}}}) {
+		t.Error("blocked runs should not ask for confirmation")
+	}
+}
+
+func TestEngine_RequirePlanWithin(t *testing.T) {
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	engine, err := New(Config{Rules: []Rule{
+		{Name: "plan first", Actions: []string{"apply"}, RequirePlanWithin: "1h"},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	apply := applyRun("prod/vpc", "main", false, nil)
+	if got := engine.Evaluate(apply, store); got.Decision != run.PolicyBlocked {
+		t.Errorf("Decision without plan = %s, want blocked", got.Decision)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	plans := []*run.Run{
+		{ID: run.GenerateID(old), Workspace: "prod/vpc", Timestamp: old, Status: run.StatusSuccess, Command: []string{"terraform", "plan"}},
+		{ID: run.GenerateID(time.Now()), Workspace: "prod/vpc-other", Timestamp: time.Now(), Status: run.StatusSuccess, Command: []string{"terraform", "plan"}},
+		{ID: run.GenerateID(time.Now()), Workspace: "prod/vpc", Timestamp: time.Now(), Status: run.StatusFailed, Command: []string{"terraform", "plan"}},
+	}
+	for _, p := range plans {
+		if err := store.SaveRun(p); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+	if got := engine.Evaluate(apply, store); got.Decision != run.PolicyBlocked {
+		t.Errorf("Decision with stale, foreign and failed plans = %s, want blocked", got.Decision)
+	}
+
+	recent := time.Now().Add(-10 * time.Minute)
+	plan := &run.Run{ID: run.GenerateID(recent), Workspace: "prod/vpc", Timestamp: recent, Status: run.StatusSuccess, Command: []string{"terraform", "plan"}}
+	if err := store.SaveRun(plan); err != nil {
+		t.Fatalf("failed to save run: %v", err)
+	}
+	if got := engine.Evaluate(apply, store); got.Decision != run.PolicyAllowed {
+		t.Errorf("Decision with recent plan = %s, want allowed", got.Decision)
+	}
+}
@@ -0,0 +1,60 @@
+package prcomment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const _pageSize = 100
+
+type GitHub struct {
+	client  *http.Client
+	apiURL  string
+	repo    string
+	headers map[string]string
+}
+
+func NewGitHub(client *http.Client, apiURL, repo, token string) *GitHub {
+	return &GitHub{
+		client: client,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		repo:   repo,
+		headers: map[string]string{
+			"Authorization":        "Bearer " + token,
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+	}
+}
+
+func (g *GitHub) ListComments(ctx context.Context, pr string) ([]Comment, error) {
+	var comments []Comment
+	for page := 1; ; page++ {
+		var batch []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		endpoint := fmt.Sprintf("%s/repos/%s/issues/%s/comments?per_page=%d&page=%d", g.apiURL, g.repo, pr, _pageSize, page)
+		if err := doJSON(ctx, g.client, http.MethodGet, endpoint, g.headers, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, c := range batch {
+			comments = append(comments, Comment{ID: c.ID, Body: c.Body})
+		}
+		if len(batch) < _pageSize {
+			return comments, nil
+		}
+	}
+}
+
+func (g *GitHub) CreateComment(ctx context.Context, pr, body string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%s/comments", g.apiURL, g.repo, pr)
+	return doJSON(ctx, g.client, http.MethodPost, endpoint, g.headers, map[string]string{"body": body}, nil)
+}
+
+func (g *GitHub) UpdateComment(ctx context.Context, pr string, id int64, body string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/comments/%d", g.apiURL, g.repo, id)
+	return doJSON(ctx, g.client, http.MethodPatch, endpoint, g.headers, map[string]string{"body": body}, nil)
+}
@@ -0,0 +1,58 @@
+package prcomment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type GitLab struct {
+	client  *http.Client
+	apiURL  string
+	project string
+	headers map[string]string
+}
+
+func NewGitLab(client *http.Client, apiURL, project, token string) *GitLab {
+	return &GitLab{
+		client:  client,
+		apiURL:  strings.TrimSuffix(apiURL, "/"),
+		project: url.PathEscape(project),
+		headers: map[string]string{"PRIVATE-TOKEN": token},
+	}
+}
+
+func (g *GitLab) ListComments(ctx context.Context, mr string) ([]Comment, error) {
+	var comments []Comment
+	for page := 1; ; page++ {
+		var batch []struct {
+			ID     int64  `json:"id"`
+			Body   string `json:"body"`
+			System bool   `json:"system"`
+		}
+		endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%s/notes?per_page=%d&page=%d", g.apiURL, g.project, mr, _pageSize, page)
+		if err := doJSON(ctx, g.client, http.MethodGet, endpoint, g.headers, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, n := range batch {
+			if !n.System {
+				comments = append(comments, Comment{ID: n.ID, Body: n.Body})
+			}
+		}
+		if len(batch) < _pageSize {
+			return comments, nil
+		}
+	}
+}
+
+func (g *GitLab) CreateComment(ctx context.Context, mr, body string) error {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%s/notes", g.apiURL, g.project, mr)
+	return doJSON(ctx, g.client, http.MethodPost, endpoint, g.headers, map[string]string{"body": body}, nil)
+}
+
+func (g *GitLab) UpdateComment(ctx context.Context, mr string, id int64, body string) error {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%s/notes/%d", g.apiURL, g.project, mr, id)
+	return doJSON(ctx, g.client, http.MethodPut, endpoint, g.headers, map[string]string{"body": body}, nil)
+}
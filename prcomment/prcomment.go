@@ -0,0 +1,261 @@
+package prcomment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+
+	_defaultMaxResources = 50
+	_requestTimeout      = 10 * time.Second
+	_markerPrefix        = "<!-- tfjournal:"
+)
+
+var _defaultActions = []string{"plan", "apply", "destroy"}
+
+type Config struct {
+	Enabled      bool     `json:"enabled"`
+	Provider     string   `json:"provider,omitempty"`
+	APIURL       string   `json:"api_url,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	Token        string   `json:"token,omitempty"`
+	Actions      []string `json:"actions,omitempty"`
+	MaxResources int      `json:"max_resources,omitempty"`
+	BaseURL      string   `json:"base_url,omitempty"`
+}
+
+type Comment struct {
+	ID   int64
+	Body string
+}
+
+type Client interface {
+	ListComments(ctx context.Context, pr string) ([]Comment, error)
+	CreateComment(ctx context.Context, pr, body string) error
+	UpdateComment(ctx context.Context, pr string, id int64, body string) error
+}
+
+type Commenter struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+func New(cfg Config) (*Commenter, error) {
+	switch cfg.Provider {
+	case "", ProviderGitHub, ProviderGitLab:
+	default:
+		return nil, fmt.Errorf("comments: unknown provider %q (must be github or gitlab)", cfg.Provider)
+	}
+	if cfg.MaxResources <= 0 {
+		cfg.MaxResources = _defaultMaxResources
+	}
+	if len(cfg.Actions) == 0 {
+		cfg.Actions = _defaultActions
+	}
+	return &Commenter{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: _requestTimeout},
+	}, nil
+}
+
+func (c *Commenter) Applies(r *run.Run) bool {
+	if !c.cfg.Enabled || r.CI == nil || r.CI.PullRequest == "" {
+		return false
+	}
+	return slices.Contains(c.cfg.Actions, r.Action())
+}
+
+func (c *Commenter) Post(ctx context.Context, r *run.Run) error {
+	if !c.Applies(r) {
+		return nil
+	}
+	client, err := c.client(r.CI.Provider)
+	if err != nil {
+		return err
+	}
+	return Upsert(ctx, client, r.CI.PullRequest, Marker(r.Workspace), Render(r, c.cfg.BaseURL, c.cfg.MaxResources))
+}
+
+func (c *Commenter) client(ciProvider string) (Client, error) {
+	provider := c.cfg.Provider
+	if provider == "" {
+		switch ciProvider {
+		case "github-actions":
+			provider = ProviderGitHub
+		case "gitlab-ci":
+			provider = ProviderGitLab
+		default:
+			return nil, fmt.Errorf("comments: cannot infer provider for CI %q, set comments.provider", ciProvider)
+		}
+	}
+
+	apiURL, repo, token := c.cfg.APIURL, c.cfg.Repository, c.cfg.Token
+	switch provider {
+	case ProviderGitHub:
+		apiURL = firstNonEmpty(apiURL, os.Getenv("GITHUB_API_URL"), "https://api.github.com")
+		repo = firstNonEmpty(repo, os.Getenv("GITHUB_REPOSITORY"))
+		token = firstNonEmpty(token, os.Getenv("GITHUB_TOKEN"))
+	case ProviderGitLab:
+		apiURL = firstNonEmpty(apiURL, os.Getenv("CI_API_V4_URL"), "https://gitlab.com/api/v4")
+		repo = firstNonEmpty(repo, os.Getenv("CI_PROJECT_ID"))
+		token = firstNonEmpty(token, os.Getenv("GITLAB_TOKEN"))
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("comments: missing repository for %s", provider)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("comments: missing token for %s", provider)
+	}
+
+	if provider == ProviderGitLab {
+		return NewGitLab(c.httpClient, apiURL, repo, token), nil
+	}
+	return NewGitHub(c.httpClient, apiURL, repo, token), nil
+}
+
+func Marker(workspace string) string {
+	return _markerPrefix + workspace + " -->"
+}
+
+func Upsert(ctx context.Context, client Client, pr, marker, body string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*_requestTimeout)
+	defer cancel()
+
+	comments, err := client.ListComments(ctx, pr)
+	if err != nil {
+		return fmt.Errorf("comments: failed to list comments on #%s: %w", pr, err)
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.Body, marker) {
+			if err := client.UpdateComment(ctx, pr, comment.ID, body); err != nil {
+				return fmt.Errorf("comments: failed to update comment on #%s: %w", pr, err)
+			}
+			return nil
+		}
+	}
+	if err := client.CreateComment(ctx, pr, body); err != nil {
+		return fmt.Errorf("comments: failed to create comment on #%s: %w", pr, err)
+	}
+	return nil
+}
+
+func Render(r *run.Run, baseURL string, maxResources int) string {
+	var b strings.Builder
+	b.WriteString(Marker(r.Workspace))
+	b.WriteString("\n")
+
+	icon := "✓"
+	switch {
+	case r.Status == run.StatusDrift:
+		icon = "⚠"
+	case !r.Status.Succeeded():
+		icon = "✗"
+	}
+	fmt.Fprintf(&b, "### %s `%s %s` %s in `%s`\n\n", icon, r.Program, r.Action(), r.Status, r.Workspace)
+
+	if r.Changes != nil {
+		fmt.Fprintf(&b, "**%d** to add, **%d** to change, **%d** to destroy\n\n", r.Changes.Add, r.Changes.Change, r.Changes.Destroy)
+	} else {
+		b.WriteString("No changes.\n\n")
+	}
+
+	rows := resourceRows(r)
+	if len(rows) > 0 {
+		b.WriteString("| Action | Resource |\n|---|---|\n")
+		for i, row := range rows {
+			if i == maxResources {
+				fmt.Fprintf(&b, "\n_and %d more_\n", len(rows)-maxResources)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | `%s` |\n", row.Action, strings.ReplaceAll(row.Address, "|", "\\|"))
+		}
+		b.WriteString("\n")
+	}
+
+	var footer []string
+	if baseURL != "" {
+		footer = append(footer, fmt.Sprintf("[%s](%s/?run=%s)", r.ID, strings.TrimSuffix(baseURL, "/"), url.QueryEscape(r.ID)))
+	} else {
+		footer = append(footer, "`"+r.ID+"`")
+	}
+	footer = append(footer, r.Duration().Round(time.Second).String())
+	if r.Git != nil && r.Git.Commit != "" {
+		footer = append(footer, "commit `"+r.Git.Commit+"`")
+	}
+	b.WriteString("<sub>tfjournal · " + strings.Join(footer, " · ") + "</sub>\n")
+
+	return b.String()
+}
+
+func resourceRows(r *run.Run) []run.Planned {
+	if len(r.Planned) > 0 {
+		return r.Planned
+	}
+	rows := make([]run.Planned, 0, len(r.Resources))
+	for _, res := range r.Resources {
+		rows = append(rows, run.Planned{Address: res.Address, Action: res.Action})
+	}
+	return rows
+}
+
+func doJSON(ctx context.Context, client *http.Client, method, endpoint string, headers map[string]string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%s %s returned %s", method, req.URL.Path, resp.Status)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,266 @@
+package prcomment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type fakeForge struct {
+	*httptest.Server
+	mu       sync.Mutex
+	comments map[int64]string
+	nextID   int64
+	requests []string
+	auth     []string
+}
+
+func newFakeGitHub(t *testing.T) *fakeForge {
+	f := &fakeForge{comments: map[int64]string{}, nextID: 100}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/acme/infra/issues/42/comments", f.list)
+	mux.HandleFunc("POST /repos/acme/infra/issues/42/comments", f.create)
+	mux.HandleFunc("PATCH /repos/acme/infra/issues/comments/{id}", f.update)
+	f.Server = httptest.NewServer(f.record(mux, "Authorization"))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newFakeGitLab(t *testing.T) *fakeForge {
+	f := &fakeForge{comments: map[int64]string{}, nextID: 500}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/acme%2Finfra/merge_requests/7/notes", f.list)
+	mux.HandleFunc("POST /api/v4/projects/acme%2Finfra/merge_requests/7/notes", f.create)
+	mux.HandleFunc("PUT /api/v4/projects/acme%2Finfra/merge_requests/7/notes/{id}", f.update)
+	f.Server = httptest.NewServer(f.record(mux, "PRIVATE-TOKEN"))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeForge) record(next http.Handler, authHeader string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, r.Method)
+		f.auth = append(f.auth, r.Header.Get(authHeader))
+		f.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *fakeForge) list(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []map[string]any
+	if r.URL.Query().Get("page") == "1" {
+		out = append(out, map[string]any{"id": 1, "body": "looks good", "system": false})
+		for id, body := range f.comments {
+			out = append(out, map[string]any{"id": id, "body": body})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (f *fakeForge) create(w http.ResponseWriter, r *http.Request) {
+	var in struct{ Body string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.comments[f.nextID] = in.Body
+	w.WriteHeader(http.StatusCreated)
+	_, _ = fmt.Fprintf(w, `{"id": %d}`, f.nextID)
+}
+
+func (f *fakeForge) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var in struct{ Body string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.comments[id]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f.comments[id] = in.Body
+	_, _ = fmt.Fprintf(w, `{"id": %d}`, id)
+}
+
+func planRun(provider, pr string) *run.Run {
+	return &run.Run{
+		ID:         "run_20250123T103000_abcd1234",
+		Workspace:  "prod/vpc",
+		Status:     run.StatusSuccess,
+		Program:    "terraform",
+		Command:    []string{"terraform", "plan"},
+		DurationMs: 12_400,
+		Git:        &run.GitInfo{Commit: "abc1234"},
+		CI:         &run.CIInfo{Provider: provider, PullRequest: pr},
+		Changes:    &run.Changes{Add: 2, Destroy: 1},
+		Planned: []run.Planned{
+			{Address: "aws_subnet.a", Action: "create"},
+			{Address: "aws_subnet.b", Action: "create"},
+			{Address: "aws_instance.old", Action: "destroy"},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := planRun("github-actions", "42")
+	body := Render(r, "https://tfjournal.example.com/", 2)
+
+	for _, want := range []string{
+		Marker("prod/vpc"),
+		"### ✓ `terraform plan` success in `prod/vpc`",
+		"**2** to add, **0** to change, **1** to destroy",
+		"| create | `aws_subnet.a` |",
+		"_and 1 more_",
+		"[run_20250123T103000_abcd1234](https://tfjournal.example.com/?run=run_20250123T103000_abcd1234)",
+		"12s",
+		"commit `abc1234`",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "aws_instance.old") {
+		t.Error("Render() should truncate past max resources")
+	}
+}
+
+func TestRender_AppliedResources(t *testing.T) {
+	r := planRun("github-actions", "42")
+	r.Command = []string{"terraform", "apply"}
+	r.Status = run.StatusFailed
+	r.Planned = nil
+	r.Resources = []run.Resource{{Address: "aws_instance.web", Action: "create", StartTime: time.Now()}}
+
+	body := Render(r, "", _defaultMaxResources)
+	for _, want := range []string{"### ✗ `terraform apply` failed", "| create | `aws_instance.web` |", "`run_20250123T103000_abcd1234`"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestCommenter_GitHub(t *testing.T) {
+	fake := newFakeGitHub(t)
+	c, err := New(Config{Enabled: true, APIURL: fake.URL, Repository: "acme/infra", Token: "gh-token"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := planRun("github-actions", "42")
+	if err := c.Post(context.Background(), r); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	r.Changes.Add = 5
+	if err := c.Post(context.Background(), r); err != nil {
+		t.Fatalf("second Post() error = %v", err)
+	}
+
+	if len(fake.comments) != 1 {
+		t.Fatalf("comments = %d, want 1 (updated in place)", len(fake.comments))
+	}
+	for _, body := range fake.comments {
+		if !strings.Contains(body, "**5** to add") {
+			t.Errorf("comment not updated:\n%s", body)
+		}
+	}
+	if want := []string{"GET", "POST", "GET", "PATCH"}; strings.Join(fake.requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", fake.requests, want)
+	}
+	if fake.auth[0] != "Bearer gh-token" {
+		t.Errorf("Authorization = %q", fake.auth[0])
+	}
+}
+
+func TestCommenter_GitLab(t *testing.T) {
+	fake := newFakeGitLab(t)
+	c, err := New(Config{Enabled: true, APIURL: fake.URL + "/api/v4", Repository: "acme/infra", Token: "gl-token"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := planRun("gitlab-ci", "7")
+	if err := c.Post(context.Background(), r); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if err := c.Post(context.Background(), r); err != nil {
+		t.Fatalf("second Post() error = %v", err)
+	}
+
+	if len(fake.comments) != 1 {
+		t.Fatalf("comments = %d, want 1 (updated in place)", len(fake.comments))
+	}
+	if want := []string{"GET", "POST", "GET", "PUT"}; strings.Join(fake.requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", fake.requests, want)
+	}
+	if fake.auth[0] != "gl-token" {
+		t.Errorf("PRIVATE-TOKEN = %q", fake.auth[0])
+	}
+}
+
+func TestCommenter_Applies(t *testing.T) {
+	c, err := New(Config{Enabled: true, Actions: []string{"plan"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  *run.Run
+		want bool
+	}{
+		{"plan on pr", planRun("github-actions", "42"), true},
+		{"no pr", planRun("github-actions", ""), false},
+		{"no ci", &run.Run{Command: []string{"terraform", "plan"}}, false},
+		{"apply not configured", &run.Run{Command: []string{"terraform", "apply"}, CI: &run.CIInfo{PullRequest: "1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Applies(tt.run); got != tt.want {
+				t.Errorf("Applies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	disabled, _ := New(Config{})
+	if disabled.Applies(planRun("github-actions", "42")) {
+		t.Error("disabled commenter should not apply")
+	}
+}
+
+func TestCommenter_Errors(t *testing.T) {
+	if _, err := New(Config{Provider: "bitbucket"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+
+	t.Setenv("GITHUB_REPOSITORY", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	c, _ := New(Config{Enabled: true})
+	if err := c.Post(context.Background(), planRun("jenkins", "3")); err == nil {
+		t.Error("expected error when provider cannot be inferred")
+	}
+	if err := c.Post(context.Background(), planRun("github-actions", "3")); err == nil {
+		t.Error("expected error without repository and token")
+	}
+}
@@ -0,0 +1,353 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/approval"
+	"github.com/Owloops/tfjournal/run"
+)
+
+type planJSON struct {
+	FormatVersion   string `json:"format_version"`
+	ResourceChanges []struct {
+		Address string     `json:"address"`
+		Change  planChange `json:"change"`
+	} `json:"resource_changes"`
+	OutputChanges map[string]planChange `json:"output_changes"`
+}
+
+type planChange struct {
+	Actions []string `json:"actions"`
+}
+
+var (
+	valueFlags = []string{"-var", "-var-file", "-target", "-replace", "-lock-timeout", "-parallelism", "-state", "-state-out", "-backup"}
+
+	planDropFlags  = []string{"-auto-approve", "-state-out", "-backup"}
+	applyKeepFlags = []string{"-lock", "-lock-timeout", "-input", "-no-color", "-parallelism", "-compact-warnings", "-state", "-state-out", "-backup", "-json"}
+)
+
+func gatedAction(args []string) (int, string) {
+	for i, arg := range args {
+		switch arg {
+		case "apply", "destroy":
+			return i, arg
+		case "plan", "import", "taint", "untaint", "refresh", "state", "init", "validate", "output", "show":
+			return -1, ""
+		}
+	}
+	return -1, ""
+}
+
+func executeGated(gate *approval.Gate, r *run.Run, args []string, token string, issued func(token string) *run.Approval) (int, []byte, error) {
+	idx, action := gatedAction(args)
+	global, opts := args[:idx], args[idx+1:]
+
+	if slices.Contains(args, "run-all") || slices.Contains(args, "--all") {
+		r.Status = run.StatusBlocked
+		msg := "tfjournal: approval gate cannot check run-all applies; run each module separately\n"
+		fmt.Fprint(os.Stderr, msg)
+		return 1, []byte(msg), nil
+	}
+
+	var output bytes.Buffer
+
+	planFile, savedPlan := positional(opts)
+	var planArgs []string
+	if savedPlan {
+		planArgs = slices.Concat(global, []string{"show", "-no-color", planFile})
+	} else {
+		f, err := os.CreateTemp("", "tfjournal-*.tfplan")
+		if err != nil {
+			return 1, nil, fmt.Errorf("failed to create plan file: %w", err)
+		}
+		planFile = f.Name()
+		_ = f.Close()
+		defer func() {
+			if r.Approval == nil || r.Approval.PlanFile == "" {
+				_ = os.Remove(planFile)
+			}
+		}()
+
+		planArgs = slices.Concat(global, []string{"plan"})
+		if action == "destroy" {
+			planArgs = append(planArgs, "-destroy")
+		}
+		planArgs = append(planArgs, filterFlags(opts, func(name string) bool {
+			return !slices.Contains(planDropFlags, name)
+		})...)
+		planArgs = append(planArgs, "-out="+planFile)
+	}
+
+	exitCode, planOutput, err := execute(planArgs)
+	output.Write(planOutput)
+	if err != nil || exitCode != 0 {
+		return exitCode, output.Bytes(), err
+	}
+
+	planned, changed, err := showPlan(global, planFile)
+	if err != nil {
+		r.Status = run.StatusBlocked
+		msg := fmt.Sprintf("\ntfjournal: apply blocked, could not read the plan: %v\n", err)
+		fmt.Fprint(os.Stderr, msg)
+		output.WriteString(msg)
+		return 1, output.Bytes(), nil
+	}
+	reasons := gate.Check(r.Workspace, planned)
+
+	if len(reasons) > 0 {
+		r.Approval = requestApproval(r, planned, reasons, token, resolvePath(workingDir(args), planFile), issued)
+		if r.Approval.Decision == run.ApprovalRejected {
+			r.Status = run.StatusBlocked
+			if !savedPlan && r.Approval.Token != "" {
+				r.Approval.PlanFile = planFile
+			}
+			fmt.Fprint(os.Stderr, approveHint(global, r.Approval))
+			output.WriteString(approvalSummary(global, r.Approval))
+			return 1, output.Bytes(), nil
+		}
+	} else if !savedPlan && changed && !slices.ContainsFunc(opts, func(opt string) bool {
+		name, _ := flagName(opt)
+		return name == "-auto-approve"
+	}) {
+		if !confirm("\nDo you want to perform these actions?", "yes") {
+			r.Status = run.StatusCanceled
+			output.WriteString("\nApply cancelled.\n")
+			fmt.Fprintln(os.Stderr, "\nApply cancelled.")
+			return 1, output.Bytes(), nil
+		}
+	}
+
+	applyArgs := args
+	if !savedPlan {
+		applyArgs = slices.Concat(global, []string{"apply"})
+		applyArgs = append(applyArgs, filterFlags(opts, func(name string) bool {
+			return slices.Contains(applyKeepFlags, name)
+		})...)
+		applyArgs = append(applyArgs, planFile)
+	}
+
+	exitCode, applyOutput, err := execute(applyArgs)
+	output.Write(applyOutput)
+	if savedPlan && r.Approval != nil && r.Approval.PlanFile == planFile {
+		_ = os.Remove(planFile)
+	}
+	return exitCode, output.Bytes(), err
+}
+
+func requestApproval(r *run.Run, planned []run.Planned, reasons []string, token, planFile string, issued func(token string) *run.Approval) *run.Approval {
+	a := &run.Approval{
+		Decision:  run.ApprovalRejected,
+		Reasons:   reasons,
+		Timestamp: time.Now(),
+	}
+	if hash, err := approval.HashFile(planFile); err == nil {
+		a.PlanHash = hash
+		a.Nonce = approval.NewNonce()
+		a.Token = approval.Token(r.Workspace, planned, hash, a.Nonce)
+	}
+
+	fmt.Fprintln(os.Stderr, "\ntfjournal: this plan requires approval:")
+	for _, reason := range reasons {
+		fmt.Fprintf(os.Stderr, "  %s\n", reason)
+	}
+
+	switch {
+	case token != "":
+		a.Method = run.ApprovalMethodToken
+		prev := issued(token)
+		if prev == nil || a.PlanHash == "" || prev.PlanHash != a.PlanHash || approval.Token(r.Workspace, planned, a.PlanHash, prev.Nonce) != token {
+			fmt.Fprintf(os.Stderr, "tfjournal: approval token %s was not issued for this plan file or was already used\n", token)
+			return a
+		}
+		a.Token, a.Nonce, a.PlanFile = token, prev.Nonce, prev.PlanFile
+		if approver := os.Getenv("TFJOURNAL_APPROVER"); approver != "" {
+			a.Approver = approver
+			a.Method = run.ApprovalMethodTokenUnverified
+		}
+	case isTerminal(os.Stdin):
+		a.Method = run.ApprovalMethodPrompt
+		if !confirm("tfjournal: type the workspace name to approve.", r.Workspace) {
+			return a
+		}
+	default:
+		return a
+	}
+
+	if a.Approver == "" {
+		a.Approver = r.User
+	}
+	a.Decision = run.ApprovalApproved
+	return a
+}
+
+func pendingApproval(r *run.Run, reasons []string, planFile string) *run.Approval {
+	a := &run.Approval{
+		Decision:  run.ApprovalPending,
+		Reasons:   reasons,
+		Timestamp: time.Now(),
+	}
+	hash, err := approval.HashFile(planFile)
+	if planFile == "" || err != nil {
+		fmt.Fprintf(os.Stderr, "\ntfjournal: applying this plan requires approval (%s); save it with -out to get an approval token\n",
+			strings.Join(reasons, "; "))
+		return a
+	}
+	a.PlanHash = hash
+	a.Nonce = approval.NewNonce()
+	a.Token = approval.Token(r.Workspace, r.Planned, hash, a.Nonce)
+	fmt.Fprintf(os.Stderr, "\ntfjournal: applying this plan requires approval (%s); approve with --approve %s\n",
+		strings.Join(reasons, "; "), a.Token)
+	return a
+}
+
+func planOut(args []string) string {
+	for i, arg := range args {
+		if out, ok := strings.CutPrefix(arg, "-out="); ok {
+			return out
+		}
+		if arg == "-out" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func showPlan(global []string, planFile string) ([]run.Planned, bool, error) {
+	cmd := exec.Command(global[0], slices.Concat(global[1:], []string{"show", "-json", planFile})...)
+	cmd.Env = os.Environ()
+	if commandName(global[0]) == "terragrunt" {
+		cmd.Env = append(cmd.Env, "TG_TF_FORWARD_STDOUT=true")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, false, fmt.Errorf("show -json: %w: %s", err, msg)
+		}
+		return nil, false, fmt.Errorf("show -json: %w", err)
+	}
+	return parsePlanJSON(data)
+}
+
+func parsePlanJSON(data []byte) ([]run.Planned, bool, error) {
+	if i := bytes.IndexByte(data, '{'); i > 0 {
+		data = data[i:]
+	}
+	var plan planJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, false, fmt.Errorf("invalid plan JSON: %w", err)
+	}
+	if plan.FormatVersion == "" {
+		return nil, false, fmt.Errorf("invalid plan JSON: missing format_version")
+	}
+
+	var planned []run.Planned
+	for _, rc := range plan.ResourceChanges {
+		action, err := plannedAction(rc.Change.Actions)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", rc.Address, err)
+		}
+		if action != "" {
+			planned = append(planned, run.Planned{Address: rc.Address, Action: action})
+		}
+	}
+
+	changed := len(planned) > 0
+	for _, oc := range plan.OutputChanges {
+		if !slices.Equal(oc.Actions, []string{"no-op"}) {
+			changed = true
+		}
+	}
+	return planned, changed, nil
+}
+
+func plannedAction(actions []string) (string, error) {
+	switch strings.Join(actions, ",") {
+	case "no-op", "read":
+		return "", nil
+	case "create":
+		return "create", nil
+	case "update":
+		return "update", nil
+	case "delete":
+		return "destroy", nil
+	case "delete,create", "create,delete":
+		return "replace", nil
+	case "forget":
+		return "forget", nil
+	}
+	return "", fmt.Errorf("unrecognized plan actions %v", actions)
+}
+
+func approvalSummary(global []string, a *run.Approval) string {
+	var b strings.Builder
+	b.WriteString("\ntfjournal: apply blocked, approval required\n")
+	for _, reason := range a.Reasons {
+		fmt.Fprintf(&b, "  %s\n", reason)
+	}
+	b.WriteString(approveHint(global, a))
+	return b.String()
+}
+
+func approveHint(global []string, a *run.Approval) string {
+	switch {
+	case a.Token == "":
+		return "the plan file could not be read, so no approval token was issued\n"
+	case a.PlanFile != "":
+		return fmt.Sprintf("approve with: tfjournal --approve %s -- %s apply %s\n", a.Token, strings.Join(global, " "), a.PlanFile)
+	}
+	return fmt.Sprintf("approve with --approve %s\n", a.Token)
+}
+
+func positional(opts []string) (string, bool) {
+	for i := 0; i < len(opts); i++ {
+		opt := opts[i]
+		if !strings.HasPrefix(opt, "-") {
+			return opt, true
+		}
+		if name, hasValue := flagName(opt); !hasValue && slices.Contains(valueFlags, name) {
+			i++
+		}
+	}
+	return "", false
+}
+
+func filterFlags(opts []string, keep func(name string) bool) []string {
+	var kept []string
+	for i := 0; i < len(opts); i++ {
+		name, hasValue := flagName(opts[i])
+		takesNext := !hasValue && slices.Contains(valueFlags, name) && i+1 < len(opts)
+		if keep(name) {
+			kept = append(kept, opts[i])
+			if takesNext {
+				kept = append(kept, opts[i+1])
+			}
+		}
+		if takesNext {
+			i++
+		}
+	}
+	return kept
+}
+
+func flagName(opt string) (string, bool) {
+	name, _, hasValue := strings.Cut(opt, "=")
+	return "-" + strings.TrimLeft(name, "-"), hasValue
+}
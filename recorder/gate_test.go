@@ -0,0 +1,284 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Owloops/tfjournal/approval"
+	"github.com/Owloops/tfjournal/run"
+)
+
+const _planJSON = `{"format_version":"1.2","resource_changes":[
+	{"address":"aws_instance.web","change":{"actions":["update"]}},
+	{"address":"aws_db_instance.main","change":{"actions":["delete","create"]}},
+	{"address":"data.aws_ami.latest","change":{"actions":["read"]}},
+	{"address":"aws_s3_bucket.logs","change":{"actions":["no-op"]}}
+]}`
+
+func TestParsePlanJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantPlanned []run.Planned
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name: "resource changes",
+			data: _planJSON,
+			wantPlanned: []run.Planned{
+				{Address: "aws_instance.web", Action: "update"},
+				{Address: "aws_db_instance.main", Action: "replace"},
+			},
+			wantChanged: true,
+		},
+		{
+			name:        "outputs only",
+			data:        `{"format_version":"1.2","output_changes":{"url":{"actions":["update"]}}}`,
+			wantChanged: true,
+		},
+		{
+			name: "no changes",
+			data: `{"format_version":"1.2","resource_changes":[{"address":"a.b","change":{"actions":["no-op"]}}],"output_changes":{"url":{"actions":["no-op"]}}}`,
+		},
+		{
+			name:        "leading log lines",
+			data:        "INFO loading module\n" + `{"format_version":"1.2","resource_changes":[{"address":"a.b","change":{"actions":["delete"]}}]}`,
+			wantPlanned: []run.Planned{{Address: "a.b", Action: "destroy"}},
+			wantChanged: true,
+		},
+		{name: "human output", data: "Plan: 1 to add, 0 to change, 0 to destroy.", wantErr: true},
+		{name: "not a plan", data: `{"resource_changes":[]}`, wantErr: true},
+		{name: "unknown action", data: `{"format_version":"1.2","resource_changes":[{"address":"a.b","change":{"actions":["explode"]}}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, changed, err := parsePlanJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlanJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(planned, tt.wantPlanned) || changed != tt.wantChanged {
+				t.Errorf("parsePlanJSON() = %+v, %v, want %+v, %v", planned, changed, tt.wantPlanned, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestGatedAction(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantIdx   int
+		wantValue string
+	}{
+		{[]string{"terraform", "apply", "-auto-approve"}, 1, "apply"},
+		{[]string{"terraform", "-chdir=prod", "destroy"}, 2, "destroy"},
+		{[]string{"terraform", "plan", "-out=apply"}, -1, ""},
+		{[]string{"terraform", "state", "rm", "apply"}, -1, ""},
+	}
+	for _, tt := range tests {
+		idx, action := gatedAction(tt.args)
+		if idx != tt.wantIdx || action != tt.wantValue {
+			t.Errorf("gatedAction(%v) = %d, %q, want %d, %q", tt.args, idx, action, tt.wantIdx, tt.wantValue)
+		}
+	}
+}
+
+func TestPositional(t *testing.T) {
+	if file, ok := positional([]string{"-var", "a=b", "-lock=false", "plan.out"}); !ok || file != "plan.out" {
+		t.Errorf("positional() = %q, %v, want plan.out", file, ok)
+	}
+	if _, ok := positional([]string{"-var-file", "prod.tfvars", "-auto-approve"}); ok {
+		t.Error("positional() found a plan file in flags only")
+	}
+}
+
+func TestFilterFlags(t *testing.T) {
+	opts := []string{"-auto-approve", "-var", "a=b", "-lock-timeout=5m", "-state-out", "x.tfstate"}
+	got := filterFlags(opts, func(name string) bool { return name != "-auto-approve" && name != "-state-out" })
+	want := []string{"-var", "a=b", "-lock-timeout=5m"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterFlags() = %v, want %v", got, want)
+	}
+}
+
+func TestExecuteGated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the terraform binary")
+	}
+
+	one := 1
+	zero := 0
+	gate, err := approval.New(approval.Config{Rules: []approval.Rule{
+		{Name: "prod", MaxDestroy: &zero, MaxReplace: &one, Protected: []string{"aws_db_instance.*"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		plan       string
+		args       []string
+		wantStatus run.Status
+		wantApply  bool
+		wantOutput string
+	}{
+		{
+			name:       "protected resource blocks",
+			plan:       _planJSON,
+			args:       []string{"apply", "-auto-approve"},
+			wantStatus: run.StatusBlocked,
+			wantOutput: "protected resource aws_db_instance.main will be replaced",
+		},
+		{
+			name:       "unreadable plan blocks",
+			plan:       "not json",
+			args:       []string{"apply", "-auto-approve"},
+			wantStatus: run.StatusBlocked,
+			wantOutput: "could not read the plan",
+		},
+		{
+			name:       "unreadable saved plan blocks",
+			plan:       "not json",
+			args:       []string{"apply", "-json", "saved.tfplan"},
+			wantStatus: run.StatusBlocked,
+			wantOutput: "could not read the plan",
+		},
+		{
+			name:       "changes need confirmation",
+			plan:       `{"format_version":"1.2","resource_changes":[{"address":"aws_instance.web","change":{"actions":["create"]}}]}`,
+			args:       []string{"apply", "-json"},
+			wantStatus: run.StatusCanceled,
+			wantOutput: "Apply cancelled.",
+		},
+		{
+			name:       "auto-approve applies",
+			plan:       `{"format_version":"1.2","resource_changes":[{"address":"aws_instance.web","change":{"actions":["create"]}}]}`,
+			args:       []string{"apply", "-auto-approve"},
+			wantStatus: run.StatusRunning,
+			wantApply:  true,
+			wantOutput: "Apply complete!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			program, log := fakeTerraform(t, dir, tt.plan)
+
+			r := &run.Run{Workspace: "prod", Status: run.StatusRunning}
+			_, output, err := executeGated(gate, r, append([]string{program}, tt.args...), "", noIssued)
+			if err != nil {
+				t.Fatalf("executeGated() error = %v", err)
+			}
+			if r.Approval != nil && r.Approval.PlanFile != "" {
+				_ = os.Remove(r.Approval.PlanFile)
+			}
+			if r.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", r.Status, tt.wantStatus)
+			}
+			if !strings.Contains(string(output), tt.wantOutput) {
+				t.Errorf("output = %q, want it to contain %q", output, tt.wantOutput)
+			}
+
+			calls, _ := os.ReadFile(log)
+			applied := strings.Contains(string(calls), "\napply ") || strings.HasPrefix(string(calls), "apply ")
+			if applied != tt.wantApply {
+				t.Errorf("apply executed = %v, want %v; calls:\n%s", applied, tt.wantApply, calls)
+			}
+		})
+	}
+}
+
+func TestExecuteGated_Token(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the terraform binary")
+	}
+
+	zero := 0
+	gate, err := approval.New(approval.Config{Rules: []approval.Rule{{Name: "prod", MaxReplace: &zero}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	program, log := fakeTerraform(t, dir, _planJSON)
+
+	blocked := &run.Run{Workspace: "prod", Status: run.StatusRunning}
+	if _, _, err := executeGated(gate, blocked, []string{program, "apply"}, "", noIssued); err != nil {
+		t.Fatalf("executeGated() error = %v", err)
+	}
+	a := blocked.Approval
+	if blocked.Status != run.StatusBlocked || a == nil || a.Token == "" || a.Nonce == "" || a.PlanFile == "" {
+		t.Fatalf("blocked run approval = %+v", a)
+	}
+	t.Cleanup(func() { _ = os.Remove(a.PlanFile) })
+	if _, err := os.Stat(a.PlanFile); err != nil {
+		t.Fatalf("blocked plan file not kept: %v", err)
+	}
+	issued := func(token string) *run.Approval {
+		if token == a.Token {
+			return a
+		}
+		return nil
+	}
+
+	wrong := &run.Run{Workspace: "prod", Status: run.StatusRunning}
+	if _, _, err := executeGated(gate, wrong, []string{program, "apply", a.PlanFile}, "0123456789ab", issued); err != nil {
+		t.Fatal(err)
+	}
+	if wrong.Status != run.StatusBlocked {
+		t.Errorf("unknown token: status = %q, want blocked", wrong.Status)
+	}
+
+	t.Setenv("TFJOURNAL_APPROVER", "carol")
+	approved := &run.Run{Workspace: "prod", Status: run.StatusRunning, User: "alice"}
+	if _, _, err := executeGated(gate, approved, []string{program, "apply", a.PlanFile}, a.Token, issued); err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != run.StatusRunning || approved.Approval.Decision != run.ApprovalApproved {
+		t.Fatalf("approved run = %q, approval %+v", approved.Status, approved.Approval)
+	}
+	if approved.Approval.Approver != "carol" || approved.Approval.Method != run.ApprovalMethodTokenUnverified {
+		t.Errorf("approver = %q via %q, want carol via %q", approved.Approval.Approver, approved.Approval.Method, run.ApprovalMethodTokenUnverified)
+	}
+	calls, _ := os.ReadFile(log)
+	if !strings.Contains(string(calls), "apply "+a.PlanFile) {
+		t.Errorf("saved plan not applied; calls:\n%s", calls)
+	}
+	if _, err := os.Stat(a.PlanFile); !os.IsNotExist(err) {
+		t.Errorf("kept plan file not removed after the apply: %v", err)
+	}
+}
+
+func noIssued(string) *run.Approval {
+	return nil
+}
+
+func fakeTerraform(t *testing.T, dir, plan string) (string, string) {
+	t.Helper()
+	planPath := filepath.Join(dir, "plan.json")
+	logPath := filepath.Join(dir, "calls.log")
+	if err := os.WriteFile(planPath, []byte(plan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$1" in
+plan) echo "Plan: changes" ;;
+show)
+	if [ "$2" = "-json" ]; then cat "` + planPath + `"; else echo "Plan: changes"; fi ;;
+apply) echo "Apply complete!" ;;
+esac
+`
+	program := filepath.Join(dir, "terraform")
+	if err := os.WriteFile(program, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return program, logPath
+}
@@ -0,0 +1,45 @@
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func confirm(prompt, expected string) bool {
+	if !isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "tfjournal: confirmation required but stdin is not a terminal")
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s Type %q to continue: ", prompt, expected)
+	return strings.TrimSpace(readLine(os.Stdin)) == expected
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+func readLine(r io.Reader) string {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			b.WriteByte(buf[0])
+		}
+		if err != nil {
+			break
+		}
+	}
+	return b.String()
+}
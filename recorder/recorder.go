@@ -2,32 +2,84 @@ package recorder
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Owloops/tfjournal/backend"
 	"github.com/Owloops/tfjournal/ci"
+	"github.com/Owloops/tfjournal/cloud"
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/failure"
 	"github.com/Owloops/tfjournal/git"
+	"github.com/Owloops/tfjournal/lock"
 	"github.com/Owloops/tfjournal/parser"
+	"github.com/Owloops/tfjournal/policy"
+	"github.com/Owloops/tfjournal/retry"
 	"github.com/Owloops/tfjournal/run"
 	"github.com/Owloops/tfjournal/storage"
+	"github.com/Owloops/tfjournal/tfversion"
 )
 
+const _notifyTimeout = 15 * time.Second
+
+type Options struct {
+	Config    *config.Config
+	Workspace string
+	Approve   string
+	Retry     *retry.Retrier
+}
+
 type Result struct {
 	Run       *run.Run
 	ExitCode  int
 	SaveError error
 }
 
-func Record(store storage.Store, workspace string, args []string) (*Result, error) {
+func Record(store storage.Store, opts Options, args []string) (*Result, error) {
+	if opts.Retry == nil {
+		result, _, err := record(store, opts, args, "", 0)
+		return result, err
+	}
+
+	var parentID string
+	for attempt := 1; ; attempt++ {
+		result, reason, err := record(store, opts, args, parentID, attempt)
+		if err != nil || reason == "" {
+			return result, err
+		}
+		if parentID == "" {
+			parentID = result.Run.ID
+		}
+
+		delay := opts.Retry.Delay(attempt)
+		PrintSummary(result.Run)
+		fmt.Fprintf(os.Stderr, "tfjournal: transient error %q, retrying in %s (attempt %d/%d)\n\n",
+			reason, delay, attempt+1, opts.Retry.Attempts())
+		time.Sleep(delay)
+	}
+}
+
+func record(store storage.Store, opts Options, args []string, parentID string, attempt int) (*Result, string, error) {
+	cfg := opts.Config
+	workspace := opts.Workspace
+	if workspace == "" {
+		workspace = cfg.Workspace.Name
+	}
 	if workspace == "" {
 		workspace = detectWorkspace()
 	}
 
+	dir := workingDir(args)
 	userName, userEmail := git.GetUser()
 	ciInfo := detectCI()
 
@@ -37,6 +89,8 @@ func Record(store storage.Store, workspace string, args []string) (*Result, erro
 
 	r := &run.Run{
 		ID:        run.NewID(),
+		ParentID:  parentID,
+		Attempt:   attempt,
 		Workspace: workspace,
 		Timestamp: time.Now(),
 		Status:    run.StatusRunning,
@@ -44,24 +98,93 @@ func Record(store storage.Store, workspace string, args []string) (*Result, erro
 		Command:   args,
 		User:      userName,
 		UserEmail: userEmail,
-		Git:       git.GetInfo(),
+		Git:       git.GetInfo(dir),
 		CI:        ciInfo,
+		Cloud:     cloud.Detect(cfg.Cloud),
 	}
+	cfg.Naming().Apply(r)
+	captureDiff(cfg, store, r, dir)
 
-	exitCode, output, execErr := execute(args)
+	allowed, err := enforcePolicy(cfg, store, r)
+	if err != nil {
+		return nil, "", err
+	}
+	if !allowed {
+		r.Status = run.StatusBlocked
+		r.ExitCode = 1
+		saveErr := finish(cfg, store, r, []byte(policySummary(r.Policy)), true)
+		return &Result{Run: r, ExitCode: r.ExitCode, SaveError: saveErr}, "", nil
+	}
+
+	gate, err := cfg.ApprovalGate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		exitCode int
+		output   []byte
+		execErr  error
+	)
+	saveRunning(cfg, store, r)
+	if idx, _ := gatedAction(args); gate != nil && idx >= 0 && gate.Applies(r.Workspace) {
+		exitCode, output, execErr = executeGated(gate, r, args, opts.Approve, issuedApproval(store, r.Workspace))
+	} else {
+		exitCode, output, execErr = execute(args)
+	}
 	r.DurationMs = time.Since(r.Timestamp).Milliseconds()
 	r.ExitCode = exitCode
+	r.Versions = tfversion.Detect(args[0], dir)
+	r.State = backend.Detect(dir)
 
-	if execErr != nil || exitCode != 0 {
+	switch {
+	case r.Status != run.StatusRunning:
+	case detailedExitCode(r) && exitCode == 0:
+		r.Status = run.StatusClean
+	case detailedExitCode(r) && exitCode == 2:
+		r.Status = run.StatusDrift
+	case execErr != nil || exitCode != 0:
 		r.Status = run.StatusFailed
-	} else {
+	default:
 		r.Status = run.StatusSuccess
 	}
 
 	result := parser.Parse(string(output))
 	r.Changes = result.Changes
 	r.Resources = result.Resources
+	r.Planned = result.Planned
+	r.Lock = result.Lock
+	if r.Status == run.StatusFailed {
+		r.Failure = failure.Classify(cfg.RedactString(parser.StripAnsi(string(output))))
+	}
+
+	if gate != nil && r.Action() == "plan" && r.Status.Succeeded() {
+		if reasons := gate.Check(r.Workspace, r.Planned); len(reasons) > 0 {
+			r.Approval = pendingApproval(r, reasons, resolvePath(dir, planOut(args)))
+		}
+	}
+
+	reason := retryReason(opts, r, attempt, output)
+	saveErr := finish(cfg, store, r, output, reason == "")
+	if report, err := lock.Lookup(store, r); err == nil && report != nil {
+		fmt.Fprintf(os.Stderr, "\ntfjournal: state %s\n", report.Holder.Message)
+	}
+	return &Result{Run: r, ExitCode: exitCode, SaveError: saveErr}, reason, nil
+}
+
+func retryReason(opts Options, r *run.Run, attempt int, output []byte) string {
+	if opts.Retry == nil || attempt >= opts.Retry.Attempts() || r.Status != run.StatusFailed || r.Failure == nil {
+		return ""
+	}
+	if r.Failure.Transient {
+		return r.Failure.Message
+	}
+	return opts.Retry.Match(failure.Diagnostics(opts.Config.RedactString(parser.StripAnsi(string(output)))))
+}
+
+func finish(cfg *config.Config, store storage.Store, r *run.Run, output []byte, final bool) error {
 	r.OutputFile = store.OutputPath(r.ID)
+	r.Command = redactCommand(cfg, r.Command)
 
 	var saveErr error
 	if err := store.SaveRun(r); err != nil {
@@ -69,7 +192,7 @@ func Record(store storage.Store, workspace string, args []string) (*Result, erro
 		fmt.Fprintf(os.Stderr, "tfjournal: failed to save run: %v\n", err)
 	}
 
-	cleanOutput := parser.StripAnsi(string(output))
+	cleanOutput := cfg.RedactString(parser.StripAnsi(string(output)))
 	if err := store.SaveOutput(r.ID, []byte(cleanOutput)); err != nil {
 		if saveErr == nil {
 			saveErr = err
@@ -77,17 +200,194 @@ func Record(store storage.Store, workspace string, args []string) (*Result, erro
 		fmt.Fprintf(os.Stderr, "tfjournal: failed to save output: %v\n", err)
 	}
 
-	return &Result{Run: r, ExitCode: exitCode, SaveError: saveErr}, nil
+	if exporter := cfg.Exporter(); exporter != nil {
+		if err := exporter.Export(r); err != nil {
+			fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+		}
+	}
+
+	if final {
+		notify(cfg, r)
+	}
+
+	if cfg.MaxAge() > 0 || cfg.Retention.MaxRuns > 0 {
+		if _, err := storage.PruneIfDue(store, cfg.MaxAge(), cfg.Retention.MaxRuns); err != nil {
+			fmt.Fprintf(os.Stderr, "tfjournal: failed to apply retention: %v\n", err)
+		}
+	}
+
+	return saveErr
+}
+
+func notify(cfg *config.Config, r *run.Run) {
+	ctx, cancel := context.WithTimeout(context.Background(), _notifyTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	notifier, err := cfg.Notifier()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+	} else if notifier != nil {
+		wg.Go(func() {
+			if err := notifier.Notify(ctx, r); err != nil {
+				fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+			}
+		})
+	}
+
+	commenter, err := cfg.Commenter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+	} else if commenter != nil {
+		wg.Go(func() {
+			if err := commenter.Post(ctx, r); err != nil {
+				fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func saveRunning(cfg *config.Config, store storage.Store, r *run.Run) {
+	snapshot := *r
+	snapshot.Command = redactCommand(cfg, r.Command)
+	if err := store.SaveRun(&snapshot); err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: failed to save run: %v\n", err)
+	}
+}
+
+func redactCommand(cfg *config.Config, args []string) []string {
+	command := make([]string, len(args))
+	for i, arg := range args {
+		command[i] = cfg.RedactString(arg)
+	}
+	return command
+}
+
+func captureDiff(cfg *config.Config, store storage.Store, r *run.Run, dir string) {
+	if !cfg.Git.CaptureDiff || r.Git == nil || !r.Git.Dirty {
+		return
+	}
+
+	diff, truncated := git.Diff(dir, cfg.Git.MaxDiffBytes)
+	if len(diff) == 0 {
+		return
+	}
+	redacted := []byte(cfg.RedactDiff(string(diff)))
+	if cfg.Git.MaxDiffBytes > 0 && len(redacted) > cfg.Git.MaxDiffBytes {
+		redacted = cutDiff(redacted, cfg.Git.MaxDiffBytes)
+		truncated = true
+	}
+	if err := store.SaveDiff(r.ID, redacted); err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: failed to save diff: %v\n", err)
+		return
+	}
+	r.Git.DiffBytes = len(redacted)
+	r.Git.DiffTruncated = truncated
+}
+
+func cutDiff(diff []byte, n int) []byte {
+	diff = diff[:n]
+	if i := bytes.LastIndexByte(diff, '\n'); i >= 0 {
+		return diff[:i+1]
+	}
+	for i := 0; i < utf8.UTFMax && len(diff) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(diff); r != utf8.RuneError || size > 1 {
+			break
+		}
+		diff = diff[:len(diff)-1]
+	}
+	return diff
+}
+
+func issuedApproval(store storage.Store, workspace string) func(token string) *run.Approval {
+	return func(token string) *run.Approval {
+		runs, err := store.ListRuns(storage.ListOptions{Workspace: workspace})
+		if err != nil {
+			return nil
+		}
+		var issued *run.Approval
+		for _, r := range runs {
+			if r.Workspace != workspace || r.Approval == nil || r.Approval.Token != token {
+				continue
+			}
+			if r.Approval.Decision == run.ApprovalApproved {
+				return nil
+			}
+			if r.Approval.Nonce != "" {
+				issued = r.Approval
+			}
+		}
+		return issued
+	}
+}
+
+func enforcePolicy(cfg *config.Config, store storage.Store, r *run.Run) (bool, error) {
+	engine, err := cfg.PolicyEngine()
+	if err != nil {
+		return false, err
+	}
+	if engine == nil {
+		return true, nil
+	}
+
+	r.Policy = engine.Evaluate(r, store)
+	for _, v := range r.Policy.Violations {
+		fmt.Fprintf(os.Stderr, "tfjournal: policy %q (%s): %s\n", v.Rule, v.Effect, v.Message)
+	}
+
+	if policy.NeedsConfirmation(r.Policy) {
+		if confirm("tfjournal: policy requires confirmation.", "yes") {
+			r.Policy.Decision = run.PolicyConfirmed
+		} else {
+			r.Policy.Decision = run.PolicyBlocked
+		}
+	}
+
+	return r.Policy.Decision != run.PolicyBlocked, nil
+}
+
+func policySummary(p *run.Policy) string {
+	var b strings.Builder
+	b.WriteString("tfjournal: blocked by policy\n")
+	for _, v := range p.Violations {
+		fmt.Fprintf(&b, "  %s (%s): %s\n", v.Rule, v.Effect, v.Message)
+	}
+	return b.String()
 }
 
 func PrintSummary(r *run.Run) {
 	status := "✓"
-	if r.Status == run.StatusFailed {
+	if !r.Status.Succeeded() {
 		status = "✗"
 	}
 
+	summary := r.ChangeSummary()
+	switch {
+	case r.Status == run.StatusDrift:
+		status = "⚠"
+		summary = "drift detected, " + summary
+	case r.Status == run.StatusClean:
+		summary = "no drift"
+	case r.Approval != nil && r.Approval.Decision == run.ApprovalRejected:
+		summary = "approval rejected"
+	case r.Status == run.StatusBlocked:
+		summary = "blocked by policy"
+	case r.Status == run.StatusCanceled:
+		summary = "canceled"
+	case r.Failure != nil:
+		summary = "failed: " + r.Failure.Category
+		if r.Failure.Transient {
+			summary += " (transient)"
+		}
+	}
+
+	if r.Attempt > 1 {
+		summary += fmt.Sprintf(", attempt %d", r.Attempt)
+	}
+
 	fmt.Fprintf(os.Stderr, "\n%s tfjournal: recorded %s (%s) %s\n",
-		status, r.ID, r.Duration().Round(time.Second), r.ChangeSummary())
+		status, r.ID, r.Duration().Round(time.Second), summary)
 }
 
 func execute(args []string) (int, []byte, error) {
@@ -106,7 +406,16 @@ func execute(args []string) (int, []byte, error) {
 	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
 	cmd.Stdin = os.Stdin
 
-	err := cmd.Run()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	err := cmd.Start()
+	if err == nil {
+		go forwardSignals(cmd.Process, signals)
+		err = cmd.Wait()
+	}
+	signal.Stop(signals)
+	close(signals)
 
 	exitCode := 0
 	if err != nil {
@@ -120,6 +429,38 @@ func execute(args []string) (int, []byte, error) {
 	return exitCode, output.Bytes(), err
 }
 
+func forwardSignals(p *os.Process, signals <-chan os.Signal) {
+	for sig := range signals {
+		if sig != os.Interrupt {
+			_ = p.Signal(sig)
+		}
+	}
+}
+
+func detailedExitCode(r *run.Run) bool {
+	if r.Action() != "plan" {
+		return false
+	}
+	for _, arg := range r.Command {
+		if arg == "-detailed-exitcode" || arg == "--detailed-exitcode" {
+			return true
+		}
+	}
+	return false
+}
+
+func workingDir(args []string) string {
+	for _, arg := range args[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		if dir, ok := strings.CutPrefix(arg, "-chdir="); ok {
+			return dir
+		}
+	}
+	return ""
+}
+
 func detectWorkspace() string {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -159,10 +500,18 @@ func detectCI() *run.CIInfo {
 		return nil
 	}
 	return &run.CIInfo{
-		Provider: info.Provider,
-		RunID:    info.RunID,
-		Workflow: info.Workflow,
-		Actor:    info.Actor,
-		URL:      info.URL,
+		Provider:       info.Provider,
+		RunID:          info.RunID,
+		Workflow:       info.Workflow,
+		Job:            info.Job,
+		Attempt:        info.Attempt,
+		Event:          info.Event,
+		Actor:          info.Actor,
+		PullRequest:    info.PullRequest,
+		PullRequestURL: info.PullRequestURL,
+		SourceBranch:   info.SourceBranch,
+		TargetBranch:   info.TargetBranch,
+		Commit:         info.Commit,
+		URL:            info.URL,
 	}
 }
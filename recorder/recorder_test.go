@@ -0,0 +1,132 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Owloops/tfjournal/config"
+	"github.com/Owloops/tfjournal/failure"
+	"github.com/Owloops/tfjournal/retry"
+	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/storage"
+)
+
+func TestRecord_SavesRunningRecord(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the terraform binary")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	cfg, err := config.LoadFrom(dir)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	storePath := filepath.Join(dir, "store")
+	store, err := storage.New(storePath)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	program := filepath.Join(dir, "terraform")
+	script := `#!/bin/sh
+[ "$1" = "version" ] && exit 0
+find "` + storePath + `/runs" -name '*.json' -exec cat {} \;
+`
+	if err := os.WriteFile(program, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Record(store, Options{Config: cfg, Workspace: "test"}, []string{program, "apply"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if result.Run.Status != run.StatusSuccess {
+		t.Errorf("status = %q, want success", result.Run.Status)
+	}
+
+	output, err := store.GetOutput(result.Run.ID)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !strings.Contains(string(output), `"status": "running"`) || !strings.Contains(string(output), result.Run.ID) {
+		t.Errorf("run was not saved as running before execution; output:\n%s", output)
+	}
+
+	saved, err := store.GetRun(result.Run.ID)
+	if err != nil || saved.Status != run.StatusSuccess {
+		t.Errorf("saved run = %+v, %v, want status success", saved, err)
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	cfg, err := config.LoadFrom(dir)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	retrier, err := retry.New(retry.Config{Attempts: 3, Patterns: []string{`ConcurrentModification`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Config: cfg, Retry: retrier}
+
+	tests := []struct {
+		name    string
+		output  string
+		attempt int
+		want    string
+	}{
+		{"transient", "│ Error: Error acquiring the state lock\n", 1, "Error acquiring the state lock"},
+		{"last attempt", "│ Error: Error acquiring the state lock\n", 3, ""},
+		{"config error naming a throttle resource", "│ Error: Unsupported argument\n│\n│   on main.tf line 3, in resource \"aws_lambda_function\" \"throttle_handler\":\n", 1, ""},
+		{"custom pattern", "│ Error: ConcurrentModificationException: update in progress\n", 1, "ConcurrentModification"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &run.Run{Status: run.StatusFailed, Failure: failure.Classify(tt.output)}
+			if got := retryReason(opts, r, tt.attempt, []byte(tt.output)); got != tt.want {
+				t.Errorf("retryReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkingDir(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"terraform", "plan"}, ""},
+		{[]string{"terraform", "-chdir=envs/prod", "apply", "-auto-approve"}, "envs/prod"},
+		{[]string{"terraform", "plan", "-chdir=ignored"}, ""},
+	}
+	for _, tt := range tests {
+		if got := workingDir(tt.args); got != tt.want {
+			t.Errorf("workingDir(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCutDiff(t *testing.T) {
+	tests := []struct {
+		diff string
+		n    int
+		want string
+	}{
+		{"+a = 1\n+b = 2\n", 10, "+a = 1\n"},
+		{"+name = \"héllo\"", 11, "+name = \"h"},
+		{"+name = \"héllo\"", 12, "+name = \"hé"},
+	}
+	for _, tt := range tests {
+		if got := string(cutDiff([]byte(tt.diff), tt.n)); got != tt.want {
+			t.Errorf("cutDiff(%q, %d) = %q, want %q", tt.diff, tt.n, got, tt.want)
+		}
+	}
+}
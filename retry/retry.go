@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	_defaultAttempts   = 3
+	_defaultBackoff    = 10 * time.Second
+	_defaultMaxBackoff = 2 * time.Minute
+)
+
+type Config struct {
+	Attempts   int      `json:"attempts,omitempty"`
+	Backoff    string   `json:"backoff,omitempty"`
+	MaxBackoff string   `json:"max_backoff,omitempty"`
+	Patterns   []string `json:"patterns,omitempty"`
+}
+
+type Retrier struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	patterns   []*regexp.Regexp
+}
+
+func New(cfg Config) (*Retrier, error) {
+	r := &Retrier{
+		attempts:   cfg.Attempts,
+		backoff:    _defaultBackoff,
+		maxBackoff: _defaultMaxBackoff,
+	}
+	if r.attempts <= 0 {
+		r.attempts = _defaultAttempts
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"backoff", cfg.Backoff, &r.backoff},
+		{"max_backoff", cfg.MaxBackoff, &r.maxBackoff},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid retry %s %q", d.name, d.value)
+		}
+		*d.dst = parsed
+	}
+
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func (r *Retrier) Attempts() int {
+	return r.attempts
+}
+
+func (r *Retrier) Match(output string) string {
+	for _, re := range r.patterns {
+		if m := re.FindString(output); m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+func (r *Retrier) Delay(attempt int) time.Duration {
+	d := r.backoff
+	for i := 1; i < attempt && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.maxBackoff)
+}
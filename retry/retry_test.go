@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	r, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if r.Attempts() != _defaultAttempts || r.backoff != _defaultBackoff || len(r.patterns) != 0 {
+		t.Errorf("New() defaults = %+v", r)
+	}
+
+	for _, cfg := range []Config{
+		{Backoff: "soon"},
+		{MaxBackoff: "-1s"},
+		{Patterns: []string{"("}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) expected error", cfg)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	r, err := New(Config{Patterns: []string{`ConcurrentModification`, `(?i)quota.*retry later`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"Error: ConcurrentModificationException: update in progress", "ConcurrentModification"},
+		{"Error: Quota exhausted, retry later", "Quota exhausted, retry later"},
+		{"Error: Unsupported argument", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := r.Match(tt.output); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+
+	none, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := none.Match("Error: Error acquiring the state lock"); got != "" {
+		t.Errorf("Match() without patterns = %q, want empty", got)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	r, err := New(Config{Backoff: "5s", MaxBackoff: "30s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, w := range want {
+		if got := r.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
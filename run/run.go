@@ -17,8 +17,24 @@ const (
 	StatusSuccess  Status = "success"
 	StatusFailed   Status = "failed"
 	StatusCanceled Status = "canceled"
+	StatusBlocked  Status = "blocked"
+	StatusDrift    Status = "drift"
+	StatusClean    Status = "clean"
 )
 
+func (s Status) Valid() bool {
+	switch s {
+	case StatusRunning, StatusSuccess, StatusFailed, StatusCanceled, StatusBlocked, StatusDrift, StatusClean:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Status) Succeeded() bool {
+	return s == StatusSuccess || s == StatusDrift || s == StatusClean
+}
+
 type SyncStatus string
 
 const (
@@ -28,38 +44,175 @@ const (
 )
 
 type Run struct {
-	ID         string     `json:"id"`
-	Workspace  string     `json:"workspace"`
-	Timestamp  time.Time  `json:"timestamp"`
-	DurationMs int64      `json:"duration_ms"`
-	Status     Status     `json:"status"`
-	ExitCode   int        `json:"exit_code"`
-	Program    string     `json:"program"`
-	Command    []string   `json:"command"`
-	User       string     `json:"user"`
-	UserEmail  string     `json:"user_email,omitempty"`
-	Git        *GitInfo   `json:"git,omitempty"`
-	CI         *CIInfo    `json:"ci,omitempty"`
-	Changes    *Changes   `json:"changes,omitempty"`
-	Resources  []Resource `json:"resources,omitempty"`
-	OutputFile string     `json:"output_file,omitempty"`
-	SyncStatus SyncStatus `json:"sync_status,omitempty"`
+	ID           string     `json:"id"`
+	ParentID     string     `json:"parent_id,omitempty"`
+	Attempt      int        `json:"attempt,omitempty"`
+	Workspace    string     `json:"workspace"`
+	Environment  string     `json:"environment,omitempty"`
+	Component    string     `json:"component,omitempty"`
+	RawWorkspace string     `json:"raw_workspace,omitempty"`
+	Timestamp    time.Time  `json:"timestamp"`
+	DurationMs   int64      `json:"duration_ms"`
+	Status       Status     `json:"status"`
+	ExitCode     int        `json:"exit_code"`
+	Program      string     `json:"program"`
+	Command      []string   `json:"command"`
+	User         string     `json:"user"`
+	UserEmail    string     `json:"user_email,omitempty"`
+	Git          *GitInfo   `json:"git,omitempty"`
+	CI           *CIInfo    `json:"ci,omitempty"`
+	Versions     *Versions  `json:"versions,omitempty"`
+	State        *StateInfo `json:"state,omitempty"`
+	Cloud        *CloudInfo `json:"cloud,omitempty"`
+	Changes      *Changes   `json:"changes,omitempty"`
+	Resources    []Resource `json:"resources,omitempty"`
+	Planned      []Planned  `json:"planned,omitempty"`
+	Policy       *Policy    `json:"policy,omitempty"`
+	Approval     *Approval  `json:"approval,omitempty"`
+	Failure      *Failure   `json:"failure,omitempty"`
+	Lock         *LockInfo  `json:"lock,omitempty"`
+	OutputFile   string     `json:"output_file,omitempty"`
+	SyncStatus   SyncStatus `json:"sync_status,omitempty"`
 }
 
 type CIInfo struct {
-	Provider string `json:"provider"`
-	RunID    string `json:"run_id,omitempty"`
-	Workflow string `json:"workflow,omitempty"`
-	Actor    string `json:"actor,omitempty"`
-	URL      string `json:"url,omitempty"`
+	Provider       string `json:"provider"`
+	RunID          string `json:"run_id,omitempty"`
+	Workflow       string `json:"workflow,omitempty"`
+	Job            string `json:"job,omitempty"`
+	Attempt        int    `json:"attempt,omitempty"`
+	Event          string `json:"event,omitempty"`
+	Actor          string `json:"actor,omitempty"`
+	PullRequest    string `json:"pull_request,omitempty"`
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+	SourceBranch   string `json:"source_branch,omitempty"`
+	TargetBranch   string `json:"target_branch,omitempty"`
+	Commit         string `json:"commit,omitempty"`
+	URL            string `json:"url,omitempty"`
 }
 
 type GitInfo struct {
-	Commit  string `json:"commit"`
-	Branch  string `json:"branch"`
-	Dirty   bool   `json:"dirty"`
-	Remote  string `json:"remote,omitempty"`
-	Message string `json:"message,omitempty"`
+	Commit         string    `json:"commit"`
+	SHA            string    `json:"sha,omitempty"`
+	Branch         string    `json:"branch"`
+	Dirty          bool      `json:"dirty"`
+	DirtyFiles     []string  `json:"dirty_files,omitempty"`
+	Remote         string    `json:"remote,omitempty"`
+	RemoteURL      string    `json:"remote_url,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	Author         string    `json:"author,omitempty"`
+	AuthorEmail    string    `json:"author_email,omitempty"`
+	Committer      string    `json:"committer,omitempty"`
+	CommitterEmail string    `json:"committer_email,omitempty"`
+	CommitTime     time.Time `json:"commit_time,omitzero"`
+	Tags           []string  `json:"tags,omitempty"`
+	Upstream       string    `json:"upstream,omitempty"`
+	Ahead          int       `json:"ahead,omitempty"`
+	Behind         int       `json:"behind,omitempty"`
+	DiffBytes      int       `json:"diff_bytes,omitempty"`
+	DiffTruncated  bool      `json:"diff_truncated,omitempty"`
+}
+
+type CloudInfo struct {
+	AWSAccountID string            `json:"aws_account_id,omitempty"`
+	AWSRoleARN   string            `json:"aws_role_arn,omitempty"`
+	AWSProfile   string            `json:"aws_profile,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	TFVars       []string          `json:"tf_vars,omitempty"`
+}
+
+type StateInfo struct {
+	Backend   string `json:"backend"`
+	ID        string `json:"id,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+}
+
+type Versions struct {
+	Binary     string            `json:"binary,omitempty"`
+	Version    string            `json:"version,omitempty"`
+	Terragrunt string            `json:"terragrunt,omitempty"`
+	Providers  []ProviderVersion `json:"providers,omitempty"`
+	Modules    []ModuleVersion   `json:"modules,omitempty"`
+}
+
+type ProviderVersion struct {
+	Source      string   `json:"source"`
+	Version     string   `json:"version"`
+	Constraints string   `json:"constraints,omitempty"`
+	Hashes      []string `json:"hashes,omitempty"`
+}
+
+type ModuleVersion struct {
+	Key     string `json:"key"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+}
+
+const (
+	PolicyAllowed   = "allowed"
+	PolicyConfirmed = "confirmed"
+	PolicyBlocked   = "blocked"
+)
+
+type Policy struct {
+	Decision   string            `json:"decision"`
+	Violations []PolicyViolation `json:"violations,omitempty"`
+}
+
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Effect  string `json:"effect"`
+	Message string `json:"message"`
+}
+
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalPending  = "pending"
+
+	ApprovalMethodPrompt          = "prompt"
+	ApprovalMethodToken           = "token"
+	ApprovalMethodTokenUnverified = "token_unverified_approver"
+)
+
+type Approval struct {
+	Decision  string    `json:"decision"`
+	Approver  string    `json:"approver,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Reasons   []string  `json:"reasons,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+	PlanHash  string    `json:"plan_hash,omitempty"`
+	PlanFile  string    `json:"plan_file,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+const (
+	FailureStateLock   = "state_lock"
+	FailureAuth        = "auth"
+	FailureThrottling  = "throttling"
+	FailureServerError = "server_error"
+	FailureProviderBug = "provider_bug"
+	FailureConfig      = "config"
+	FailureTimeout     = "timeout"
+	FailureUnknown     = "unknown"
+)
+
+type Failure struct {
+	Category  string `json:"category"`
+	Transient bool   `json:"transient,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+type LockInfo struct {
+	ID        string    `json:"id,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Operation string    `json:"operation,omitempty"`
+	Who       string    `json:"who,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Created   time.Time `json:"created,omitzero"`
+	Info      string    `json:"info,omitempty"`
 }
 
 type Changes struct {
@@ -78,6 +231,29 @@ type Resource struct {
 	Status     string    `json:"status,omitempty"`
 }
 
+type Planned struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+func (r Resource) Type() string {
+	parts := strings.Split(r.Address, ".")
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if idx := strings.Index(part, "["); idx >= 0 {
+			part = part[:idx]
+		}
+		switch part {
+		case "module":
+			i++
+		case "data":
+		default:
+			return part
+		}
+	}
+	return ""
+}
+
 func NewID() string {
 	return GenerateID(time.Now())
 }
@@ -115,12 +291,112 @@ func (r *Run) ChangeSummary() string {
 	return formatChanges(r.Changes.Add, r.Changes.Change, r.Changes.Destroy)
 }
 
+func (g *GitInfo) Summary() string {
+	s := fmt.Sprintf("%s (%s)", g.Commit, g.Branch)
+	if len(g.Tags) > 0 {
+		s += " [" + strings.Join(g.Tags, ", ") + "]"
+	}
+	if g.Ahead > 0 {
+		s += fmt.Sprintf(" ↑%d", g.Ahead)
+	}
+	if g.Behind > 0 {
+		s += fmt.Sprintf(" ↓%d", g.Behind)
+	}
+	if g.Dirty {
+		s += " [dirty]"
+	}
+	return s
+}
+
+func (g *GitInfo) AuthorSummary() string {
+	if g.Author == "" {
+		return ""
+	}
+	s := g.Author
+	if g.Committer != "" && g.Committer != g.Author {
+		s += " (committed by " + g.Committer + ")"
+	}
+	return s
+}
+
+func (c *CloudInfo) Account() string {
+	if c.AWSAccountID != "" {
+		return c.AWSAccountID
+	}
+	for _, k := range accountEnv {
+		if v := c.Env[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c *CloudInfo) Matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	if c.AWSAccountID == value || c.AWSRoleARN == value {
+		return true
+	}
+	for _, k := range accountEnv {
+		if c.Env[k] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *StateInfo) Summary() string {
+	id := s.ID
+	if id == "" {
+		id = s.Backend
+	}
+	if s.Workspace != "" {
+		id += " [" + s.Workspace + "]"
+	}
+	return id
+}
+
+func (v *Versions) Summary() string {
+	if v.Version == "" {
+		return v.Binary
+	}
+	s := v.Binary + " " + v.Version
+	if v.Terragrunt != "" {
+		s += " (terragrunt " + v.Terragrunt + ")"
+	}
+	return s
+}
+
+func (v *Versions) Provider(name string) *ProviderVersion {
+	for i := range v.Providers {
+		if p := &v.Providers[i]; p.Source == name || strings.HasSuffix(p.Source, "/"+name) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p ProviderVersion) Name() string {
+	return p.Source[strings.LastIndex(p.Source, "/")+1:]
+}
+
+func MatchVersion(version, want string) bool {
+	want = strings.TrimPrefix(want, "v")
+	return version == want || strings.HasPrefix(version, want+".")
+}
+
 func formatChanges(add, change, destroy int) string {
 	return "+" + strconv.Itoa(add) + " ~" + strconv.Itoa(change) + " -" + strconv.Itoa(destroy)
 }
 
 var idPattern = regexp.MustCompile(`^run_\d{8}T\d{6}_[0-9a-f]{8}$`)
 
+var accountEnv = []string{
+	"GOOGLE_PROJECT", "GOOGLE_CLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT",
+	"ARM_SUBSCRIPTION_ID", "AZURE_SUBSCRIPTION_ID",
+}
+
 func ValidateID(id string) error {
 	if !idPattern.MatchString(id) {
 		return fmt.Errorf("invalid run ID format: %s", id)
@@ -82,6 +82,30 @@ func TestParseDateFromID(t *testing.T) {
 	}
 }
 
+func TestStatusSucceeded(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   bool
+	}{
+		{StatusSuccess, true},
+		{StatusClean, true},
+		{StatusDrift, true},
+		{StatusFailed, false},
+		{StatusBlocked, false},
+		{StatusCanceled, false},
+		{StatusRunning, false},
+	}
+
+	for _, tt := range tests {
+		if !tt.status.Valid() {
+			t.Errorf("%s.Valid() = false", tt.status)
+		}
+		if got := tt.status.Succeeded(); got != tt.want {
+			t.Errorf("%s.Succeeded() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
 func TestParseDuration(t *testing.T) {
 	tests := []struct {
 		input string
@@ -132,3 +156,51 @@ func TestRunAction(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceType(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"aws_instance.web", "aws_instance"},
+		{"aws_subnet.private[0]", "aws_subnet"},
+		{"module.vpc.aws_subnet.private[\"a\"]", "aws_subnet"},
+		{"module.app[0].module.db.aws_db_instance.main", "aws_db_instance"},
+		{"data.aws_ami.ubuntu", "aws_ami"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			r := Resource{Address: tt.address}
+			if got := r.Type(); got != tt.want {
+				t.Errorf("Type() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitInfoSummary(t *testing.T) {
+	g := &GitInfo{
+		Commit:    "abc1234",
+		Branch:    "main",
+		Dirty:     true,
+		Tags:      []string{"v1.2.0"},
+		Ahead:     2,
+		Behind:    1,
+		Author:    "Alice",
+		Committer: "GitHub",
+	}
+
+	if got, want := g.Summary(), "abc1234 (main) [v1.2.0] ↑2 ↓1 [dirty]"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got, want := g.AuthorSummary(), "Alice (committed by GitHub)"; got != want {
+		t.Errorf("AuthorSummary() = %q, want %q", got, want)
+	}
+
+	g.Committer = "Alice"
+	if got := g.AuthorSummary(); got != "Alice" {
+		t.Errorf("AuthorSummary() = %q, want Alice", got)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/subtle"
 	"embed"
 	"encoding/json"
@@ -8,9 +9,17 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/Owloops/tfjournal/compare"
+	"github.com/Owloops/tfjournal/drift"
+	"github.com/Owloops/tfjournal/failure"
+	"github.com/Owloops/tfjournal/lock"
+	"github.com/Owloops/tfjournal/metrics"
 	"github.com/Owloops/tfjournal/run"
+	"github.com/Owloops/tfjournal/stats"
 	"github.com/Owloops/tfjournal/storage"
 )
 
@@ -22,26 +31,38 @@ var Version = "dev"
 var distFS embed.FS
 
 type Server struct {
-	store storage.Store
-	mux   *http.ServeMux
-	hasS3 bool
+	store   storage.Store
+	mux     *http.ServeMux
+	hasS3   bool
+	metrics *metrics.Registry
 }
 
 func New(store storage.Store) *Server {
 	_, hasS3 := store.(*storage.HybridStore)
 	s := &Server{
-		store: store,
-		mux:   http.NewServeMux(),
-		hasS3: hasS3,
+		store:   store,
+		mux:     http.NewServeMux(),
+		hasS3:   hasS3,
+		metrics: metrics.NewRegistry(),
 	}
 
 	s.mux.HandleFunc("GET /api/runs", s.handleListRuns)
 	s.mux.HandleFunc("GET /api/runs/local", s.handleListRunsLocal)
 	s.mux.HandleFunc("GET /api/runs/{id}", s.handleGetRun)
 	s.mux.HandleFunc("GET /api/runs/{id}/output", s.handleGetOutput)
+	s.mux.HandleFunc("GET /api/runs/{id}/diff", s.handleGetDiff)
+	s.mux.HandleFunc("GET /api/runs/{id}/diff/{other}", s.handleCompareRuns)
+	s.mux.HandleFunc("GET /api/runs/{id}/lock", s.handleGetLock)
+	s.mux.HandleFunc("GET /api/resources/{address...}", s.handleResourceHistory)
+	s.mux.HandleFunc("GET /api/search", s.handleSearch)
+	s.mux.HandleFunc("GET /api/stats", s.handleStats)
+	s.mux.HandleFunc("GET /api/drift", s.handleDrift)
+	s.mux.HandleFunc("GET /api/trends", s.handleTrends)
+	s.mux.HandleFunc("GET /api/failures", s.handleFailures)
 	s.mux.HandleFunc("GET /api/version", s.handleGetVersion)
 	s.mux.HandleFunc("GET /api/config", s.handleGetConfig)
 	s.mux.HandleFunc("POST /api/sync", s.handleSync)
+	s.mux.Handle("GET /metrics", s.metrics)
 
 	distSubFS, _ := fs.Sub(distFS, "dist")
 	s.mux.Handle("GET /", http.FileServer(http.FS(distSubFS)))
@@ -53,6 +74,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
+func (s *Server) WatchMetrics(ctx context.Context, interval time.Duration) {
+	s.metrics.Watch(ctx, s.store, interval)
+}
+
 func (s *Server) WithBasicAuth(username, password string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user, pass, ok := r.BasicAuth()
@@ -67,61 +92,299 @@ func (s *Server) WithBasicAuth(username, password string) http.Handler {
 	})
 }
 
-func (s *Server) parseListOptions(r *http.Request) storage.ListOptions {
+func (s *Server) parseListOptions(r *http.Request) (storage.ListOptions, error) {
+	q := r.URL.Query()
 	opts := storage.ListOptions{Limit: DefaultLimit}
 
-	if status := r.URL.Query().Get("status"); status != "" {
+	if status := q.Get("status"); status != "" {
 		opts.Status = run.Status(status)
+		if !opts.Status.Valid() {
+			return opts, fmt.Errorf("invalid status %q: must be one of running, success, failed, canceled, blocked, drift, clean", status)
+		}
 	}
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		_, _ = fmt.Sscanf(limitStr, "%d", &opts.Limit)
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q: must be a non-negative integer", limitStr)
+		}
+		opts.Limit = limit
 	}
-	if workspace := r.URL.Query().Get("workspace"); workspace != "" {
+	if workspace := q.Get("workspace"); workspace != "" {
 		opts.Workspace = workspace
 	}
-	if user := r.URL.Query().Get("user"); user != "" {
+	if user := q.Get("user"); user != "" {
 		opts.User = user
 	}
-	if since := r.URL.Query().Get("since"); since != "" {
-		if d, err := run.ParseDuration(since); err == nil {
-			opts.Since = time.Now().Add(-d)
+	if since := q.Get("since"); since != "" {
+		d, err := run.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid since %q: must be a positive duration (e.g. 7d, 24h)", since)
 		}
+		opts.Since = time.Now().Add(-d)
 	}
-	if program := r.URL.Query().Get("program"); program != "" {
+	if program := q.Get("program"); program != "" {
 		opts.Program = program
 	}
-	if action := r.URL.Query().Get("action"); action != "" {
+	if action := q.Get("action"); action != "" {
 		opts.Action = action
 	}
-	if branch := r.URL.Query().Get("branch"); branch != "" {
+	if branch := q.Get("branch"); branch != "" {
 		opts.Branch = branch
 	}
-	if r.URL.Query().Get("has-changes") == "true" {
+	if pr := q.Get("pr"); pr != "" {
+		opts.PR = pr
+	}
+	if version := q.Get("version"); version != "" {
+		opts.Version = version
+	}
+	if provider := q.Get("provider"); provider != "" {
+		opts.Provider = provider
+	}
+	if stateID := q.Get("state"); stateID != "" {
+		opts.State = stateID
+	}
+	if account := q.Get("account"); account != "" {
+		opts.Account = account
+	}
+	if parent := q.Get("parent"); parent != "" {
+		if err := run.ValidateID(parent); err != nil {
+			return opts, fmt.Errorf("invalid parent %q", parent)
+		}
+		opts.Parent = parent
+	}
+	if q.Get("has-changes") == "true" {
 		opts.HasChanges = true
 	}
+	if before := q.Get("before"); before != "" {
+		if err := run.ValidateID(before); err != nil {
+			return opts, fmt.Errorf("invalid before cursor %q", before)
+		}
+		opts.Before = before
+	}
+	if after := q.Get("after"); after != "" {
+		if err := run.ValidateID(after); err != nil {
+			return opts, fmt.Errorf("invalid after cursor %q", after)
+		}
+		opts.After = after
+	}
+	if opts.Before != "" && opts.After != "" {
+		return opts, errors.New("before and after cannot be combined")
+	}
 
-	return opts
+	return opts, nil
 }
 
 func (s *Server) handleListRunsLocal(w http.ResponseWriter, r *http.Request) {
-	opts := s.parseListOptions(r)
-	runs, err := s.store.ListRunsLocal(opts)
+	s.handleListPage(w, r, s.store.ListRunsLocal)
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	s.handleListPage(w, r, s.store.ListRuns)
+}
+
+func (s *Server) handleListPage(w http.ResponseWriter, r *http.Request, list func(storage.ListOptions) ([]*run.Run, error)) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("page") != "true" && opts.Before == "" && opts.After == "" {
+		runs, err := list(opts)
+		if err != nil {
+			s.jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.jsonResponse(w, runs)
+		return
+	}
+
+	all := opts
+	all.Limit = 0
+	all.Before = ""
+	all.After = ""
+	runs, err := list(all)
 	if err != nil {
 		s.jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	s.jsonResponse(w, runs)
+	s.jsonResponse(w, storage.Paginate(runs, opts))
 }
 
-func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
-	opts := s.parseListOptions(r)
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dims, err := stats.ParseDimensions(r.URL.Query().Get("by"))
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts.Limit = 0
+	opts.Before = ""
+	opts.After = ""
 	runs, err := s.store.ListRuns(opts)
 	if err != nil {
 		s.jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if slices.Contains(dims, stats.ByFailure) {
+		if err := failure.Fill(s.store, runs); err != nil {
+			s.jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
-	s.jsonResponse(w, runs)
+	s.jsonResponse(w, stats.Compute(runs, dims))
+}
+
+func (s *Server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts.Status = run.StatusFailed
+	opts.Limit = 0
+	opts.Before = ""
+	opts.After = ""
+	runs, err := s.store.ListRuns(opts)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := failure.Fill(s.store, runs); err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, stats.Failures(runs))
+}
+
+func (s *Server) handleTrends(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	trendOpts := stats.TrendOptions{By: q.Get("by"), Action: q.Get("resource_action")}
+	if trendOpts.By != "" {
+		if err := stats.ValidateTrendBy(trendOpts.By); err != nil {
+			s.jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("threshold"); v != "" {
+		pct, err := strconv.Atoi(v)
+		if err != nil || pct <= 0 {
+			s.jsonError(w, fmt.Sprintf("invalid threshold %q: must be a positive percentage", v), http.StatusBadRequest)
+			return
+		}
+		trendOpts.Threshold = float64(pct) / 100
+	}
+	if v := q.Get("recent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.jsonError(w, fmt.Sprintf("invalid recent %q: must be a positive integer", v), http.StatusBadRequest)
+			return
+		}
+		trendOpts.Recent = n
+	}
+
+	limit := opts.Limit
+	opts.Limit = 0
+	opts.Before = ""
+	opts.After = ""
+	runs, err := s.store.ListRuns(opts)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report := stats.ResourceTrends(runs, trendOpts)
+	if limit > 0 && len(report.Trends) > limit {
+		report.Trends = report.Trends[:limit]
+	}
+	s.jsonResponse(w, report)
+}
+
+func (s *Server) handleDrift(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts.Action = "plan"
+	opts.Status = ""
+	opts.Limit = 0
+	opts.Before = ""
+	opts.After = ""
+	runs, err := s.store.ListRuns(opts)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, drift.Compute(runs))
+}
+
+func (s *Server) handleResourceHistory(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if address == "" {
+		s.jsonError(w, "missing resource address", http.StatusBadRequest)
+		return
+	}
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := s.store.ResourceHistory(address, opts)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []storage.ResourceEvent{}
+	}
+
+	s.jsonResponse(w, events)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		s.jsonError(w, "missing search query", http.StatusBadRequest)
+		return
+	}
+	opts, err := s.parseListOptions(r)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.store.Search(query, opts)
+	if err != nil {
+		if errors.Is(err, storage.ErrEmptyQuery) {
+			s.jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []storage.SearchResult{}
+	}
+
+	s.jsonResponse(w, results)
 }
 
 func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
@@ -148,6 +411,51 @@ func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, run)
 }
 
+func (s *Server) handleGetLock(w http.ResponseWriter, r *http.Request) {
+	run, err := s.store.GetRun(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrRunNotFound) {
+			s.jsonError(w, "run not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidRunID) {
+			s.jsonError(w, "invalid run id", http.StatusBadRequest)
+			return
+		}
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := lock.Lookup(s.store, run)
+	if err != nil {
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		s.jsonError(w, "no lock info recorded", http.StatusNotFound)
+		return
+	}
+	s.jsonResponse(w, report)
+}
+
+func (s *Server) handleCompareRuns(w http.ResponseWriter, r *http.Request) {
+	res, err := compare.Load(s.store, r.PathValue("id"), r.PathValue("other"), compare.DefaultContext)
+	if err != nil {
+		if errors.Is(err, storage.ErrRunNotFound) {
+			s.jsonError(w, "run not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidRunID) {
+			s.jsonError(w, "invalid run id", http.StatusBadRequest)
+			return
+		}
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, res)
+}
+
 func (s *Server) handleGetOutput(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -173,6 +481,31 @@ func (s *Server) handleGetOutput(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(output)
 }
 
+func (s *Server) handleGetDiff(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		s.jsonError(w, "missing run id", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := s.store.GetDiff(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDiffNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidRunID) {
+			s.jsonError(w, "invalid run id", http.StatusBadRequest)
+			return
+		}
+		s.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	_, _ = w.Write(diff)
+}
+
 func (s *Server) handleGetVersion(w http.ResponseWriter, _ *http.Request) {
 	s.jsonResponse(w, map[string]string{"version": Version})
 }
@@ -183,6 +516,7 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
 
 func (s *Server) handleSync(w http.ResponseWriter, _ *http.Request) {
 	result, err := s.store.Sync()
+	s.metrics.ObserveSync(result, err)
 	if err != nil {
 		s.jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
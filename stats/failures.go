@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type FailureSignature struct {
+	Workspace string    `json:"workspace"`
+	Category  string    `json:"category"`
+	Transient bool      `json:"transient,omitempty"`
+	Signature string    `json:"signature"`
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	Recurring bool      `json:"recurring"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	LastRunID string    `json:"last_run_id"`
+}
+
+type FailureReport struct {
+	Failed       int                 `json:"failed"`
+	Transient    int                 `json:"transient"`
+	Unclassified int                 `json:"unclassified,omitempty"`
+	Categories   map[string]int      `json:"categories"`
+	Signatures   []*FailureSignature `json:"signatures"`
+}
+
+func Failures(runs []*run.Run) *FailureReport {
+	report := &FailureReport{
+		Categories: make(map[string]int),
+		Signatures: []*FailureSignature{},
+	}
+
+	signatures := make(map[string]*FailureSignature)
+	for _, r := range runs {
+		if r.Status != run.StatusFailed {
+			continue
+		}
+		report.Failed++
+		f := r.Failure
+		if f == nil {
+			report.Unclassified++
+			continue
+		}
+		report.Categories[f.Category]++
+		if f.Transient {
+			report.Transient++
+		}
+
+		key := r.Workspace + "\x00" + f.Category + "\x00" + f.Signature
+		s, ok := signatures[key]
+		if !ok {
+			s = &FailureSignature{
+				Workspace: r.Workspace,
+				Category:  f.Category,
+				Transient: f.Transient,
+				Signature: f.Signature,
+				FirstSeen: r.Timestamp,
+			}
+			signatures[key] = s
+			report.Signatures = append(report.Signatures, s)
+		}
+		s.Count++
+		s.Recurring = s.Count > 1
+		if r.Timestamp.Before(s.FirstSeen) {
+			s.FirstSeen = r.Timestamp
+		}
+		if !r.Timestamp.Before(s.LastSeen) {
+			s.LastSeen = r.Timestamp
+			s.LastRunID = r.ID
+			s.Message = f.Message
+		}
+	}
+
+	sort.Slice(report.Signatures, func(i, j int) bool {
+		a, b := report.Signatures[i], report.Signatures[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		return a.Workspace+a.Signature < b.Workspace+b.Signature
+	})
+	return report
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestFailures(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	lock := &run.Failure{Category: run.FailureStateLock, Transient: true, Signature: "Error acquiring the state lock", Message: "Error acquiring the state lock"}
+	config := &run.Failure{Category: run.FailureConfig, Signature: "Unsupported argument", Message: "Unsupported argument"}
+
+	runs := []*run.Run{
+		{ID: "r1", Workspace: "prod", Timestamp: base, Status: run.StatusFailed, Failure: lock},
+		{ID: "r2", Workspace: "prod", Timestamp: base.Add(2 * time.Hour), Status: run.StatusFailed, Failure: lock},
+		{ID: "r3", Workspace: "prod", Timestamp: base.Add(time.Hour), Status: run.StatusFailed, Failure: lock},
+		{ID: "r4", Workspace: "dev", Timestamp: base, Status: run.StatusFailed, Failure: lock},
+		{ID: "r5", Workspace: "prod", Timestamp: base, Status: run.StatusFailed, Failure: config},
+		{ID: "r6", Workspace: "prod", Timestamp: base, Status: run.StatusFailed},
+		{ID: "r7", Workspace: "prod", Timestamp: base, Status: run.StatusSuccess},
+	}
+
+	report := Failures(runs)
+	if report.Failed != 6 || report.Transient != 4 || report.Unclassified != 1 {
+		t.Errorf("Failures() = failed %d, transient %d, unclassified %d", report.Failed, report.Transient, report.Unclassified)
+	}
+	if report.Categories[run.FailureStateLock] != 4 || report.Categories[run.FailureConfig] != 1 {
+		t.Errorf("Categories = %v", report.Categories)
+	}
+	if len(report.Signatures) != 3 {
+		t.Fatalf("len(Signatures) = %d, want 3", len(report.Signatures))
+	}
+
+	top := report.Signatures[0]
+	if top.Workspace != "prod" || top.Count != 3 || !top.Recurring || top.LastRunID != "r2" {
+		t.Errorf("Signatures[0] = %+v", top)
+	}
+	if !top.FirstSeen.Equal(base) || !top.LastSeen.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Signatures[0] seen %v..%v", top.FirstSeen, top.LastSeen)
+	}
+	for _, s := range report.Signatures[1:] {
+		if s.Recurring {
+			t.Errorf("%s/%s marked recurring with count %d", s.Workspace, s.Category, s.Count)
+		}
+	}
+}
+
+func TestCompute_ByFailure(t *testing.T) {
+	runs := []*run.Run{
+		{Status: run.StatusFailed, Failure: &run.Failure{Category: run.FailureAuth}},
+		{Status: run.StatusFailed, Failure: &run.Failure{Category: run.FailureAuth}},
+		{Status: run.StatusSuccess},
+	}
+
+	report := Compute(runs, []Dimension{ByFailure})
+	if len(report.Groups) != 2 || report.Groups[0].Key["failure"] != run.FailureAuth || report.Groups[0].Count != 2 {
+		t.Errorf("Compute(by failure) groups = %+v", report.Groups)
+	}
+}
@@ -0,0 +1,165 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	ByType    = "type"
+	ByAddress = "address"
+
+	DefaultThreshold = 0.5
+	DefaultRecent    = 3
+
+	_minBaseline     = 3
+	_minRegressionMs = 1000
+)
+
+var regressionActions = []string{"create", "update"}
+
+type TrendOptions struct {
+	By        string
+	Action    string
+	Threshold float64
+	Recent    int
+}
+
+type TrendPoint struct {
+	RunID      string    `json:"run_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type ResourceTrend struct {
+	Key        string       `json:"key"`
+	Action     string       `json:"action"`
+	Samples    int          `json:"samples"`
+	P50Ms      int64        `json:"p50_ms"`
+	P95Ms      int64        `json:"p95_ms"`
+	MaxMs      int64        `json:"max_ms"`
+	BaselineMs int64        `json:"baseline_ms,omitempty"`
+	RecentMs   int64        `json:"recent_ms,omitempty"`
+	Change     float64      `json:"change,omitempty"`
+	Regressed  bool         `json:"regressed"`
+	Points     []TrendPoint `json:"points"`
+}
+
+type TrendReport struct {
+	By        string           `json:"by"`
+	Threshold float64          `json:"threshold"`
+	Recent    int              `json:"recent"`
+	Regressed int              `json:"regressed"`
+	Trends    []*ResourceTrend `json:"trends"`
+}
+
+func ValidateTrendBy(by string) error {
+	if by != ByType && by != ByAddress {
+		return fmt.Errorf("invalid group %q: must be %s or %s", by, ByType, ByAddress)
+	}
+	return nil
+}
+
+func ResourceTrends(runs []*run.Run, opts TrendOptions) *TrendReport {
+	if opts.By == "" {
+		opts.By = ByType
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.Recent <= 0 {
+		opts.Recent = DefaultRecent
+	}
+
+	report := &TrendReport{
+		By:        opts.By,
+		Threshold: opts.Threshold,
+		Recent:    opts.Recent,
+		Trends:    []*ResourceTrend{},
+	}
+
+	trends := make(map[string]*ResourceTrend)
+	for _, r := range runs {
+		for _, res := range r.Resources {
+			if res.DurationMs <= 0 || res.Status == "failed" || (opts.Action != "" && res.Action != opts.Action) {
+				continue
+			}
+			key := res.Address
+			if opts.By == ByType {
+				key = res.Type()
+			}
+			id := key + "\x00" + res.Action
+			t, ok := trends[id]
+			if !ok {
+				t = &ResourceTrend{Key: key, Action: res.Action}
+				trends[id] = t
+				report.Trends = append(report.Trends, t)
+			}
+			t.Points = append(t.Points, TrendPoint{RunID: r.ID, Timestamp: r.Timestamp, DurationMs: res.DurationMs})
+		}
+	}
+
+	for _, t := range report.Trends {
+		t.finish(opts)
+		if t.Regressed {
+			report.Regressed++
+		}
+	}
+
+	sort.Slice(report.Trends, func(i, j int) bool {
+		a, b := report.Trends[i], report.Trends[j]
+		if a.Regressed != b.Regressed {
+			return a.Regressed
+		}
+		if a.Regressed && a.Change != b.Change {
+			return a.Change > b.Change
+		}
+		if a.P95Ms != b.P95Ms {
+			return a.P95Ms > b.P95Ms
+		}
+		return a.Key+a.Action < b.Key+b.Action
+	})
+	return report
+}
+
+func (t *ResourceTrend) finish(opts TrendOptions) {
+	sort.SliceStable(t.Points, func(i, j int) bool {
+		return t.Points[i].Timestamp.Before(t.Points[j].Timestamp)
+	})
+
+	durations := make([]int64, len(t.Points))
+	for i, p := range t.Points {
+		durations[i] = p.DurationMs
+	}
+	t.Samples = len(durations)
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	t.P50Ms = Percentile(sorted, 50)
+	t.P95Ms = Percentile(sorted, 95)
+	t.MaxMs = sorted[len(sorted)-1]
+
+	split := len(durations) - opts.Recent
+	if split < _minBaseline {
+		return
+	}
+	t.BaselineMs = median(durations[:split])
+	t.RecentMs = median(durations[split:])
+	if t.BaselineMs > 0 {
+		t.Change = math.Round(float64(t.RecentMs-t.BaselineMs)/float64(t.BaselineMs)*1000) / 1000
+	}
+	t.Regressed = slices.Contains(regressionActions, t.Action) &&
+		t.Change > opts.Threshold &&
+		t.RecentMs-t.BaselineMs >= _minRegressionMs
+}
+
+func median(durations []int64) int64 {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return Percentile(sorted, 50)
+}
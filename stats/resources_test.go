@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestResourceTrends(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)
+	var runs []*run.Run
+	for i, ms := range []int64{10000, 11000, 9000, 10000, 25000, 30000, 28000} {
+		runs = append(runs, &run.Run{
+			ID:        run.GenerateID(ts.AddDate(0, 0, i)),
+			Timestamp: ts.AddDate(0, 0, i),
+			Resources: []run.Resource{
+				{Address: "aws_db_instance.main", Action: "update", DurationMs: ms, Status: "success"},
+				{Address: "aws_instance.web", Action: "create", DurationMs: 40000, Status: "success"},
+				{Address: "aws_instance.api", Action: "create", DurationMs: 20000 + int64(i)*100, Status: "success"},
+				{Address: "aws_s3_bucket.logs", Action: "destroy", DurationMs: 500, Status: "failed"},
+			},
+		})
+	}
+
+	t.Run("by type", func(t *testing.T) {
+		report := ResourceTrends(runs, TrendOptions{})
+		if report.By != ByType || report.Threshold != DefaultThreshold || report.Recent != DefaultRecent {
+			t.Errorf("defaults = %s %v %d", report.By, report.Threshold, report.Recent)
+		}
+		if len(report.Trends) != 2 || report.Regressed != 1 {
+			t.Fatalf("got %d trends with %d regressed, want 2 with 1", len(report.Trends), report.Regressed)
+		}
+
+		db := report.Trends[0]
+		if db.Key != "aws_db_instance" || db.Action != "update" || !db.Regressed {
+			t.Errorf("Trends[0] = %+v", db)
+		}
+		if db.BaselineMs != 10000 || db.RecentMs != 28000 || db.Change != 1.8 || db.Samples != 7 {
+			t.Errorf("db baseline = %d recent = %d change = %v samples = %d", db.BaselineMs, db.RecentMs, db.Change, db.Samples)
+		}
+		if !db.Points[0].Timestamp.Equal(ts) {
+			t.Errorf("points are not chronological: %v", db.Points[0].Timestamp)
+		}
+
+		instances := report.Trends[1]
+		if instances.Key != "aws_instance" || instances.Samples != 14 || instances.Regressed || instances.MaxMs != 40000 {
+			t.Errorf("Trends[1] = %+v", instances)
+		}
+	})
+
+	t.Run("by address with action filter", func(t *testing.T) {
+		report := ResourceTrends(runs, TrendOptions{By: ByAddress, Action: "create"})
+		if len(report.Trends) != 2 || report.Regressed != 0 {
+			t.Fatalf("got %d trends with %d regressed, want 2 with 0", len(report.Trends), report.Regressed)
+		}
+		if report.Trends[0].Key != "aws_instance.web" || report.Trends[1].Key != "aws_instance.api" {
+			t.Errorf("trends not ordered by p95: %s, %s", report.Trends[0].Key, report.Trends[1].Key)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		if report := ResourceTrends(runs, TrendOptions{Threshold: 2}); report.Regressed != 0 {
+			t.Errorf("Regressed = %d with a 200%% threshold, want 0", report.Regressed)
+		}
+	})
+
+	t.Run("too few samples", func(t *testing.T) {
+		report := ResourceTrends(runs[:4], TrendOptions{By: ByAddress})
+		for _, tr := range report.Trends {
+			if tr.Regressed || tr.BaselineMs != 0 {
+				t.Errorf("%s flagged with only %d samples", tr.Key, tr.Samples)
+			}
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+type Dimension string
+
+const (
+	ByWorkspace   Dimension = "workspace"
+	ByEnvironment Dimension = "environment"
+	ByComponent   Dimension = "component"
+	ByUser        Dimension = "user"
+	ByProgram     Dimension = "program"
+	ByAction      Dimension = "action"
+	ByBranch      Dimension = "branch"
+	ByVersion     Dimension = "version"
+	ByState       Dimension = "state"
+	ByAccount     Dimension = "account"
+	ByFailure     Dimension = "failure"
+	ByDay         Dimension = "day"
+	ByWeek        Dimension = "week"
+	ByMonth       Dimension = "month"
+)
+
+const providerPrefix = "provider:"
+
+var dimensions = []Dimension{ByWorkspace, ByEnvironment, ByComponent, ByUser, ByProgram, ByAction, ByBranch, ByVersion, ByState, ByAccount, ByFailure, ByDay, ByWeek, ByMonth}
+
+type Group struct {
+	Key           map[string]string `json:"key,omitempty"`
+	Count         int               `json:"count"`
+	Success       int               `json:"success"`
+	Failed        int               `json:"failed"`
+	Running       int               `json:"running"`
+	SuccessRate   float64           `json:"success_rate"`
+	DurationP50Ms int64             `json:"duration_p50_ms"`
+	DurationP90Ms int64             `json:"duration_p90_ms"`
+	DurationP95Ms int64             `json:"duration_p95_ms"`
+	DurationMaxMs int64             `json:"duration_max_ms"`
+	Add           int               `json:"add"`
+	Change        int               `json:"change"`
+	Destroy       int               `json:"destroy"`
+
+	sortKey   string
+	durations []int64
+}
+
+type Report struct {
+	GroupBy []Dimension `json:"group_by"`
+	Total   *Group      `json:"total"`
+	Groups  []*Group    `json:"groups"`
+}
+
+func ParseDimensions(s string) ([]Dimension, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var dims []Dimension
+	for part := range strings.SplitSeq(s, ",") {
+		d := Dimension(strings.TrimSpace(part))
+		if !slices.Contains(dimensions, d) && (!strings.HasPrefix(string(d), providerPrefix) || d == providerPrefix) {
+			return nil, fmt.Errorf("invalid group %q: must be one of %s, %s<name>", d, joinDimensions(dimensions), providerPrefix)
+		}
+		if !slices.Contains(dims, d) {
+			dims = append(dims, d)
+		}
+	}
+	return dims, nil
+}
+
+func Compute(runs []*run.Run, groupBy []Dimension) *Report {
+	report := &Report{
+		GroupBy: groupBy,
+		Total:   &Group{},
+		Groups:  []*Group{},
+	}
+
+	groups := make(map[string]*Group)
+	for _, r := range runs {
+		report.Total.add(r)
+		if len(groupBy) == 0 {
+			continue
+		}
+
+		key, values := groupKey(r, groupBy)
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{Key: values, sortKey: key}
+			groups[key] = g
+			report.Groups = append(report.Groups, g)
+		}
+		g.add(r)
+	}
+
+	report.Total.finish()
+	for _, g := range report.Groups {
+		g.finish()
+	}
+
+	if len(groupBy) > 0 && isTimeBucket(groupBy[0]) {
+		sort.Slice(report.Groups, func(i, j int) bool {
+			return report.Groups[i].sortKey < report.Groups[j].sortKey
+		})
+	} else {
+		sort.Slice(report.Groups, func(i, j int) bool {
+			if report.Groups[i].Count != report.Groups[j].Count {
+				return report.Groups[i].Count > report.Groups[j].Count
+			}
+			return report.Groups[i].sortKey < report.Groups[j].sortKey
+		})
+	}
+
+	return report
+}
+
+func (g *Group) add(r *run.Run) {
+	g.Count++
+	switch {
+	case r.Status == run.StatusRunning:
+		g.Running++
+		return
+	case r.Status.Succeeded():
+		g.Success++
+	case r.Status == run.StatusFailed:
+		g.Failed++
+	}
+	g.durations = append(g.durations, r.DurationMs)
+	if r.Changes != nil {
+		g.Add += r.Changes.Add
+		g.Change += r.Changes.Change
+		g.Destroy += r.Changes.Destroy
+	}
+}
+
+func (g *Group) finish() {
+	if finished := g.Count - g.Running; finished > 0 {
+		g.SuccessRate = math.Round(float64(g.Success)/float64(finished)*1000) / 1000
+	}
+
+	slices.Sort(g.durations)
+	g.DurationP50Ms = Percentile(g.durations, 50)
+	g.DurationP90Ms = Percentile(g.durations, 90)
+	g.DurationP95Ms = Percentile(g.durations, 95)
+	if len(g.durations) > 0 {
+		g.DurationMaxMs = g.durations[len(g.durations)-1]
+	}
+	g.durations = nil
+}
+
+func Percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = max(1, min(rank, len(sorted)))
+	return sorted[rank-1]
+}
+
+func groupKey(r *run.Run, groupBy []Dimension) (string, map[string]string) {
+	values := make(map[string]string, len(groupBy))
+	parts := make([]string, len(groupBy))
+	for i, d := range groupBy {
+		v := dimensionValue(r, d)
+		values[string(d)] = v
+		parts[i] = v
+	}
+	return strings.Join(parts, "\x00"), values
+}
+
+func dimensionValue(r *run.Run, d Dimension) string {
+	switch d {
+	case ByWorkspace:
+		return r.Workspace
+	case ByEnvironment:
+		return r.Environment
+	case ByComponent:
+		return r.Component
+	case ByUser:
+		return r.User
+	case ByProgram:
+		return r.Program
+	case ByAction:
+		return r.Action()
+	case ByBranch:
+		if r.Git != nil {
+			return r.Git.Branch
+		}
+		return ""
+	case ByVersion:
+		if r.Versions != nil {
+			return r.Versions.Version
+		}
+		return ""
+	case ByState:
+		if r.State != nil {
+			return r.State.ID
+		}
+		return ""
+	case ByAccount:
+		if r.Cloud != nil {
+			return r.Cloud.Account()
+		}
+		return ""
+	case ByFailure:
+		if r.Failure != nil {
+			return r.Failure.Category
+		}
+		return ""
+	case ByDay:
+		return r.Timestamp.Format("2006-01-02")
+	case ByWeek:
+		year, week := r.Timestamp.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case ByMonth:
+		return r.Timestamp.Format("2006-01")
+	default:
+		if name, ok := strings.CutPrefix(string(d), providerPrefix); ok && r.Versions != nil {
+			if p := r.Versions.Provider(name); p != nil {
+				return p.Version
+			}
+		}
+		return ""
+	}
+}
+
+func isTimeBucket(d Dimension) bool {
+	return d == ByDay || d == ByWeek || d == ByMonth
+}
+
+func joinDimensions(dims []Dimension) string {
+	parts := make([]string, len(dims))
+	for i, d := range dims {
+		parts[i] = string(d)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+func TestParseDimensions(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"workspace", 1, false},
+		{"workspace,month", 2, false},
+		{"workspace, workspace", 1, false},
+		{"color", 0, true},
+		{"version,provider:aws", 2, false},
+		{"provider:", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDimensions(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDimensions(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("ParseDimensions(%q) = %v, want %d dimensions", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+
+	tests := []struct {
+		p    float64
+		want int64
+	}{
+		{50, 50},
+		{90, 90},
+		{95, 100},
+		{100, 100},
+		{0, 10},
+	}
+
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil) = %d, want 0", got)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	ts := time.Date(2025, 1, 26, 14, 0, 0, 0, time.UTC)
+	runs := []*run.Run{
+		{Workspace: "prod", Timestamp: ts, Status: run.StatusSuccess, DurationMs: 1000, Command: []string{"terraform", "apply"}, Changes: &run.Changes{Add: 2}},
+		{Workspace: "prod", Timestamp: ts, Status: run.StatusFailed, DurationMs: 3000, Command: []string{"terraform", "apply"}},
+		{Workspace: "prod", Timestamp: ts, Status: run.StatusSuccess, DurationMs: 500, Command: []string{"terraform", "plan"}, Changes: &run.Changes{Destroy: 1}},
+		{Workspace: "dev", Timestamp: ts.AddDate(0, -1, 0), Status: run.StatusSuccess, DurationMs: 2000, Command: []string{"terraform", "apply"}},
+	}
+
+	t.Run("total", func(t *testing.T) {
+		report := Compute(runs, nil)
+		if report.Total.Count != 4 {
+			t.Errorf("Count = %d, want 4", report.Total.Count)
+		}
+		if report.Total.SuccessRate != 0.75 {
+			t.Errorf("SuccessRate = %v, want 0.75", report.Total.SuccessRate)
+		}
+		if report.Total.DurationMaxMs != 3000 {
+			t.Errorf("DurationMaxMs = %d, want 3000", report.Total.DurationMaxMs)
+		}
+		if report.Total.Add != 2 || report.Total.Destroy != 1 {
+			t.Errorf("changes = +%d -%d, want +2 -1", report.Total.Add, report.Total.Destroy)
+		}
+		if len(report.Groups) != 0 {
+			t.Errorf("got %d groups, want 0", len(report.Groups))
+		}
+	})
+
+	t.Run("running runs are reported separately", func(t *testing.T) {
+		withRunning := append(slices.Clone(runs), &run.Run{Workspace: "prod", Timestamp: ts, Status: run.StatusRunning, Command: []string{"terraform", "apply"}})
+		report := Compute(withRunning, nil)
+		if report.Total.Count != 5 || report.Total.Running != 1 {
+			t.Errorf("Count = %d Running = %d, want 5 and 1", report.Total.Count, report.Total.Running)
+		}
+		if report.Total.SuccessRate != 0.75 {
+			t.Errorf("SuccessRate = %v, want 0.75", report.Total.SuccessRate)
+		}
+	})
+
+	t.Run("by workspace and action", func(t *testing.T) {
+		report := Compute(runs, []Dimension{ByWorkspace, ByAction})
+		if len(report.Groups) != 3 {
+			t.Fatalf("got %d groups, want 3", len(report.Groups))
+		}
+		first := report.Groups[0]
+		if first.Key["workspace"] != "prod" || first.Key["action"] != "apply" {
+			t.Errorf("first group = %v, want prod/apply", first.Key)
+		}
+		if first.Count != 2 || first.Failed != 1 {
+			t.Errorf("first group count = %d failed = %d, want 2 and 1", first.Count, first.Failed)
+		}
+		if first.DurationP50Ms != 1000 || first.DurationP95Ms != 3000 {
+			t.Errorf("first group p50 = %d p95 = %d, want 1000 and 3000", first.DurationP50Ms, first.DurationP95Ms)
+		}
+	})
+
+	t.Run("by month is chronological", func(t *testing.T) {
+		report := Compute(runs, []Dimension{ByMonth})
+		if len(report.Groups) != 2 {
+			t.Fatalf("got %d groups, want 2", len(report.Groups))
+		}
+		if report.Groups[0].Key["month"] != "2024-12" || report.Groups[1].Key["month"] != "2025-01" {
+			t.Errorf("months = %s, %s, want 2024-12, 2025-01", report.Groups[0].Key["month"], report.Groups[1].Key["month"])
+		}
+	})
+
+	t.Run("by provider version", func(t *testing.T) {
+		versioned := []*run.Run{
+			{Status: run.StatusSuccess, Versions: &run.Versions{Providers: []run.ProviderVersion{{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0"}}}},
+			{Status: run.StatusFailed, Versions: &run.Versions{Providers: []run.ProviderVersion{{Source: "registry.terraform.io/hashicorp/aws", Version: "5.32.1"}}}},
+			{Status: run.StatusSuccess, Versions: &run.Versions{Providers: []run.ProviderVersion{{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0"}}}},
+			{Status: run.StatusSuccess},
+		}
+		report := Compute(versioned, []Dimension{"provider:aws"})
+		if len(report.Groups) != 3 {
+			t.Fatalf("got %d groups, want 3", len(report.Groups))
+		}
+		if v := report.Groups[0].Key["provider:aws"]; v != "5.31.0" || report.Groups[0].Count != 2 {
+			t.Errorf("first group = %q with %d runs, want 5.31.0 with 2", v, report.Groups[0].Count)
+		}
+	})
+}
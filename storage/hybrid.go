@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -16,17 +17,20 @@ const (
 )
 
 type HybridStore struct {
-	local *LocalStore
-	s3    *S3Store
-	wg    sync.WaitGroup
-	sem   chan struct{}
+	local   *LocalStore
+	s3      *S3Store
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	mu      sync.Mutex
+	uploads map[string]chan struct{}
 }
 
 func NewHybridStore(local *LocalStore, s3 *S3Store) *HybridStore {
 	return &HybridStore{
-		local: local,
-		s3:    s3,
-		sem:   make(chan struct{}, _maxConcurrent),
+		local:   local,
+		s3:      s3,
+		sem:     make(chan struct{}, _maxConcurrent),
+		uploads: make(map[string]chan struct{}),
 	}
 }
 
@@ -47,9 +51,16 @@ func (h *HybridStore) Close() error {
 }
 
 func (h *HybridStore) goBackground(fn func()) {
+	h.goAfter(nil, fn)
+}
+
+func (h *HybridStore) goAfter(prev <-chan struct{}, fn func()) {
 	h.wg.Add(1)
 	go func() {
 		defer h.wg.Done()
+		if prev != nil {
+			<-prev
+		}
 		h.sem <- struct{}{}
 		defer func() { <-h.sem }()
 		fn()
@@ -61,8 +72,31 @@ func (h *HybridStore) SaveRun(r *run.Run) error {
 		return err
 	}
 
-	h.goBackground(func() {
-		if err := h.s3.SaveRun(r); err != nil {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	snapshot := &run.Run{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return fmt.Errorf("failed to copy run: %w", err)
+	}
+
+	h.mu.Lock()
+	prev := h.uploads[r.ID]
+	done := make(chan struct{})
+	h.uploads[r.ID] = done
+	h.mu.Unlock()
+
+	h.goAfter(prev, func() {
+		defer func() {
+			h.mu.Lock()
+			if h.uploads[r.ID] == done {
+				delete(h.uploads, r.ID)
+			}
+			h.mu.Unlock()
+			close(done)
+		}()
+		if err := h.s3.SaveRun(snapshot); err != nil {
 			fmt.Fprintf(os.Stderr, "tfjournal: failed to sync run to S3: %v\n", err)
 		}
 	})
@@ -182,6 +216,27 @@ func (h *HybridStore) OutputPath(runID string) string {
 	return h.local.OutputPath(runID)
 }
 
+func (h *HybridStore) SaveDiff(runID string, diff []byte) error {
+	if err := h.local.SaveDiff(runID, diff); err != nil {
+		return err
+	}
+
+	h.goBackground(func() {
+		if err := h.s3.SaveDiff(runID, diff); err != nil {
+			fmt.Fprintf(os.Stderr, "tfjournal: failed to sync diff to S3: %v\n", err)
+		}
+	})
+	return nil
+}
+
+func (h *HybridStore) GetDiff(runID string) ([]byte, error) {
+	diff, err := h.local.GetDiff(runID)
+	if err == nil {
+		return diff, nil
+	}
+	return h.s3.GetDiff(runID)
+}
+
 func (h *HybridStore) DeleteRun(id string) error {
 	localErr := h.local.DeleteRun(id)
 	s3Err := h.s3.DeleteRun(id)
@@ -192,6 +247,40 @@ func (h *HybridStore) DeleteRun(id string) error {
 	return s3Err
 }
 
+func (h *HybridStore) ResourceHistory(address string, opts ListOptions) ([]ResourceEvent, error) {
+	filter := opts
+	filter.Limit = 0
+	events, err := h.local.ResourceHistory(address, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	events = append(events, h.s3.resourceHistory(address, opts, h.local.HasRun)...)
+	return limitEvents(events, opts.Limit), nil
+}
+
+func (h *HybridStore) Search(query string, opts ListOptions) ([]SearchResult, error) {
+	filter := opts
+	filter.Limit = 0
+	results, err := h.local.Search(query, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s3Runs, err := h.s3.ListRuns(filter)
+	if err != nil {
+		return limitResults(results, opts.Limit), nil
+	}
+	var remote []*run.Run
+	for _, r := range s3Runs {
+		if !h.local.HasRun(r.ID) {
+			remote = append(remote, r)
+		}
+	}
+	results = append(results, h.s3.searchRuns(remote, Tokenize(query), opts.Limit)...)
+	return limitResults(results, opts.Limit), nil
+}
+
 func (h *HybridStore) IsLocal(id string) bool {
 	return h.local.HasRun(id)
 }
@@ -211,6 +300,10 @@ func (h *HybridStore) ListS3Runs(opts ListOptions) ([]*run.Run, error) {
 	return h.s3.ListRuns(opts)
 }
 
+func (h *HybridStore) SaveS3Run(r *run.Run) error {
+	return h.s3.SaveRun(r)
+}
+
 func (h *HybridStore) ListS3RunIDs() (map[string]bool, error) {
 	return h.s3.ListRunIDs()
 }
@@ -228,6 +321,10 @@ func (h *HybridStore) UploadRun(id string) error {
 	if err == nil && len(output) > 0 {
 		_ = h.s3.SaveOutput(id, output)
 	}
+
+	if diff, err := h.local.GetDiff(id); err == nil {
+		_ = h.s3.SaveDiff(id, diff)
+	}
 	return nil
 }
 
@@ -244,6 +341,12 @@ func (h *HybridStore) DownloadRun(id string) error {
 	if err == nil && len(output) > 0 {
 		_ = h.local.SaveOutput(id, output)
 	}
+
+	if r.Git != nil && r.Git.DiffBytes > 0 {
+		if diff, err := h.s3.GetDiff(id); err == nil {
+			_ = h.local.SaveDiff(id, diff)
+		}
+	}
 	return nil
 }
 
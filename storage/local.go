@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -35,11 +36,18 @@ func (s *LocalStore) SaveRun(r *run.Run) error {
 		return fmt.Errorf("failed to marshal run: %w", err)
 	}
 
+	previous, _ := s.GetRun(r.ID)
 	path := s.runPath(r.ID)
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
-	return os.WriteFile(path, data, 0o644)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if err := s.indexResources(r, previous); err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: failed to update resource index: %v\n", err)
+	}
+	return nil
 }
 
 func (s *LocalStore) GetRun(id string) (*run.Run, error) {
@@ -104,7 +112,18 @@ func (s *LocalStore) SaveOutput(runID string, output []byte) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
-	return os.WriteFile(path, output, 0o644)
+	previous, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(previous, output) {
+		return nil
+	}
+	replaced := err == nil
+	if err := os.WriteFile(path, output, 0o644); err != nil {
+		return err
+	}
+	if err := s.indexOutput(runID, output, replaced); err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: failed to update output index: %v\n", err)
+	}
+	return nil
 }
 
 func (s *LocalStore) GetOutput(runID string) ([]byte, error) {
@@ -122,16 +141,41 @@ func (s *LocalStore) OutputPath(runID string) string {
 	return s.outputPath(runID)
 }
 
-func (s *LocalStore) DeleteRun(id string) error {
-	runPath := s.runPath(id)
-	outputPath := s.outputPath(id)
+func (s *LocalStore) SaveDiff(runID string, diff []byte) error {
+	path := s.diffPath(runID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, diff, 0o644)
+}
 
-	if err := os.Remove(runPath); err != nil && !os.IsNotExist(err) {
-		return err
+func (s *LocalStore) GetDiff(runID string) ([]byte, error) {
+	data, err := os.ReadFile(s.diffPath(runID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrDiffNotFound
+		}
+		return nil, err
 	}
-	if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+	return data, nil
+}
+
+func (s *LocalStore) DeleteRun(id string) error {
+	if err := s.deleteFiles(id); err != nil {
 		return err
 	}
+	if err := s.dropFromIndexes(map[string]bool{id: true}); err != nil {
+		fmt.Fprintf(os.Stderr, "tfjournal: %v\n", err)
+	}
+	return nil
+}
+
+func (s *LocalStore) deleteFiles(id string) error {
+	for _, path := range []string{s.runPath(id), s.outputPath(id), s.diffPath(id)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -151,6 +195,14 @@ func (s *LocalStore) outputPath(id string) string {
 	return filepath.Join(s.baseDir, _outputsDir, date.Format("2006/01/02"), id+".txt")
 }
 
+func (s *LocalStore) diffPath(id string) string {
+	date, err := run.ParseDateFromID(id)
+	if err != nil {
+		return filepath.Join(s.baseDir, _diffsDir, id+".patch")
+	}
+	return filepath.Join(s.baseDir, _diffsDir, date.Format("2006/01/02"), id+".patch")
+}
+
 func (s *LocalStore) HasRun(id string) bool {
 	_, err := os.Stat(s.runPath(id))
 	return err == nil
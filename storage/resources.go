@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_indexDir      = "index"
+	_resourceIndex = "resources.jsonl"
+)
+
+type ResourceEvent struct {
+	RunID      string     `json:"run_id"`
+	Timestamp  time.Time  `json:"timestamp"`
+	Workspace  string     `json:"workspace"`
+	Address    string     `json:"address"`
+	Action     string     `json:"action"`
+	Planned    bool       `json:"planned,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+	RunAction  string     `json:"run_action,omitempty"`
+	RunStatus  run.Status `json:"run_status"`
+	User       string     `json:"user"`
+}
+
+type indexEntry struct {
+	Address string `json:"address"`
+	RunID   string `json:"run_id"`
+}
+
+func (s *LocalStore) ResourceHistory(address string, opts ListOptions) ([]ResourceEvent, error) {
+	if _, err := os.Stat(s.resourceIndexPath()); os.IsNotExist(err) {
+		if err := s.ReindexResources(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(s.resourceIndexPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resource index: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	seen := make(map[string]bool)
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e indexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !MatchAddress(address, e.Address) || seen[e.RunID] {
+			continue
+		}
+		seen[e.RunID] = true
+		ids = append(ids, e.RunID)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resource index: %w", err)
+	}
+
+	var runs []*run.Run
+	for _, id := range ids {
+		r, err := s.GetRun(id)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, r)
+	}
+	return resourceHistory(runs, address, opts), nil
+}
+
+func (s *LocalStore) ReindexResources() error {
+	runs, err := s.ListRuns(ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	path := s.resourceIndexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), _resourceIndex+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create resource index: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	for _, r := range runs {
+		if err := writeIndexEntries(w, r); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write resource index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write resource index: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) indexResources(r, previous *run.Run) error {
+	if len(r.Resources) == 0 && len(r.Planned) == 0 {
+		return nil
+	}
+	if previous != nil && slices.Equal(touchedAddresses(r), touchedAddresses(previous)) {
+		return nil
+	}
+	path := s.resourceIndexPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return s.ReindexResources()
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open resource index: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := writeIndexEntries(w, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write resource index: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *LocalStore) dropFromIndexes(ids map[string]bool) error {
+	if err := dropFromIndex(s.resourceIndexPath(), ids); err != nil {
+		return fmt.Errorf("failed to update resource index: %w", err)
+	}
+	if err := dropFromIndex(s.outputIndexPath(), ids); err != nil {
+		return fmt.Errorf("failed to update output index: %w", err)
+	}
+	return nil
+}
+
+func dropFromIndex(path string, ids map[string]bool) error {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	reader := bufio.NewReader(in)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		var e struct {
+			RunID string `json:"run_id"`
+		}
+		if len(line) > 0 && json.Unmarshal(line, &e) == nil && !ids[e.RunID] {
+			_, _ = w.Write(line)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			_ = tmp.Close()
+			return readErr
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) resourceIndexPath() string {
+	return filepath.Join(s.baseDir, _indexDir, _resourceIndex)
+}
+
+func writeIndexEntries(w *bufio.Writer, r *run.Run) error {
+	for _, address := range touchedAddresses(r) {
+		data, err := json.Marshal(indexEntry{Address: address, RunID: r.ID})
+		if err != nil {
+			return fmt.Errorf("failed to marshal index entry: %w", err)
+		}
+		_, _ = w.Write(data)
+		_ = w.WriteByte('\n')
+	}
+	return nil
+}
+
+func touchedAddresses(r *run.Run) []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, res := range r.Resources {
+		if !seen[res.Address] {
+			seen[res.Address] = true
+			addresses = append(addresses, res.Address)
+		}
+	}
+	for _, p := range r.Planned {
+		if !seen[p.Address] {
+			seen[p.Address] = true
+			addresses = append(addresses, p.Address)
+		}
+	}
+	return addresses
+}
+
+func resourceHistory(runs []*run.Run, address string, opts ListOptions) []ResourceEvent {
+	filter := opts
+	filter.Limit = 0
+
+	var events []ResourceEvent
+	for _, r := range runs {
+		if !matchesFilter(r, filter) {
+			continue
+		}
+		events = append(events, resourceEvents(r, address)...)
+	}
+	return limitEvents(events, opts.Limit)
+}
+
+func limitEvents(events []ResourceEvent, limit int) []ResourceEvent {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+func resourceEvents(r *run.Run, address string) []ResourceEvent {
+	var events []ResourceEvent
+	applied := make(map[string]bool)
+	for _, res := range r.Resources {
+		if !MatchAddress(address, res.Address) {
+			continue
+		}
+		applied[res.Address] = true
+		events = append(events, newResourceEvent(r, res.Address, res.Action, false, res.Status, res.DurationMs))
+	}
+	for _, p := range r.Planned {
+		if !MatchAddress(address, p.Address) || applied[p.Address] {
+			continue
+		}
+		events = append(events, newResourceEvent(r, p.Address, p.Action, true, "", 0))
+	}
+	return events
+}
+
+func newResourceEvent(r *run.Run, address, action string, planned bool, status string, durationMs int64) ResourceEvent {
+	return ResourceEvent{
+		RunID:      r.ID,
+		Timestamp:  r.Timestamp,
+		Workspace:  r.Workspace,
+		Address:    address,
+		Action:     action,
+		Planned:    planned,
+		Status:     status,
+		DurationMs: durationMs,
+		RunAction:  r.Action(),
+		RunStatus:  r.Status,
+		User:       r.User,
+	}
+}
+
+func MatchAddress(pattern, address string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == address
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(address, parts[0]) {
+		return false
+	}
+	address = address[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(address, part)
+		if i < 0 {
+			return false
+		}
+		address = address[i+len(part):]
+	}
+	return strings.HasSuffix(address, parts[last])
+}
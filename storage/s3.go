@@ -29,6 +29,8 @@ const (
 	_defaultMaxConcurrent = 20
 	_defaultMaxIdleConns  = 25
 	_defaultSinceDays     = 30
+	_maxSearchRuns        = 500
+	_searchTimeout        = 2 * time.Minute
 )
 
 type S3Store struct {
@@ -140,14 +142,7 @@ func (s *S3Store) ListRuns(opts ListOptions) ([]*run.Run, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout*5)
 	defer cancel()
 
-	endDate := truncateToDay(time.Now())
-	startDate := opts.Since
-	if startDate.IsZero() {
-		startDate = endDate.AddDate(0, 0, -_defaultSinceDays)
-	}
-	startDate = truncateToDay(startDate)
-
-	prefixes := s.generateDatePrefixes(startDate, endDate)
+	prefixes := s.datePrefixes(opts)
 
 	var allRuns []*run.Run
 	var mu sync.Mutex
@@ -200,6 +195,45 @@ func (s *S3Store) ListRuns(opts ListOptions) ([]*run.Run, error) {
 	return allRuns, nil
 }
 
+func (s *S3Store) walkDays(opts ListOptions, fn func(runs []*run.Run) bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout*5)
+	defer cancel()
+
+	prefixes := s.datePrefixes(opts)
+	maxParallelDays := getEnvInt("TFJOURNAL_S3_PARALLEL_DAYS", 7)
+	for start := 0; start < len(prefixes); start += maxParallelDays {
+		group := prefixes[start:min(start+maxParallelDays, len(prefixes))]
+		days := make([][]*run.Run, len(group))
+		var wg sync.WaitGroup
+		for i, prefix := range group {
+			wg.Go(func() {
+				days[i], _ = s.listAndFetchPrefix(ctx, prefix, opts)
+			})
+		}
+		wg.Wait()
+
+		var runs []*run.Run
+		for _, day := range days {
+			runs = append(runs, day...)
+		}
+		sort.Slice(runs, func(i, j int) bool {
+			return runs[i].Timestamp.After(runs[j].Timestamp)
+		})
+		if !fn(runs) || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (s *S3Store) datePrefixes(opts ListOptions) []string {
+	endDate := truncateToDay(time.Now())
+	startDate := opts.Since
+	if startDate.IsZero() {
+		startDate = endDate.AddDate(0, 0, -_defaultSinceDays)
+	}
+	return s.generateDatePrefixes(truncateToDay(startDate), endDate)
+}
+
 func (s *S3Store) generateDatePrefixes(since, until time.Time) []string {
 	var prefixes []string
 	for d := until; !d.Before(since); d = d.AddDate(0, 0, -1) {
@@ -286,7 +320,11 @@ func (s *S3Store) SaveOutput(runID string, output []byte) error {
 }
 
 func (s *S3Store) GetOutput(runID string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout)
+	return s.getOutput(context.Background(), runID)
+}
+
+func (s *S3Store) getOutput(ctx context.Context, runID string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, _s3Timeout)
 	defer cancel()
 
 	key := s.outputKey(runID)
@@ -307,23 +345,122 @@ func (s *S3Store) OutputPath(runID string) string {
 	return fmt.Sprintf("s3://%s/%s", s.bucket, s.outputKey(runID))
 }
 
-func (s *S3Store) DeleteRun(id string) error {
+func (s *S3Store) SaveDiff(runID string, diff []byte) error {
 	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout)
 	defer cancel()
 
-	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(s.runKey(id)),
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.diffKey(runID)),
+		Body:        bytes.NewReader(diff),
+		ContentType: aws.String("text/x-diff"),
 	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to upload diff to S3: %w", err)
 	}
 
-	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+	return nil
+}
+
+func (s *S3Store) GetDiff(runID string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout)
+	defer cancel()
+
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(s.outputKey(id)),
+		Key:    aws.String(s.diffKey(runID)),
 	})
-	return err
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff from S3: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Store) ResourceHistory(address string, opts ListOptions) ([]ResourceEvent, error) {
+	return limitEvents(s.resourceHistory(address, opts, nil), opts.Limit), nil
+}
+
+func (s *S3Store) resourceHistory(address string, opts ListOptions, skip func(id string) bool) []ResourceEvent {
+	filter := opts
+	filter.Limit = 0
+
+	var events []ResourceEvent
+	s.walkDays(filter, func(runs []*run.Run) bool {
+		for _, r := range runs {
+			if skip == nil || !skip(r.ID) {
+				events = append(events, resourceEvents(r, address)...)
+			}
+		}
+		return opts.Limit <= 0 || len(events) < opts.Limit
+	})
+	return events
+}
+
+func (s *S3Store) Search(query string, opts ListOptions) ([]SearchResult, error) {
+	filter := opts
+	filter.Limit = 0
+	runs, err := s.ListRuns(filter)
+	if err != nil {
+		return nil, err
+	}
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	return limitResults(s.searchRuns(runs, terms, opts.Limit), opts.Limit), nil
+}
+
+func (s *S3Store) searchRuns(runs []*run.Run, terms []string, limit int) []SearchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), _searchTimeout)
+	defer cancel()
+
+	runs = runs[:min(len(runs), _maxSearchRuns)]
+	batch := getEnvInt("TFJOURNAL_S3_CONCURRENCY", _defaultMaxConcurrent)
+	var results []SearchResult
+	for start := 0; start < len(runs); start += batch {
+		if ctx.Err() != nil || (limit > 0 && len(results) >= limit) {
+			break
+		}
+		chunk := runs[start:min(start+batch, len(runs))]
+		found := make([]*SearchResult, len(chunk))
+		var wg sync.WaitGroup
+		for i, r := range chunk {
+			wg.Go(func() {
+				output, err := s.getOutput(ctx, r.ID)
+				if err != nil {
+					return
+				}
+				if result, ok := searchOutput(r, output, terms); ok {
+					found[i] = &result
+				}
+			})
+		}
+		wg.Wait()
+		for _, result := range found {
+			if result != nil {
+				results = append(results, *result)
+			}
+		}
+	}
+	return results
+}
+
+func (s *S3Store) DeleteRun(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), _s3Timeout)
+	defer cancel()
+
+	for _, key := range []string{s.runKey(id), s.outputKey(id), s.diffKey(id)} {
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *S3Store) ListRunIDs() (map[string]bool, error) {
@@ -366,6 +503,14 @@ func (s *S3Store) runKey(id string) string {
 	return fmt.Sprintf("%s%s/%s/%s.json", s.prefix, _runsDir, date.Format("2006/01/02"), id)
 }
 
+func (s *S3Store) diffKey(id string) string {
+	date, err := run.ParseDateFromID(id)
+	if err != nil {
+		return s.prefix + _diffsDir + "/" + id + ".patch"
+	}
+	return fmt.Sprintf("%s%s/%s/%s.patch", s.prefix, _diffsDir, date.Format("2006/01/02"), id)
+}
+
 func (s *S3Store) outputKey(id string) string {
 	date, err := run.ParseDateFromID(id)
 	if err != nil {
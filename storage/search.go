@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_outputIndex     = "outputs.jsonl"
+	_minTokenLength  = 2
+	_maxTokenLength  = 64
+	_maxSearchLines  = 5
+	_maxSnippetRunes = 200
+	_maxIndexLine    = 16 * 1024 * 1024
+)
+
+var ErrEmptyQuery = errors.New("search query must contain at least one word")
+
+type SearchMatch struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+type SearchResult struct {
+	RunID      string        `json:"run_id"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Workspace  string        `json:"workspace"`
+	Action     string        `json:"action"`
+	Status     run.Status    `json:"status"`
+	User       string        `json:"user"`
+	MatchCount int           `json:"match_count"`
+	Matches    []SearchMatch `json:"matches"`
+}
+
+type outputEntry struct {
+	RunID  string   `json:"run_id"`
+	Tokens []string `json:"tokens"`
+}
+
+func (s *LocalStore) Search(query string, opts ListOptions) ([]SearchResult, error) {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	if _, err := os.Stat(s.outputIndexPath()); os.IsNotExist(err) {
+		if err := s.ReindexOutputs(); err != nil {
+			return nil, err
+		}
+	}
+
+	postings, err := s.readOutputIndex(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := opts
+	filter.Limit = 0
+	var results []SearchResult
+	for _, id := range intersect(postings, terms) {
+		r, err := s.GetRun(id)
+		if err != nil || !matchesFilter(r, filter) {
+			continue
+		}
+		output, err := s.GetOutput(id)
+		if err != nil {
+			continue
+		}
+		if result, ok := searchOutput(r, output, terms); ok {
+			results = append(results, result)
+		}
+	}
+	return limitResults(results, opts.Limit), nil
+}
+
+func (s *LocalStore) ReindexOutputs() error {
+	runs, err := s.ListRuns(ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	path := s.outputIndexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), _outputIndex+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create output index: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	for _, r := range runs {
+		output, err := s.GetOutput(r.ID)
+		if err != nil {
+			continue
+		}
+		if err := writeOutputEntry(w, r.ID, output); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write output index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output index: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) indexOutput(runID string, output []byte, replaced bool) error {
+	path := s.outputIndexPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return s.ReindexOutputs()
+	}
+	if replaced {
+		if err := dropFromIndex(path, map[string]bool{runID: true}); err != nil {
+			return fmt.Errorf("failed to compact output index: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open output index: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := writeOutputEntry(w, runID, output); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write output index: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *LocalStore) readOutputIndex(terms []string) (map[string]map[string]bool, error) {
+	f, err := os.Open(s.outputIndexPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output index: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	postings := make(map[string]map[string]bool, len(terms))
+	for _, term := range terms {
+		postings[term] = make(map[string]bool)
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, readErr := readIndexLine(reader, _maxIndexLine)
+		var e outputEntry
+		if len(line) > 0 && json.Unmarshal(line, &e) == nil {
+			for _, term := range terms {
+				delete(postings[term], e.RunID)
+				if hasPrefixToken(e.Tokens, term) {
+					postings[term][e.RunID] = true
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return postings, nil
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read output index: %w", readErr)
+		}
+	}
+}
+
+func readIndexLine(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	skip := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !skip && len(line)+len(chunk) > limit {
+			skip, line = true, nil
+		}
+		if !skip {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+func (s *LocalStore) outputIndexPath() string {
+	return filepath.Join(s.baseDir, _indexDir, _outputIndex)
+}
+
+func writeOutputEntry(w *bufio.Writer, runID string, output []byte) error {
+	data, err := json.Marshal(outputEntry{RunID: runID, Tokens: Tokenize(string(output))})
+	if err != nil {
+		return fmt.Errorf("failed to marshal output index entry: %w", err)
+	}
+	_, _ = w.Write(data)
+	_ = w.WriteByte('\n')
+	return nil
+}
+
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isTokenSeparator) {
+		if len(word) < _minTokenLength || len(word) > _maxTokenLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+func isTokenSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+func hasPrefixToken(tokens []string, term string) bool {
+	i, _ := slices.BinarySearch(tokens, term)
+	return i < len(tokens) && strings.HasPrefix(tokens[i], term)
+}
+
+func intersect(postings map[string]map[string]bool, terms []string) []string {
+	var ids []string
+	for id := range postings[terms[0]] {
+		matched := true
+		for _, term := range terms[1:] {
+			if !postings[term][id] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func searchOutput(r *run.Run, output []byte, terms []string) (SearchResult, bool) {
+	lines := strings.Split(string(output), "\n")
+	found := make(map[string]bool, len(terms))
+	var all, partial []SearchMatch
+	for i, line := range lines {
+		tokens := Tokenize(line)
+		count := 0
+		for _, term := range terms {
+			if hasPrefixToken(tokens, term) {
+				found[term] = true
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		match := SearchMatch{Line: i + 1, Text: snippet(line)}
+		if count == len(terms) {
+			all = append(all, match)
+		}
+		partial = append(partial, match)
+	}
+	if len(found) < len(terms) {
+		return SearchResult{}, false
+	}
+
+	matches := all
+	if len(matches) == 0 {
+		matches = partial
+	}
+
+	result := SearchResult{
+		RunID:      r.ID,
+		Timestamp:  r.Timestamp,
+		Workspace:  r.Workspace,
+		Action:     r.Action(),
+		Status:     r.Status,
+		User:       r.User,
+		MatchCount: len(matches),
+		Matches:    matches[:min(len(matches), _maxSearchLines)],
+	}
+	return result, true
+}
+
+func snippet(line string) string {
+	line = strings.TrimSpace(strings.TrimLeft(line, "│╷╵ \t"))
+	if r := []rune(line); len(r) > _maxSnippetRunes {
+		return string(r[:_maxSnippetRunes-1]) + "…"
+	}
+	return line
+}
+
+func limitResults(results []SearchResult, limit int) []SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
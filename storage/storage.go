@@ -2,7 +2,9 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -14,12 +16,17 @@ import (
 var (
 	ErrRunNotFound    = errors.New("run not found")
 	ErrOutputNotFound = errors.New("output not found")
+	ErrDiffNotFound   = errors.New("diff not found")
 	ErrInvalidRunID   = errors.New("invalid run ID")
 )
 
 const (
 	_runsDir    = "runs"
 	_outputsDir = "outputs"
+	_diffsDir   = "diffs"
+
+	_pruneMarker   = "pruned"
+	_pruneInterval = time.Hour
 )
 
 type ListOptions struct {
@@ -30,8 +37,23 @@ type ListOptions struct {
 	Program    string
 	Action     string
 	Branch     string
+	PR         string
+	Version    string
+	Provider   string
+	State      string
+	Account    string
+	Parent     string
 	HasChanges bool
 	Limit      int
+	Before     string
+	After      string
+}
+
+type Page struct {
+	Runs       []*run.Run `json:"runs"`
+	Total      int        `json:"total"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	PrevCursor string     `json:"prev_cursor,omitempty"`
 }
 
 type SyncResult struct {
@@ -48,7 +70,11 @@ type Store interface {
 	SaveOutput(runID string, output []byte) error
 	GetOutput(runID string) ([]byte, error)
 	OutputPath(runID string) string
+	SaveDiff(runID string, diff []byte) error
+	GetDiff(runID string) ([]byte, error)
 	DeleteRun(id string) error
+	ResourceHistory(address string, opts ListOptions) ([]ResourceEvent, error)
+	Search(query string, opts ListOptions) ([]SearchResult, error)
 	Sync() (*SyncResult, error)
 	Close() error
 }
@@ -65,22 +91,6 @@ func New(localPath string) (*LocalStore, error) {
 	return NewLocalStore(localPath)
 }
 
-func NewFromEnv() (Store, error) {
-	cfg := Config{
-		LocalPath:  DefaultPath(),
-		S3Bucket:   os.Getenv("TFJOURNAL_S3_BUCKET"),
-		S3Region:   os.Getenv("TFJOURNAL_S3_REGION"),
-		S3Prefix:   os.Getenv("TFJOURNAL_S3_PREFIX"),
-		AWSProfile: os.Getenv("AWS_PROFILE"),
-	}
-
-	if path := os.Getenv("TFJOURNAL_STORAGE_PATH"); path != "" {
-		cfg.LocalPath = path
-	}
-
-	return NewFromConfig(cfg)
-}
-
 func NewFromConfig(cfg Config) (Store, error) {
 	local, err := NewLocalStore(cfg.LocalPath)
 	if err != nil {
@@ -114,6 +124,75 @@ func DefaultPath() string {
 	return filepath.Join(home, ".local", "share", "tfjournal")
 }
 
+func Prune(store Store, maxAge time.Duration, maxRuns int) (int, error) {
+	runs, err := store.ListRunsLocal(ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	local, ok := store.(*LocalStore)
+	unsynced := func(string) bool { return false }
+	if h, isHybrid := store.(*HybridStore); isHybrid {
+		remote, err := h.s3.ListRunIDs()
+		if err != nil {
+			return 0, fmt.Errorf("failed to list S3 runs: %w", err)
+		}
+		local, ok = h.local, true
+		unsynced = func(id string) bool { return !remote[id] }
+	}
+	if !ok {
+		return 0, nil
+	}
+	return pruneRuns(local, runs, maxAge, maxRuns, unsynced)
+}
+
+func PruneIfDue(store Store, maxAge time.Duration, maxRuns int) (int, error) {
+	local, ok := store.(*LocalStore)
+	if h, isHybrid := store.(*HybridStore); isHybrid {
+		local, ok = h.local, true
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	marker := filepath.Join(local.baseDir, _indexDir, _pruneMarker)
+	if fi, err := os.Stat(marker); err == nil && time.Since(fi.ModTime()) < _pruneInterval {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(marker), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return 0, fmt.Errorf("failed to write prune marker: %w", err)
+	}
+	return Prune(store, maxAge, maxRuns)
+}
+
+func pruneRuns(local *LocalStore, runs []*run.Run, maxAge time.Duration, maxRuns int, unsynced func(id string) bool) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	pruned := make(map[string]bool)
+	var deleteErr error
+	for i, r := range runs {
+		if r.Status == run.StatusRunning || unsynced(r.ID) {
+			continue
+		}
+		expired := maxAge > 0 && r.Timestamp.Before(cutoff)
+		if !expired && (maxRuns <= 0 || i < maxRuns) {
+			continue
+		}
+		if deleteErr = local.deleteFiles(r.ID); deleteErr != nil {
+			break
+		}
+		pruned[r.ID] = true
+	}
+	if len(pruned) > 0 {
+		if err := local.dropFromIndexes(pruned); err != nil {
+			return len(pruned), err
+		}
+	}
+	return len(pruned), deleteErr
+}
+
 func matchesFilter(r *run.Run, opts ListOptions) bool {
 	if opts.Workspace != "" {
 		pattern := opts.Workspace
@@ -152,6 +231,42 @@ func matchesFilter(r *run.Run, opts ListOptions) bool {
 		}
 	}
 
+	if opts.PR != "" {
+		if r.CI == nil || r.CI.PullRequest != strings.TrimPrefix(opts.PR, "#") {
+			return false
+		}
+	}
+
+	if opts.Version != "" {
+		if r.Versions == nil || !run.MatchVersion(r.Versions.Version, opts.Version) {
+			return false
+		}
+	}
+
+	if opts.Provider != "" && !matchesProvider(r, opts.Provider) {
+		return false
+	}
+
+	if opts.State != "" {
+		if r.State == nil || r.State.ID == "" {
+			return false
+		}
+		matched, _ := path.Match(opts.State, r.State.ID)
+		if !matched && r.State.ID != opts.State {
+			return false
+		}
+	}
+
+	if opts.Account != "" {
+		if r.Cloud == nil || !r.Cloud.Matches(opts.Account) {
+			return false
+		}
+	}
+
+	if opts.Parent != "" && r.ParentID != opts.Parent {
+		return false
+	}
+
 	if opts.HasChanges {
 		if r.Changes == nil {
 			return false
@@ -163,3 +278,70 @@ func matchesFilter(r *run.Run, opts ListOptions) bool {
 
 	return true
 }
+
+func matchesProvider(r *run.Run, filter string) bool {
+	if r.Versions == nil {
+		return false
+	}
+	name, version, _ := strings.Cut(filter, "@")
+	p := r.Versions.Provider(name)
+	if p == nil {
+		return false
+	}
+	return version == "" || run.MatchVersion(p.Version, version)
+}
+
+func Paginate(runs []*run.Run, opts ListOptions) *Page {
+	start, end := 0, len(runs)
+
+	if opts.Before != "" {
+		start = cursorIndex(runs, opts.Before, true)
+	}
+	if opts.After != "" {
+		end = cursorIndex(runs, opts.After, false)
+	}
+	if end < start {
+		end = start
+	}
+
+	if opts.Limit > 0 && end-start > opts.Limit {
+		if opts.After != "" && opts.Before == "" {
+			start = end - opts.Limit
+		} else {
+			end = start + opts.Limit
+		}
+	}
+
+	page := &Page{
+		Runs:  runs[start:end],
+		Total: len(runs),
+	}
+	if len(page.Runs) == 0 {
+		page.Runs = []*run.Run{}
+		return page
+	}
+	if end < len(runs) {
+		page.NextCursor = runs[end-1].ID
+	}
+	if start > 0 {
+		page.PrevCursor = runs[start].ID
+	}
+	return page
+}
+
+func cursorIndex(runs []*run.Run, cursor string, after bool) int {
+	for i, r := range runs {
+		if r.ID == cursor {
+			if after {
+				return i + 1
+			}
+			return i
+		}
+	}
+	for i, r := range runs {
+		if r.ID < cursor {
+			return i
+		}
+	}
+	return len(runs)
+}
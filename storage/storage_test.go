@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"bufio"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -81,9 +86,13 @@ func TestStore_ListRuns(t *testing.T) {
 	id3 := run.GenerateID(ts3)
 
 	runs := []*run.Run{
-		{ID: id1, Workspace: "prod/web", Timestamp: ts1, Status: run.StatusSuccess, User: "alice"},
-		{ID: id2, Workspace: "prod/api", Timestamp: ts2, Status: run.StatusFailed, User: "bob"},
-		{ID: id3, Workspace: "dev/web", Timestamp: ts3, Status: run.StatusSuccess, User: "alice"},
+		{ID: id1, Workspace: "prod/web", Timestamp: ts1, Status: run.StatusSuccess, User: "alice", State: &run.StateInfo{Backend: "s3", ID: "s3://acme-state/prod/web.tfstate"}},
+		{ID: id2, Workspace: "prod/api", Timestamp: ts2, Status: run.StatusFailed, User: "bob", CI: &run.CIInfo{Provider: "github-actions", PullRequest: "42"}, Cloud: &run.CloudInfo{AWSAccountID: "123456789012", Env: map[string]string{"AWS_PROFILE": "prod-admin"}}},
+		{ID: id3, Workspace: "dev/web", Timestamp: ts3, Status: run.StatusSuccess, User: "alice", ParentID: id2, Attempt: 2, Versions: &run.Versions{
+			Binary:    "terraform",
+			Version:   "1.7.5",
+			Providers: []run.ProviderVersion{{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0"}},
+		}},
 	}
 
 	for _, r := range runs {
@@ -128,6 +137,78 @@ func TestStore_ListRuns(t *testing.T) {
 		}
 	})
 
+	t.Run("filter by pull request", func(t *testing.T) {
+		got, err := store.ListRuns(ListOptions{PR: "#42"})
+		if err != nil {
+			t.Fatalf("failed to list runs: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != id2 {
+			t.Errorf("got %d runs, want only %s", len(got), id2)
+		}
+	})
+
+	t.Run("filter by versions", func(t *testing.T) {
+		for _, opts := range []ListOptions{
+			{Version: "1.7"},
+			{Version: "v1.7.5"},
+			{Provider: "aws"},
+			{Provider: "hashicorp/aws@5.31"},
+		} {
+			got, err := store.ListRuns(opts)
+			if err != nil {
+				t.Fatalf("failed to list runs: %v", err)
+			}
+			if len(got) != 1 || got[0].ID != id3 {
+				t.Errorf("%+v: got %d runs, want only %s", opts, len(got), id3)
+			}
+		}
+
+		for _, opts := range []ListOptions{{Version: "1.70"}, {Provider: "aws@5.3"}, {Provider: "google"}} {
+			got, err := store.ListRuns(opts)
+			if err != nil {
+				t.Fatalf("failed to list runs: %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("%+v: got %d runs, want 0", opts, len(got))
+			}
+		}
+	})
+
+	t.Run("filter by account", func(t *testing.T) {
+		got, err := store.ListRuns(ListOptions{Account: "123456789012"})
+		if err != nil {
+			t.Fatalf("failed to list runs: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != id2 {
+			t.Errorf("got %d runs, want only %s", len(got), id2)
+		}
+		if got, _ := store.ListRuns(ListOptions{Account: "prod-admin"}); len(got) != 0 {
+			t.Errorf("profile name matched %d runs, want 0", len(got))
+		}
+	})
+
+	t.Run("filter by state", func(t *testing.T) {
+		for _, pattern := range []string{"s3://acme-state/prod/web.tfstate", "s3://acme-state/prod/*"} {
+			got, err := store.ListRuns(ListOptions{State: pattern})
+			if err != nil {
+				t.Fatalf("failed to list runs: %v", err)
+			}
+			if len(got) != 1 || got[0].ID != id1 {
+				t.Errorf("%s: got %d runs, want only %s", pattern, len(got), id1)
+			}
+		}
+	})
+
+	t.Run("filter by parent", func(t *testing.T) {
+		got, err := store.ListRuns(ListOptions{Parent: id2})
+		if err != nil {
+			t.Fatalf("failed to list runs: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != id3 || got[0].Attempt != 2 {
+			t.Errorf("got %d runs, want only %s", len(got), id3)
+		}
+	})
+
 	t.Run("limit", func(t *testing.T) {
 		got, err := store.ListRuns(ListOptions{Limit: 2})
 		if err != nil {
@@ -163,6 +244,46 @@ func TestStore_SaveAndGetOutput(t *testing.T) {
 	}
 }
 
+func TestStore_SaveAndGetDiff(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	ts := time.Date(2025, 1, 26, 14, 30, 52, 0, time.UTC)
+	id := run.GenerateID(ts)
+
+	if _, err := store.GetDiff(id); !errors.Is(err, ErrDiffNotFound) {
+		t.Errorf("GetDiff() error = %v, want ErrDiffNotFound", err)
+	}
+
+	diff := []byte("diff --git a/main.tf b/main.tf\n+resource \"null_resource\" \"x\" {}\n")
+	if err := store.SaveDiff(id, diff); err != nil {
+		t.Fatalf("failed to save diff: %v", err)
+	}
+
+	got, err := store.GetDiff(id)
+	if err != nil {
+		t.Fatalf("failed to get diff: %v", err)
+	}
+	if string(got) != string(diff) {
+		t.Errorf("diff = %q, want %q", string(got), string(diff))
+	}
+
+	diffFile := filepath.Join(dir, "diffs", "2025", "01", "26", id+".patch")
+	if _, err := os.Stat(diffFile); os.IsNotExist(err) {
+		t.Errorf("diff file not created at %s", diffFile)
+	}
+
+	if err := store.DeleteRun(id); err != nil {
+		t.Fatalf("DeleteRun() error = %v", err)
+	}
+	if _, err := os.Stat(diffFile); !os.IsNotExist(err) {
+		t.Errorf("diff file not removed with run")
+	}
+}
+
 func TestStore_DirectoryStructure(t *testing.T) {
 	dir := t.TempDir()
 	store, err := New(dir)
@@ -191,3 +312,546 @@ func TestStore_DirectoryStructure(t *testing.T) {
 		t.Errorf("output file not created at %s", outputFile)
 	}
 }
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxRuns int
+		want    int
+	}{
+		{"max age", 36 * time.Hour, 0, 2},
+		{"max runs", 0, 3, 3},
+		{"both", 36 * time.Hour, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+
+			ages := []time.Duration{time.Hour, 24 * time.Hour, 48 * time.Hour, 72 * time.Hour}
+			for _, age := range ages {
+				ts := time.Now().Add(-age)
+				if err := store.SaveRun(&run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusSuccess}); err != nil {
+					t.Fatalf("failed to save run: %v", err)
+				}
+			}
+			ts := time.Now().Add(-96 * time.Hour)
+			if err := store.SaveRun(&run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusRunning}); err != nil {
+				t.Fatalf("failed to save run: %v", err)
+			}
+
+			pruned, err := Prune(store, tt.maxAge, tt.maxRuns)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if pruned != len(ages)-tt.want {
+				t.Errorf("pruned %d runs, want %d", pruned, len(ages)-tt.want)
+			}
+
+			left, _ := store.ListRuns(ListOptions{})
+			if len(left) != tt.want+1 {
+				t.Errorf("%d runs left, want %d (including the running one)", len(left), tt.want+1)
+			}
+		})
+	}
+}
+
+func TestPruneRuns_KeepsUnsynced(t *testing.T) {
+	store, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	var ids []string
+	for _, age := range []time.Duration{time.Hour, 48 * time.Hour, 72 * time.Hour} {
+		ts := time.Now().Add(-age)
+		id := run.GenerateID(ts)
+		ids = append(ids, id)
+		if err := store.SaveRun(&run.Run{ID: id, Timestamp: ts, Status: run.StatusSuccess}); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+
+	runs, _ := store.ListRuns(ListOptions{})
+	unsynced := func(id string) bool { return id == ids[2] }
+	pruned, err := pruneRuns(store, runs, 24*time.Hour, 0, unsynced)
+	if err != nil {
+		t.Fatalf("pruneRuns() error = %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned %d runs, want 1", pruned)
+	}
+	if !store.HasRun(ids[2]) {
+		t.Error("unsynced run was pruned")
+	}
+	if store.HasRun(ids[1]) {
+		t.Error("synced expired run was kept")
+	}
+}
+
+func TestPruneIfDue(t *testing.T) {
+	store, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	save := func() {
+		ts := time.Now().Add(-48 * time.Hour)
+		if err := store.SaveRun(&run.Run{ID: run.GenerateID(ts), Timestamp: ts, Status: run.StatusSuccess}); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+
+	save()
+	if pruned, err := PruneIfDue(store, 24*time.Hour, 0); err != nil || pruned != 1 {
+		t.Fatalf("first PruneIfDue() = %d, %v, want 1", pruned, err)
+	}
+
+	save()
+	if pruned, err := PruneIfDue(store, 24*time.Hour, 0); err != nil || pruned != 0 {
+		t.Errorf("second PruneIfDue() = %d, %v, want 0 within the interval", pruned, err)
+	}
+}
+
+func TestStore_ResourceHistory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	plan := &run.Run{
+		ID:        run.GenerateID(now.Add(-2 * time.Hour)),
+		Workspace: "production/web",
+		Timestamp: now.Add(-2 * time.Hour),
+		Status:    run.StatusSuccess,
+		User:      "alice",
+		Command:   []string{"terraform", "plan"},
+		Planned:   []run.Planned{{Address: "aws_instance.web", Action: "update"}},
+	}
+	apply := &run.Run{
+		ID:        run.GenerateID(now.Add(-time.Hour)),
+		Workspace: "production/web",
+		Timestamp: now.Add(-time.Hour),
+		Status:    run.StatusFailed,
+		User:      "bob",
+		Command:   []string{"terraform", "apply"},
+		Planned:   []run.Planned{{Address: "aws_instance.web", Action: "update"}},
+		Resources: []run.Resource{
+			{Address: "aws_instance.web", Action: "update", DurationMs: 4200, Status: "failed"},
+			{Address: "aws_subnet.private[0]", Action: "create", DurationMs: 900, Status: "success"},
+		},
+	}
+	other := &run.Run{
+		ID:        run.GenerateID(now),
+		Workspace: "staging/web",
+		Timestamp: now,
+		Status:    run.StatusSuccess,
+		User:      "alice",
+		Resources: []run.Resource{{Address: "aws_subnet.private[1]", Action: "create", Status: "success"}},
+	}
+	for _, r := range []*run.Run{plan, apply, other} {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+
+	indexPath := filepath.Join(dir, "index", "resources.jsonl")
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("resource index not written: %v", err)
+	}
+
+	t.Run("exact address", func(t *testing.T) {
+		events, err := store.ResourceHistory("aws_instance.web", ListOptions{})
+		if err != nil {
+			t.Fatalf("ResourceHistory() error = %v", err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("got %d events, want 2", len(events))
+		}
+		if events[0].RunID != apply.ID || events[0].Planned || events[0].DurationMs != 4200 || events[0].User != "bob" {
+			t.Errorf("events[0] = %+v", events[0])
+		}
+		if events[1].RunID != plan.ID || !events[1].Planned || events[1].RunAction != "plan" {
+			t.Errorf("events[1] = %+v", events[1])
+		}
+	})
+
+	t.Run("wildcard with filters", func(t *testing.T) {
+		events, err := store.ResourceHistory("aws_subnet.private[*]", ListOptions{Workspace: "production/%"})
+		if err != nil {
+			t.Fatalf("ResourceHistory() error = %v", err)
+		}
+		if len(events) != 1 || events[0].Address != "aws_subnet.private[0]" {
+			t.Errorf("events = %+v", events)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		events, _ := store.ResourceHistory("aws_*", ListOptions{Limit: 2})
+		if len(events) != 2 || events[0].RunID != other.ID {
+			t.Errorf("events = %+v", events)
+		}
+	})
+
+	t.Run("rebuilds missing index", func(t *testing.T) {
+		if err := os.Remove(indexPath); err != nil {
+			t.Fatal(err)
+		}
+		events, err := store.ResourceHistory("aws_instance.web", ListOptions{})
+		if err != nil {
+			t.Fatalf("ResourceHistory() error = %v", err)
+		}
+		if len(events) != 2 {
+			t.Errorf("got %d events after reindex, want 2", len(events))
+		}
+		if _, err := os.Stat(indexPath); err != nil {
+			t.Errorf("resource index not rebuilt: %v", err)
+		}
+	})
+}
+
+func TestStore_ResourceIndexMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	indexPath := filepath.Join(dir, "index", "resources.jsonl")
+	lines := func() int {
+		data, _ := os.ReadFile(indexPath)
+		return strings.Count(string(data), "\n")
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	stale := &run.Run{ID: run.GenerateID(old), Timestamp: old, Status: run.StatusSuccess, Resources: []run.Resource{{Address: "aws_instance.old"}}}
+	now := time.Now()
+	fresh := &run.Run{ID: run.GenerateID(now), Timestamp: now, Status: run.StatusSuccess, Resources: []run.Resource{{Address: "aws_instance.web"}}}
+	for _, r := range []*run.Run{stale, fresh} {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+	}
+	if got := lines(); got != 2 {
+		t.Fatalf("index has %d lines, want 2", got)
+	}
+
+	fresh.Workspace = "renamed"
+	if err := store.SaveRun(fresh); err != nil {
+		t.Fatalf("failed to re-save run: %v", err)
+	}
+	if got := lines(); got != 2 {
+		t.Errorf("re-saving an unchanged run grew the index to %d lines", got)
+	}
+
+	if pruned, err := Prune(store, 24*time.Hour, 0); err != nil || pruned != 1 {
+		t.Fatalf("Prune() = %d, %v, want 1", pruned, err)
+	}
+	data, _ := os.ReadFile(indexPath)
+	if strings.Contains(string(data), stale.ID) || !strings.Contains(string(data), fresh.ID) {
+		t.Errorf("index after prune = %s", data)
+	}
+
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(indexPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveRun(&run.Run{ID: run.NewID(), Timestamp: now, Resources: []run.Resource{{Address: "a.b"}}}); err != nil {
+		t.Errorf("SaveRun() error = %v, want index failures to be logged only", err)
+	}
+}
+
+func TestStore_Search(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	locked := &run.Run{
+		ID:        run.GenerateID(now.Add(-time.Hour)),
+		Workspace: "production/web",
+		Timestamp: now.Add(-time.Hour),
+		Status:    run.StatusFailed,
+		User:      "alice",
+		Command:   []string{"terraform", "apply"},
+	}
+	applied := &run.Run{
+		ID:        run.GenerateID(now),
+		Workspace: "staging/web",
+		Timestamp: now,
+		Status:    run.StatusSuccess,
+		User:      "bob",
+		Command:   []string{"terraform", "apply"},
+	}
+	outputs := map[*run.Run]string{
+		locked:  "Acquiring state lock. This may take a few moments...\n\n│ Error: Error acquiring the state lock\n│\n│ Lock Info:\n│   ID: 4f3c\n",
+		applied: "aws_instance.web: Modifying...\naws_instance.web: Modifications complete after 4s\n\nApply complete! Resources: 0 added, 1 changed, 0 destroyed.\n",
+	}
+	for r, output := range outputs {
+		if err := store.SaveRun(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+		if err := store.SaveOutput(r.ID, []byte(output)); err != nil {
+			t.Fatalf("failed to save output: %v", err)
+		}
+	}
+
+	indexPath := filepath.Join(dir, "index", "outputs.jsonl")
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("output index not written: %v", err)
+	}
+
+	t.Run("matching lines", func(t *testing.T) {
+		results, err := store.Search("state LOCK", ListOptions{})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(results) != 1 || results[0].RunID != locked.ID {
+			t.Fatalf("results = %+v", results)
+		}
+		want := []SearchMatch{
+			{Line: 1, Text: "Acquiring state lock. This may take a few moments..."},
+			{Line: 3, Text: "Error: Error acquiring the state lock"},
+		}
+		if results[0].MatchCount != 2 || !reflect.DeepEqual(results[0].Matches, want) {
+			t.Errorf("matches = %+v", results[0].Matches)
+		}
+	})
+
+	t.Run("prefix and filters", func(t *testing.T) {
+		results, _ := store.Search("modif", ListOptions{})
+		if len(results) != 1 || results[0].RunID != applied.ID || results[0].MatchCount != 2 {
+			t.Errorf("results = %+v", results)
+		}
+		results, _ = store.Search("modif", ListOptions{Workspace: "production/%"})
+		if len(results) != 0 {
+			t.Errorf("workspace filter: results = %+v", results)
+		}
+	})
+
+	t.Run("all terms required", func(t *testing.T) {
+		results, _ := store.Search("lock complete", ListOptions{})
+		if len(results) != 0 {
+			t.Errorf("results = %+v", results)
+		}
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		if _, err := store.Search(" ... ", ListOptions{}); !errors.Is(err, ErrEmptyQuery) {
+			t.Errorf("Search() error = %v, want ErrEmptyQuery", err)
+		}
+	})
+
+	t.Run("unchanged output is not re-indexed", func(t *testing.T) {
+		before, _ := os.ReadFile(indexPath)
+		if err := store.SaveOutput(applied.ID, []byte(outputs[applied])); err != nil {
+			t.Fatalf("failed to save output: %v", err)
+		}
+		after, _ := os.ReadFile(indexPath)
+		if len(after) != len(before) {
+			t.Errorf("index grew from %d to %d bytes", len(before), len(after))
+		}
+	})
+
+	t.Run("words match by prefix only", func(t *testing.T) {
+		if results, _ := store.Search("quiring", ListOptions{}); len(results) != 0 {
+			t.Errorf("results = %+v", results)
+		}
+		if _, ok := searchOutput(locked, []byte(outputs[locked]), []string{"quiring"}); ok {
+			t.Error("searchOutput() matched inside a word")
+		}
+	})
+
+	t.Run("rewritten output replaces its index entry", func(t *testing.T) {
+		rewritten := &run.Run{ID: run.NewID(), Timestamp: now, Status: run.StatusSuccess}
+		if err := store.SaveRun(rewritten); err != nil {
+			t.Fatal(err)
+		}
+		for _, output := range []string{"refreshing stale token", "apply finished"} {
+			if err := store.SaveOutput(rewritten.ID, []byte(output)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, _ := os.ReadFile(indexPath)
+		if n := strings.Count(string(data), rewritten.ID); n != 1 {
+			t.Errorf("output index has %d entries for the run, want 1", n)
+		}
+		if strings.Contains(string(data), "stale") {
+			t.Error("output index kept tokens of the replaced output")
+		}
+		if err := store.DeleteRun(rewritten.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("deleted runs leave the index", func(t *testing.T) {
+		extra := &run.Run{ID: run.NewID(), Timestamp: now, Status: run.StatusSuccess}
+		if err := store.SaveRun(extra); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveOutput(extra.ID, []byte("state lock released")); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteRun(extra.ID); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(indexPath)
+		if strings.Contains(string(data), extra.ID) {
+			t.Error("deleted run still in the output index")
+		}
+	})
+
+	t.Run("rebuilds missing index", func(t *testing.T) {
+		if err := os.Remove(indexPath); err != nil {
+			t.Fatal(err)
+		}
+		results, err := store.Search("apply complete", ListOptions{})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(results) != 1 || results[0].Matches[0].Line != 4 {
+			t.Errorf("results = %+v", results)
+		}
+		if _, err := os.Stat(indexPath); err != nil {
+			t.Errorf("output index not rebuilt: %v", err)
+		}
+	})
+}
+
+func TestReadIndexLine(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	reader := bufio.NewReaderSize(strings.NewReader("first\n"+long+"\nlast"), 16)
+
+	var lines []string
+	for {
+		line, err := readIndexLine(reader, 64)
+		if len(line) > 0 {
+			lines = append(lines, strings.TrimSuffix(string(line), "\n"))
+		}
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("readIndexLine() error = %v", err)
+			}
+			break
+		}
+	}
+	if want := []string{"first", "last"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("readIndexLine() lines = %q, want %q", lines, want)
+	}
+}
+
+func TestMatchAddress(t *testing.T) {
+	tests := []struct {
+		pattern string
+		address string
+		want    bool
+	}{
+		{"aws_instance.web", "aws_instance.web", true},
+		{"aws_instance.web", "aws_instance.web2", false},
+		{"aws_instance.*", "aws_instance.web", true},
+		{"module.vpc.*", "module.vpc.aws_subnet.private[0]", true},
+		{"*.aws_subnet.*", "module.vpc.aws_subnet.private[0]", true},
+		{`aws_instance.web["*"]`, `aws_instance.web["a"]`, true},
+		{"*.web", "aws_instance.api", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchAddress(tt.pattern, tt.address); got != tt.want {
+			t.Errorf("MatchAddress(%q, %q) = %v, want %v", tt.pattern, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	base := time.Date(2025, 1, 26, 12, 0, 0, 0, time.UTC)
+	var runs []*run.Run
+	for i := range 5 {
+		ts := base.Add(-time.Duration(i) * time.Hour)
+		runs = append(runs, &run.Run{ID: run.GenerateID(ts), Timestamp: ts})
+	}
+
+	ids := func(p *Page) []string {
+		var out []string
+		for _, r := range p.Runs {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		p := Paginate(runs, ListOptions{Limit: 2})
+		if p.Total != 5 {
+			t.Errorf("Total = %d, want 5", p.Total)
+		}
+		if got := ids(p); len(got) != 2 || got[0] != runs[0].ID || got[1] != runs[1].ID {
+			t.Errorf("runs = %v, want first two", got)
+		}
+		if p.NextCursor != runs[1].ID {
+			t.Errorf("NextCursor = %s, want %s", p.NextCursor, runs[1].ID)
+		}
+		if p.PrevCursor != "" {
+			t.Errorf("PrevCursor = %s, want empty", p.PrevCursor)
+		}
+	})
+
+	t.Run("before cursor", func(t *testing.T) {
+		p := Paginate(runs, ListOptions{Limit: 2, Before: runs[1].ID})
+		if got := ids(p); len(got) != 2 || got[0] != runs[2].ID || got[1] != runs[3].ID {
+			t.Errorf("runs = %v, want runs 2 and 3", got)
+		}
+		if p.NextCursor != runs[3].ID {
+			t.Errorf("NextCursor = %s, want %s", p.NextCursor, runs[3].ID)
+		}
+		if p.PrevCursor != runs[2].ID {
+			t.Errorf("PrevCursor = %s, want %s", p.PrevCursor, runs[2].ID)
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		p := Paginate(runs, ListOptions{Limit: 2, Before: runs[3].ID})
+		if got := ids(p); len(got) != 1 || got[0] != runs[4].ID {
+			t.Errorf("runs = %v, want run 4", got)
+		}
+		if p.NextCursor != "" {
+			t.Errorf("NextCursor = %s, want empty", p.NextCursor)
+		}
+	})
+
+	t.Run("after cursor", func(t *testing.T) {
+		p := Paginate(runs, ListOptions{Limit: 2, After: runs[4].ID})
+		if got := ids(p); len(got) != 2 || got[0] != runs[2].ID || got[1] != runs[3].ID {
+			t.Errorf("runs = %v, want runs 2 and 3", got)
+		}
+	})
+
+	t.Run("unknown cursor", func(t *testing.T) {
+		cursor := run.GenerateID(base.Add(-150 * time.Minute))
+		p := Paginate(runs, ListOptions{Limit: 10, Before: cursor})
+		if got := ids(p); len(got) != 2 || got[0] != runs[3].ID {
+			t.Errorf("runs = %v, want runs older than cursor", got)
+		}
+	})
+
+	t.Run("total on every page", func(t *testing.T) {
+		p := Paginate(runs, ListOptions{Limit: 2, Before: runs[3].ID})
+		if p.Total != 5 {
+			t.Errorf("Total = %d, want 5", p.Total)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		p := Paginate(nil, ListOptions{Limit: 2})
+		if p.Runs == nil || len(p.Runs) != 0 || p.Total != 0 {
+			t.Errorf("got %+v, want empty page", p)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package tfversion
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Owloops/tfjournal/run"
+)
+
+const (
+	_versionTimeout = 5 * time.Second
+	_lockFile       = ".terraform.lock.hcl"
+	_modulesFile    = "modules/modules.json"
+)
+
+var (
+	providerBlock = regexp.MustCompile(`^provider\s+"([^"]+)"\s*\{`)
+	attribute     = regexp.MustCompile(`^(\w+)\s*=\s*"([^"]*)"`)
+	quoted        = regexp.MustCompile(`"([^"]*)"`)
+)
+
+func Detect(program, dir string) *run.Versions {
+	v := &run.Versions{}
+	switch name := strings.TrimSuffix(filepath.Base(program), ".exe"); name {
+	case "terragrunt":
+		v.Terragrunt = terragruntVersion(program)
+		binary := terragruntBinary()
+		v.Binary = filepath.Base(binary)
+		v.Version = binaryVersion(binary)
+	case "terraform", "tofu":
+		v.Binary = name
+		v.Version = binaryVersion(program)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, _lockFile)); err == nil {
+		v.Providers = ParseLockFile(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(dataDir(dir), _modulesFile)); err == nil {
+		v.Modules = ParseModules(data)
+	}
+
+	if v.Version == "" && v.Terragrunt == "" && len(v.Providers) == 0 && len(v.Modules) == 0 {
+		return nil
+	}
+	return v
+}
+
+func ParseLockFile(data string) []run.ProviderVersion {
+	var (
+		providers []run.ProviderVersion
+		current   *run.ProviderVersion
+		inHashes  bool
+	)
+	for line := range strings.SplitSeq(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case current == nil:
+			if m := providerBlock.FindStringSubmatch(line); m != nil {
+				current = &run.ProviderVersion{Source: m[1]}
+			}
+		case inHashes:
+			for _, m := range quoted.FindAllStringSubmatch(line, -1) {
+				current.Hashes = append(current.Hashes, m[1])
+			}
+			inHashes = !strings.HasPrefix(line, "]")
+		case strings.HasPrefix(line, "hashes"):
+			for _, m := range quoted.FindAllStringSubmatch(line, -1) {
+				current.Hashes = append(current.Hashes, m[1])
+			}
+			inHashes = !strings.HasSuffix(line, "]")
+		case line == "}":
+			providers = append(providers, *current)
+			current = nil
+		default:
+			m := attribute.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			switch m[1] {
+			case "version":
+				current.Version = m[2]
+			case "constraints":
+				current.Constraints = m[2]
+			}
+		}
+	}
+	return providers
+}
+
+func ParseModules(data []byte) []run.ModuleVersion {
+	var manifest struct {
+		Modules []struct {
+			Key     string `json:"Key"`
+			Source  string `json:"Source"`
+			Version string `json:"Version"`
+		} `json:"Modules"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+
+	var modules []run.ModuleVersion
+	for _, m := range manifest.Modules {
+		if m.Key == "" {
+			continue
+		}
+		modules = append(modules, run.ModuleVersion{Key: m.Key, Source: m.Source, Version: m.Version})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Key < modules[j].Key
+	})
+	return modules
+}
+
+func ParseVersionJSON(data []byte) string {
+	var out struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return ""
+	}
+	return out.TerraformVersion
+}
+
+func binaryVersion(binary string) string {
+	return ParseVersionJSON([]byte(runVersion(binary, "version", "-json")))
+}
+
+func terragruntVersion(program string) string {
+	fields := strings.Fields(runVersion(program, "--version"))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(fields[len(fields)-1], "v")
+}
+
+func terragruntBinary() string {
+	for _, key := range []string{"TG_TF_PATH", "TERRAGRUNT_TFPATH"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	if _, err := exec.LookPath("tofu"); err == nil {
+		return "tofu"
+	}
+	return "terraform"
+}
+
+func dataDir(dir string) string {
+	data := os.Getenv("TF_DATA_DIR")
+	if data == "" {
+		data = ".terraform"
+	}
+	if filepath.IsAbs(data) {
+		return data
+	}
+	return filepath.Join(dir, data)
+}
+
+func runVersion(binary string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), _versionTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = append(os.Environ(), "CHECKPOINT_DISABLE=1")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,135 @@
+package tfversion
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+const lockFile = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:ltxyuBWIy9cq0kIKDJH1jeWJy/y7XJLjS4QrsQK4plA=",
+    "zh:0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d",
+  ]
+}
+
+provider "registry.opentofu.org/hashicorp/random" {
+  version = "3.6.0"
+  hashes  = ["h1:R5Ucn26riKIEijcsiOMBR3uOAjuOMfI1x7XvH4P6B1w="]
+}
+`
+
+func TestParseLockFile(t *testing.T) {
+	providers := ParseLockFile(lockFile)
+	if len(providers) != 2 {
+		t.Fatalf("ParseLockFile() returned %d providers, want 2", len(providers))
+	}
+
+	aws := providers[0]
+	if aws.Source != "registry.terraform.io/hashicorp/aws" || aws.Version != "5.31.0" || aws.Constraints != "~> 5.0" {
+		t.Errorf("aws = %+v", aws)
+	}
+	if len(aws.Hashes) != 2 || aws.Hashes[0] != "h1:ltxyuBWIy9cq0kIKDJH1jeWJy/y7XJLjS4QrsQK4plA=" {
+		t.Errorf("aws hashes = %q", aws.Hashes)
+	}
+
+	random := providers[1]
+	if random.Name() != "random" || random.Version != "3.6.0" || len(random.Hashes) != 1 {
+		t.Errorf("random = %+v", random)
+	}
+
+	if got := ParseLockFile(""); got != nil {
+		t.Errorf("ParseLockFile(\"\") = %v, want nil", got)
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	data := []byte(`{"Modules":[
+		{"Key":"","Source":"","Dir":"."},
+		{"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.1.2","Dir":".terraform/modules/vpc"},
+		{"Key":"dns","Source":"git::https://github.com/acme/tf-dns.git?ref=v1.0.0","Dir":".terraform/modules/dns"}
+	]}`)
+
+	modules := ParseModules(data)
+	var keys []string
+	for _, m := range modules {
+		keys = append(keys, m.Key)
+	}
+	if !slices.Equal(keys, []string{"dns", "vpc"}) {
+		t.Fatalf("ParseModules() keys = %v, want [dns vpc]", keys)
+	}
+	if modules[1].Version != "5.1.2" || modules[0].Version != "" {
+		t.Errorf("ParseModules() = %+v", modules)
+	}
+
+	if got := ParseModules([]byte("not json")); got != nil {
+		t.Errorf("ParseModules(invalid) = %v, want nil", got)
+	}
+}
+
+func TestParseVersionJSON(t *testing.T) {
+	data := []byte(`{"terraform_version":"1.7.5","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}`)
+	if got := ParseVersionJSON(data); got != "1.7.5" {
+		t.Errorf("ParseVersionJSON() = %q, want 1.7.5", got)
+	}
+	if got := ParseVersionJSON([]byte("Terraform v1.7.5")); got != "" {
+		t.Errorf("ParseVersionJSON(text) = %q, want empty", got)
+	}
+}
+
+func TestDetect_Chdir(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("TF_DATA_DIR", "")
+
+	workdir := filepath.Join("envs", "prod")
+	if err := os.MkdirAll(filepath.Join(workdir, ".terraform", "modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, ".terraform.lock.hcl"), []byte(lockFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modules := `{"Modules": [{"Key": "vpc", "Source": "terraform-aws-modules/vpc/aws", "Version": "5.1.0"}]}`
+	if err := os.WriteFile(filepath.Join(workdir, ".terraform", "modules", "modules.json"), []byte(modules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := filepath.Join(dir, "no-such-terraform")
+	if v := Detect(missing, ""); v != nil {
+		t.Errorf("Detect() from the parent = %+v, want nil", v)
+	}
+	v := Detect(missing, workdir)
+	if v == nil || len(v.Providers) != 2 || len(v.Modules) != 1 || v.Modules[0].Version != "5.1.0" {
+		t.Errorf("Detect(envs/prod) = %+v", v)
+	}
+}
+
+func TestDetect_SkipsWrappers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the wrapped command")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	script := filepath.Join(dir, "deploy.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(lockFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := Detect(script, dir)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Detect() ran the wrapped script")
+	}
+	if v == nil || v.Version != "" || v.Binary != "" || len(v.Providers) != 2 {
+		t.Errorf("Detect() = %+v, want providers from the lock file only", v)
+	}
+}
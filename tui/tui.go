@@ -1,13 +1,17 @@
 package tui
 
 import (
+	"bytes"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 
+	"github.com/Owloops/tfjournal/compare"
 	"github.com/Owloops/tfjournal/run"
 	"github.com/Owloops/tfjournal/storage"
 )
@@ -19,8 +23,8 @@ const (
 	_timelineTitle = "Timeline"
 	_eventsTitle   = "Events"
 	_outputTitle   = "Output"
-	_footerText    = "tfjournal · j/k:nav d/e/t/o:views /:search ?:help"
-	_footerTextS3  = "tfjournal · j/k:nav s:sync d/e/t/o:views /:search ?:help"
+	_footerText    = "tfjournal · j/k:nav d/e/t/o/f/c:views m:mark /:search ?:help"
+	_footerTextS3  = "tfjournal · j/k:nav s:sync d/e/t/o/f/c:views m:mark /:search ?:help"
 	_searchPrefix  = "/"
 	_noRunsMessage = "No runs found. Use 'tfjournal -- terraform apply' to record runs."
 )
@@ -32,6 +36,8 @@ const (
 	viewModeEvents
 	viewModeTimeline
 	viewModeOutput
+	viewModeDiff
+	viewModeCompare
 )
 
 type focusPanel int
@@ -50,6 +56,7 @@ type App struct {
 	selectedIdx  int
 	searchMode   bool
 	searchQuery  string
+	compareBase  string
 	showHelp     bool
 	focus        focusPanel
 	version      string
@@ -230,7 +237,7 @@ func (a *App) setupGrid() {
 		contentWidget = a.eventsTable
 	case viewModeTimeline:
 		contentWidget = a.ganttChart
-	case viewModeOutput:
+	case viewModeOutput, viewModeDiff, viewModeCompare:
 		contentWidget = a.outputView
 	}
 
@@ -302,6 +309,8 @@ func (a *App) updateTabsText() {
 		{viewModeEvents, "e", "Events"},
 		{viewModeTimeline, "t", "Timeline"},
 		{viewModeOutput, "o", "Output"},
+		{viewModeDiff, "f", "Diff"},
+		{viewModeCompare, "c", "Compare"},
 	}
 
 	var parts []string
@@ -358,6 +367,22 @@ func (a *App) updateFiltersText() {
 		parts = append(parts, fmt.Sprintf("branch:%s", a.listOpts.Branch))
 	}
 
+	if a.listOpts.Version != "" {
+		parts = append(parts, fmt.Sprintf("version:%s", a.listOpts.Version))
+	}
+
+	if a.listOpts.Provider != "" {
+		parts = append(parts, fmt.Sprintf("provider:%s", a.listOpts.Provider))
+	}
+
+	if a.listOpts.State != "" {
+		parts = append(parts, fmt.Sprintf("state:%s", a.listOpts.State))
+	}
+
+	if a.listOpts.Account != "" {
+		parts = append(parts, fmt.Sprintf("account:%s", a.listOpts.Account))
+	}
+
 	if a.listOpts.HasChanges {
 		parts = append(parts, "has-changes")
 	}
@@ -379,6 +404,7 @@ func (a *App) updateRunsList() {
 	totalRuns := len(a.runs)
 	isLoading := a.isLoading
 	isOffline := a.isOffline
+	compareBase := a.compareBase
 	a.mu.Unlock()
 
 	if len(runs) == 0 {
@@ -398,6 +424,12 @@ func (a *App) updateRunsList() {
 		case run.StatusRunning:
 			icon = "●"
 			iconColor = "yellow"
+		case run.StatusBlocked:
+			icon = "⊘"
+			iconColor = "magenta"
+		case run.StatusDrift:
+			icon = "⚠"
+			iconColor = "yellow"
 		}
 
 		timestamp := r.Timestamp.Format("01-02 15:04")
@@ -415,8 +447,13 @@ func (a *App) updateRunsList() {
 			}
 		}
 
-		rows[i] = fmt.Sprintf("[%s](fg:%s) %s %s %s%s",
-			icon, iconColor, timestamp, changes, truncate(r.Workspace, 16), syncIcon)
+		markIcon := ""
+		if r.ID == compareBase {
+			markIcon = " [◆](fg:cyan)"
+		}
+
+		rows[i] = fmt.Sprintf("[%s](fg:%s) %s %s %s%s%s",
+			icon, iconColor, timestamp, changes, truncate(r.Workspace, 16), syncIcon, markIcon)
 	}
 
 	a.runsList.Rows = rows
@@ -478,9 +515,87 @@ func (a *App) updateContentPaneWithOutput(r *run.Run, loadOutput bool) {
 
 	case viewModeOutput:
 		if loadOutput {
-			a.updateOutputPane(r)
+			a.loadTextPane(r, a.store.GetOutput, "No output available", "No output recorded for this run.")
+		}
+
+	case viewModeDiff:
+		if !loadOutput {
+			return
+		}
+		if r.Git == nil || r.Git.DiffBytes == 0 {
+			a.outputView.Rows = []string{"No diff captured for this run."}
+			a.outputView.SelectedRow = 0
+			a.outputScroll = 0
+			return
+		}
+		a.loadTextPane(r, a.store.GetDiff, "No diff available", "No diff captured for this run.")
+
+	case viewModeCompare:
+		if !loadOutput {
+			return
 		}
+		base := a.compareBaseFor(r)
+		if base == "" {
+			a.outputView.Rows = []string{"No earlier run of this workspace. Press m on a run to compare against it."}
+			a.outputView.SelectedRow = 0
+			a.outputScroll = 0
+			return
+		}
+		a.loadTextPane(r, func(id string) ([]byte, error) {
+			res, err := compare.Load(a.store, base, id, compare.DefaultContext)
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if err := res.Write(&buf); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}, "Comparison unavailable", "")
+	}
+}
+
+func (a *App) compareBaseFor(r *run.Run) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.compareBase != "" {
+		if a.compareBase == r.ID {
+			return ""
+		}
+		return a.compareBase
+	}
+	for i, candidate := range a.runs {
+		if candidate.ID != r.ID {
+			continue
+		}
+		for _, older := range a.runs[i+1:] {
+			if older.Workspace == r.Workspace {
+				return older.ID
+			}
+		}
+		break
+	}
+	return ""
+}
+
+func (a *App) toggleCompareBase() {
+	a.mu.Lock()
+	if a.selectedIdx >= len(a.filteredRuns) {
+		a.mu.Unlock()
+		return
 	}
+	id := a.filteredRuns[a.selectedIdx].ID
+	if a.compareBase == id {
+		a.compareBase = ""
+	} else {
+		a.compareBase = id
+	}
+	a.mu.Unlock()
+
+	a.updateRunsList()
+	a.updateDetails()
+	ui.Render(a.grid)
 }
 
 func (a *App) updateDetailsPane(r *run.Run) {
@@ -504,12 +619,61 @@ func (a *App) updateDetailsPane(r *run.Run) {
 		r.ChangeSummary(),
 	)
 
+	if r.Environment != "" {
+		details += fmt.Sprintf("\n[Environment:](fg:cyan) %s", r.Environment)
+	}
+	if r.Component != "" {
+		details += fmt.Sprintf("\n[Component:](fg:cyan)  %s", r.Component)
+	}
+
 	if r.Git != nil {
-		gitLine := fmt.Sprintf("%s (%s)", r.Git.Commit, r.Git.Branch)
-		if r.Git.Dirty {
-			gitLine += " [dirty]"
+		details += fmt.Sprintf("\n[Git:](fg:cyan)        %s", r.Git.Summary())
+		if author := r.Git.AuthorSummary(); author != "" {
+			details += fmt.Sprintf("\n[Author:](fg:cyan)     %s", author)
+		}
+		if len(r.Git.DirtyFiles) > 0 {
+			details += fmt.Sprintf("\n[Dirty files:](fg:cyan) %s", strings.Join(r.Git.DirtyFiles, ", "))
+		}
+	}
+
+	if r.Cloud != nil {
+		if account := r.Cloud.Account(); account != "" {
+			details += fmt.Sprintf("\n[Account:](fg:cyan)    %s", account)
+		}
+		if r.Cloud.AWSProfile != "" {
+			details += fmt.Sprintf("\n[Profile:](fg:cyan)    %s", r.Cloud.AWSProfile)
+		}
+		if r.Cloud.AWSRoleARN != "" {
+			details += fmt.Sprintf("\n[Role:](fg:cyan)       %s", r.Cloud.AWSRoleARN)
+		}
+		if len(r.Cloud.Env) > 0 {
+			env := make([]string, 0, len(r.Cloud.Env))
+			for _, k := range slices.Sorted(maps.Keys(r.Cloud.Env)) {
+				env = append(env, k+"="+r.Cloud.Env[k])
+			}
+			details += fmt.Sprintf("\n[Env:](fg:cyan)        %s", strings.Join(env, " "))
+		}
+		if len(r.Cloud.TFVars) > 0 {
+			details += fmt.Sprintf("\n[TF vars:](fg:cyan)    %s", strings.Join(r.Cloud.TFVars, ", "))
+		}
+	}
+
+	if r.State != nil {
+		details += fmt.Sprintf("\n[State:](fg:cyan)      %s (%s)", r.State.Summary(), r.State.Backend)
+	}
+
+	if r.Versions != nil {
+		details += fmt.Sprintf("\n[Versions:](fg:cyan)   %s", r.Versions.Summary())
+		if len(r.Versions.Providers) > 0 {
+			providers := make([]string, len(r.Versions.Providers))
+			for i, p := range r.Versions.Providers {
+				providers[i] = p.Name() + " " + p.Version
+			}
+			details += fmt.Sprintf("\n[Providers:](fg:cyan)  %s", strings.Join(providers, ", "))
+		}
+		if len(r.Versions.Modules) > 0 {
+			details += fmt.Sprintf("\n[Modules:](fg:cyan)    %d", len(r.Versions.Modules))
 		}
-		details += fmt.Sprintf("\n[Git:](fg:cyan)        %s", gitLine)
 	}
 
 	if r.CI != nil {
@@ -517,6 +681,34 @@ func (a *App) updateDetailsPane(r *run.Run) {
 		if r.CI.Workflow != "" {
 			details += fmt.Sprintf("\n[Workflow:](fg:cyan)   %s", r.CI.Workflow)
 		}
+		if r.CI.Job != "" {
+			details += fmt.Sprintf("\n[Job:](fg:cyan)        %s", r.CI.Job)
+		}
+		if r.CI.PullRequest != "" {
+			pr := "#" + r.CI.PullRequest
+			if r.CI.SourceBranch != "" && r.CI.TargetBranch != "" {
+				pr += fmt.Sprintf(" (%s → %s)", r.CI.SourceBranch, r.CI.TargetBranch)
+			}
+			details += fmt.Sprintf("\n[PR:](fg:cyan)         %s", pr)
+		}
+	}
+
+	if r.Approval != nil {
+		approval := r.Approval.Decision
+		if r.Approval.Approver != "" {
+			approval += " by " + r.Approval.Approver
+		}
+		details += fmt.Sprintf("\n[Approval:](fg:cyan)   %s", approval)
+		for _, reason := range r.Approval.Reasons {
+			details += fmt.Sprintf("\n  [%s](fg:magenta)", reason)
+		}
+	}
+
+	if r.Policy != nil {
+		details += fmt.Sprintf("\n[Policy:](fg:cyan)     %s", r.Policy.Decision)
+		for _, v := range r.Policy.Violations {
+			details += fmt.Sprintf("\n  [%s](fg:magenta) %s", v.Rule, v.Message)
+		}
 	}
 
 	if len(r.Resources) > 0 {
@@ -539,13 +731,13 @@ func (a *App) updateDetailsPane(r *run.Run) {
 	a.detailsView.Text = details
 }
 
-func (a *App) updateOutputPane(r *run.Run) {
-	a.outputView.Rows = []string{"Loading output..."}
+func (a *App) loadTextPane(r *run.Run, load func(string) ([]byte, error), unavailable, empty string) {
+	a.outputView.Rows = []string{"Loading..."}
 	a.outputView.SelectedRow = 0
 	a.outputScroll = 0
 
 	go func() {
-		output, err := a.store.GetOutput(r.ID)
+		output, err := load(r.ID)
 
 		a.mu.Lock()
 		if a.selectedIdx < len(a.filteredRuns) && a.filteredRuns[a.selectedIdx].ID != r.ID {
@@ -555,9 +747,9 @@ func (a *App) updateOutputPane(r *run.Run) {
 		a.mu.Unlock()
 
 		if err != nil {
-			a.outputView.Rows = []string{"No output available"}
+			a.outputView.Rows = []string{unavailable}
 		} else if len(output) == 0 {
-			a.outputView.Rows = []string{"No output recorded for this run."}
+			a.outputView.Rows = []string{empty}
 		} else {
 			lines := strings.Split(string(output), "\n")
 			a.outputLines = lines
@@ -664,6 +856,10 @@ func (a *App) eventLoop() error {
 				a.switchView(viewModeDetails)
 			case "o":
 				a.switchView(viewModeOutput)
+			case "f":
+				a.switchView(viewModeDiff)
+			case "c":
+				a.switchView(viewModeCompare)
 			case "q", "<C-c>":
 				return nil
 			}
@@ -708,6 +904,12 @@ func (a *App) eventLoop() error {
 			a.switchView(viewModeDetails)
 		case "o":
 			a.switchView(viewModeOutput)
+		case "f":
+			a.switchView(viewModeDiff)
+		case "c":
+			a.switchView(viewModeCompare)
+		case "m":
+			a.toggleCompareBase()
 		case "s":
 			if a.hybrid != nil {
 				a.syncRuns()
@@ -755,7 +957,7 @@ func (a *App) updateBorders() {
 }
 
 func (a *App) scrollContent(delta int) {
-	if a.viewMode != viewModeOutput {
+	if !a.isTextView() {
 		return
 	}
 	totalRows := len(a.outputView.Rows)
@@ -774,7 +976,7 @@ func (a *App) scrollContent(delta int) {
 }
 
 func (a *App) scrollContentPage(delta int) {
-	if a.viewMode != viewModeOutput {
+	if !a.isTextView() {
 		return
 	}
 	if delta > 0 {
@@ -786,7 +988,7 @@ func (a *App) scrollContentPage(delta int) {
 }
 
 func (a *App) scrollContentTop() {
-	if a.viewMode != viewModeOutput {
+	if !a.isTextView() {
 		return
 	}
 	a.outputView.ScrollTop()
@@ -794,7 +996,7 @@ func (a *App) scrollContentTop() {
 }
 
 func (a *App) scrollContentBottom() {
-	if a.viewMode != viewModeOutput {
+	if !a.isTextView() {
 		return
 	}
 	a.outputView.ScrollBottom()
@@ -841,6 +1043,10 @@ func (a *App) refreshSyncStatus() {
 	ui.Render(a.grid)
 }
 
+func (a *App) isTextView() bool {
+	return a.viewMode == viewModeOutput || a.viewMode == viewModeDiff || a.viewMode == viewModeCompare
+}
+
 func (a *App) switchView(mode viewMode) {
 	if a.viewMode == mode {
 		return
@@ -865,7 +1071,7 @@ func (a *App) navigate(direction int) {
 	}
 
 	a.runsList.SelectedRow = a.selectedIdx
-	a.updateDetailsWithOutput(a.viewMode == viewModeOutput)
+	a.updateDetailsWithOutput(a.isTextView())
 	ui.Render(a.grid)
 }
 
@@ -912,6 +1118,12 @@ func formatStatus(s run.Status) string {
 		return "[● running](fg:yellow)"
 	case run.StatusCanceled:
 		return "[○ canceled](fg:white)"
+	case run.StatusBlocked:
+		return "[⊘ blocked](fg:magenta)"
+	case run.StatusDrift:
+		return "[⚠ drift](fg:yellow)"
+	case run.StatusClean:
+		return "[✓ clean](fg:green)"
 	default:
 		return string(s)
 	}
@@ -6,19 +6,25 @@ function debounce(fn, delay) {
   }
 }
 
+const MIN_OUTPUT_QUERY = 3
+
 const state = {
   runs: [],
   filteredRuns: [],
+  total: 0,
+  nextCursor: '',
   selectedRunId: null,
   selectedRun: null,
   selectedIndex: -1,
   currentView: 'details',
   searchQuery: '',
+  outputMatches: {},
   statusFilter: '',
   sinceFilter: '',
   programFilter: '',
   actionFilter: '',
   branchFilter: '',
+  stateFilter: '',
   hasChangesFilter: false,
   limit: 20,
   focusPanel: 'runs',
@@ -38,6 +44,7 @@ const sinceFilter = document.getElementById('sinceFilter')
 const programFilter = document.getElementById('programFilter')
 const actionFilter = document.getElementById('actionFilter')
 const branchFilter = document.getElementById('branchFilter')
+const stateFilter = document.getElementById('stateFilter')
 const hasChangesFilter = document.getElementById('hasChangesFilter')
 const limitFilter = document.getElementById('limitFilter')
 const viewTabs = document.querySelectorAll('.tab')
@@ -50,6 +57,7 @@ function buildParams() {
   if (state.programFilter) params.set('program', state.programFilter)
   if (state.actionFilter) params.set('action', state.actionFilter)
   if (state.branchFilter) params.set('branch', state.branchFilter)
+  if (state.stateFilter) params.set('state', state.stateFilter)
   if (state.hasChangesFilter) params.set('has-changes', 'true')
   if (state.limit) params.set('limit', state.limit.toString())
   return params
@@ -57,13 +65,16 @@ function buildParams() {
 
 async function fetchRunsLocal() {
   const params = buildParams()
+  params.set('page', 'true')
   const response = await fetch(`/api/runs/local?${params}`)
   if (!response.ok) throw new Error('Failed to fetch local runs')
   return response.json()
 }
 
-async function fetchRuns() {
+async function fetchRuns(before) {
   const params = buildParams()
+  params.set('page', 'true')
+  if (before) params.set('before', before)
   const response = await fetch(`/api/runs?${params}`)
   if (!response.ok) throw new Error('Failed to fetch runs')
   return response.json()
@@ -81,6 +92,43 @@ async function fetchOutput(id) {
   return response.text()
 }
 
+async function fetchDiff(id) {
+  const response = await fetch(`/api/runs/${id}/diff`)
+  if (!response.ok) return null
+  return response.text()
+}
+
+async function fetchDrift() {
+  const params = new URLSearchParams()
+  if (state.sinceFilter) params.set('since', state.sinceFilter)
+  const response = await fetch(`/api/drift?${params}`)
+  if (!response.ok) return null
+  return response.json()
+}
+
+async function fetchLock(id) {
+  const response = await fetch(`/api/runs/${id}/lock`)
+  if (!response.ok) return null
+  return response.json()
+}
+
+async function fetchFailures() {
+  const params = new URLSearchParams()
+  if (state.sinceFilter) params.set('since', state.sinceFilter)
+  const response = await fetch(`/api/failures?${params}`)
+  if (!response.ok) return null
+  return response.json()
+}
+
+async function fetchSearch(query) {
+  const params = new URLSearchParams({ q: query, limit: '50' })
+  if (state.statusFilter) params.set('status', state.statusFilter)
+  if (state.sinceFilter) params.set('since', state.sinceFilter)
+  const response = await fetch(`/api/search?${params}`)
+  if (!response.ok) return []
+  return response.json()
+}
+
 async function fetchVersion() {
   try {
     const response = await fetch('/api/version')
@@ -143,6 +191,20 @@ function formatSyncStatus(syncStatus) {
   }
 }
 
+async function searchOutputs() {
+  const query = state.searchQuery.trim()
+  state.outputMatches = {}
+  if (query.length < MIN_OUTPUT_QUERY) return
+  try {
+    const results = await fetchSearch(query)
+    results.forEach((result) => {
+      state.outputMatches[result.run_id] = result
+    })
+  } catch {
+    state.outputMatches = {}
+  }
+}
+
 function filterRuns() {
   const query = state.searchQuery.toLowerCase()
   state.filteredRuns = state.runs.filter((run) => {
@@ -150,7 +212,8 @@ function filterRuns() {
       query &&
       !run.workspace.toLowerCase().includes(query) &&
       !run.user?.toLowerCase().includes(query) &&
-      !run.id.toLowerCase().includes(query)
+      !run.id.toLowerCase().includes(query) &&
+      !state.outputMatches[run.id]
     ) {
       return false
     }
@@ -158,9 +221,19 @@ function filterRuns() {
   })
 }
 
+function renderLoadMore() {
+  if (!state.nextCursor) return ''
+  return `
+    <div class="runs-more">
+      <span>${state.runs.length} of ${state.total}</span>
+      <button class="runs-more-btn" id="loadMoreBtn">Load more</button>
+    </div>
+  `
+}
+
 function renderRunsList() {
   if (state.filteredRuns.length === 0) {
-    runsList.innerHTML = '<div class="empty-state"><p>No runs found</p></div>'
+    runsList.innerHTML = '<div class="empty-state"><p>No runs found</p></div>' + renderLoadMore()
     return
   }
 
@@ -175,17 +248,110 @@ function renderRunsList() {
       </div>
       <div class="run-item-meta">
         <span class="run-time">${formatTimestamp(run.timestamp)}</span>
+        ${run.ci?.pull_request ? `<span class="run-pr">#${escapeHtml(run.ci.pull_request)}</span>` : ''}
+        ${run.attempt > 1 ? `<span class="run-attempt" title="Retry of ${escapeHtml(run.parent_id)}">↻${run.attempt}</span>` : ''}
+        ${state.outputMatches[run.id] ? `<span class="run-match" title="Output matches on ${state.outputMatches[run.id].match_count} lines">≡${state.outputMatches[run.id].match_count}</span>` : ''}
         <span class="run-user">${escapeHtml(run.user || 'unknown')}</span>
       </div>
     </div>
   `
     )
-    .join('')
+    .join('') + renderLoadMore()
+}
+
+function renderRetrySection(run) {
+  const retries = state.runs.filter((r) => r.parent_id === run.id).sort((a, b) => a.attempt - b.attempt)
+  if (!run.parent_id && retries.length === 0) return ''
+
+  const runLink = (id, label) =>
+    `<a href="#" class="detail-link" data-run-id="${escapeHtml(id).replace(/"/g, '&quot;')}">${escapeHtml(label)}</a>`
+
+  return `
+      <div class="detail-section">
+        <div class="detail-section-title">Retry</div>
+        <div class="detail-grid">
+          ${
+            run.parent_id
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Attempt</span>
+            <span class="detail-value">${run.attempt}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Retry of</span>
+            <span class="detail-value">${runLink(run.parent_id, run.parent_id)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${retries
+            .map(
+              (r) => `
+          <div class="detail-item">
+            <span class="detail-label">Attempt ${r.attempt}</span>
+            <span class="detail-value ${r.status === 'failed' ? 'failed' : 'success'}">${runLink(r.id, `${r.id} (${r.status})`)}</span>
+          </div>
+          `
+            )
+            .join('')}
+        </div>
+      </div>
+  `
 }
 
-function renderDetailsView(run) {
+function renderLockSection(report) {
+  if (!report) return ''
+  const lock = report.lock
+  const holder = report.holder
+
+  return `
+      <div class="detail-section">
+        <div class="detail-section-title">State Lock</div>
+        <div class="detail-grid">
+          <div class="detail-item">
+            <span class="detail-label">Held by</span>
+            <span class="detail-value">${escapeHtml(lock.who || '-')}${lock.operation ? ` (${escapeHtml(lock.operation)})` : ''}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Since</span>
+            <span class="detail-value">${lock.created ? formatTimestamp(lock.created) : '-'}</span>
+          </div>
+          ${
+            lock.id
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Lock ID</span>
+            <span class="detail-value">${escapeHtml(lock.id)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            holder.run_id
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Holder run</span>
+            <span class="detail-value"><a href="#" class="detail-link" data-run-id="${escapeHtml(holder.run_id).replace(/"/g, '&quot;')}">${escapeHtml(holder.run_id)}</a></span>
+          </div>
+          `
+              : ''
+          }
+          <div class="detail-item">
+            <span class="detail-label">Holder</span>
+            <span class="detail-value ${holder.state === 'running' ? '' : 'failed'}">${escapeHtml(holder.message)}</span>
+          </div>
+        </div>
+      </div>
+  `
+}
+
+function renderDetailsView(run, lockReport) {
   const gitInfo = run.git || {}
   const ciInfo = run.ci || {}
+  const versions = run.versions || {}
+  const stateInfo = run.state || {}
+  const cloudInfo = run.cloud || {}
+  const cloudEnv = Object.entries(cloudInfo.env || {}).sort(([a], [b]) => a.localeCompare(b))
 
   return `
     <div class="details-view">
@@ -198,12 +364,26 @@ function renderDetailsView(run) {
           </div>
           <div class="detail-item">
             <span class="detail-label">Status</span>
-            <span class="badge badge-${run.status === 'success' ? 'success' : 'error'}">${run.status}</span>
+            <span class="badge badge-${statusBadge(run.status)}">${run.status}</span>
           </div>
           <div class="detail-item">
             <span class="detail-label">Workspace</span>
             <span class="detail-value">${escapeHtml(run.workspace)}</span>
           </div>
+          ${
+            run.environment || run.component
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Environment</span>
+            <span class="detail-value">${escapeHtml(run.environment || '-')}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Component</span>
+            <span class="detail-value">${escapeHtml(run.component || '-')}</span>
+          </div>
+          `
+              : ''
+          }
           <div class="detail-item">
             <span class="detail-label">Duration</span>
             <span class="detail-value">${formatDuration(run.duration_ms)}</span>
@@ -239,7 +419,7 @@ function renderDetailsView(run) {
         <div class="detail-grid">
           <div class="detail-item">
             <span class="detail-label">Commit</span>
-            <span class="detail-value">${escapeHtml(gitInfo.commit)}</span>
+            <span class="detail-value" title="${escapeHtml(gitInfo.sha || gitInfo.commit)}">${formatLink(commitUrl(gitInfo), gitInfo.commit)}</span>
           </div>
           <div class="detail-item">
             <span class="detail-label">Branch</span>
@@ -249,6 +429,56 @@ function renderDetailsView(run) {
             <span class="detail-label">Dirty</span>
             <span class="detail-value">${gitInfo.dirty ? 'Yes' : 'No'}</span>
           </div>
+          ${
+            gitInfo.author
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Author</span>
+            <span class="detail-value">${escapeHtml(gitInfo.author)}${gitInfo.committer && gitInfo.committer !== gitInfo.author ? ` (committed by ${escapeHtml(gitInfo.committer)})` : ''}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            gitInfo.commit_time
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Committed</span>
+            <span class="detail-value">${formatTimestamp(gitInfo.commit_time)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            gitInfo.tags?.length
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Tags</span>
+            <span class="detail-value">${gitInfo.tags.map(escapeHtml).join(', ')}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            gitInfo.upstream
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Upstream</span>
+            <span class="detail-value">${escapeHtml(gitInfo.upstream)} ↑${gitInfo.ahead || 0} ↓${gitInfo.behind || 0}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            gitInfo.dirty_files?.length
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Dirty Files</span>
+            <span class="detail-value">${gitInfo.dirty_files.map(escapeHtml).join('<br>')}</span>
+          </div>
+          `
+              : ''
+          }
           ${
             gitInfo.message
               ? `
@@ -265,6 +495,160 @@ function renderDetailsView(run) {
           : ''
       }
 
+      ${
+        cloudInfo.aws_account_id || cloudInfo.aws_profile || cloudInfo.aws_role_arn || cloudEnv.length || cloudInfo.tf_vars?.length
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">Cloud</div>
+        <div class="detail-grid">
+          ${
+            cloudInfo.aws_account_id
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">AWS Account</span>
+            <span class="detail-value">${escapeHtml(cloudInfo.aws_account_id)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            cloudInfo.aws_profile
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">AWS Profile</span>
+            <span class="detail-value">${escapeHtml(cloudInfo.aws_profile)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            cloudInfo.aws_role_arn
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">AWS Role</span>
+            <span class="detail-value">${escapeHtml(cloudInfo.aws_role_arn)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${cloudEnv
+            .map(
+              ([name, value]) => `
+          <div class="detail-item">
+            <span class="detail-label">${escapeHtml(name)}</span>
+            <span class="detail-value">${escapeHtml(value)}</span>
+          </div>
+          `
+            )
+            .join('')}
+          ${
+            cloudInfo.tf_vars?.length
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">TF Vars</span>
+            <span class="detail-value">${cloudInfo.tf_vars.map(escapeHtml).join(', ')}</span>
+          </div>
+          `
+              : ''
+          }
+        </div>
+      </div>
+      `
+          : ''
+      }
+
+      ${
+        stateInfo.backend
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">State</div>
+        <div class="detail-grid">
+          <div class="detail-item">
+            <span class="detail-label">Backend</span>
+            <span class="detail-value">${escapeHtml(stateInfo.backend)}</span>
+          </div>
+          ${
+            stateInfo.id
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Identity</span>
+            <span class="detail-value"><a class="detail-link" href="#" data-state-id="${escapeHtml(stateInfo.id).replace(/"/g, '&quot;')}" title="Show all runs for this state">${escapeHtml(stateInfo.id)}</a></span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            stateInfo.workspace
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Workspace</span>
+            <span class="detail-value">${escapeHtml(stateInfo.workspace)}</span>
+          </div>
+          `
+              : ''
+          }
+        </div>
+      </div>
+      `
+          : ''
+      }
+
+      ${
+        versions.binary || versions.providers?.length
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">Versions</div>
+        <div class="detail-grid">
+          ${
+            versions.version
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">${escapeHtml(versions.binary || 'Version')}</span>
+            <span class="detail-value">${escapeHtml(versions.version)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            versions.terragrunt
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Terragrunt</span>
+            <span class="detail-value">${escapeHtml(versions.terragrunt)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            versions.providers?.length
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Providers</span>
+            <span class="detail-value">${versions.providers
+              .map((p) => `<span title="${escapeHtml(p.source).replace(/"/g, '&quot;')}">${escapeHtml(p.source.split('/').pop())} ${escapeHtml(p.version)}</span>${p.constraints ? ` (${escapeHtml(p.constraints)})` : ''}`)
+              .join('<br>')}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            versions.modules?.length
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Modules</span>
+            <span class="detail-value">${versions.modules
+              .map((m) => `${escapeHtml(m.key)}: ${escapeHtml(m.source)}${m.version ? ` ${escapeHtml(m.version)}` : ''}`)
+              .join('<br>')}</span>
+          </div>
+          `
+              : ''
+          }
+        </div>
+      </div>
+      `
+          : ''
+      }
+
       ${
         ciInfo.provider
           ? `
@@ -273,7 +657,7 @@ function renderDetailsView(run) {
         <div class="detail-grid">
           <div class="detail-item">
             <span class="detail-label">Provider</span>
-            <span class="detail-value">${escapeHtml(ciInfo.provider)}</span>
+            <span class="detail-value">${formatLink(ciInfo.url, ciInfo.provider)}</span>
           </div>
           <div class="detail-item">
             <span class="detail-label">Actor</span>
@@ -289,6 +673,130 @@ function renderDetailsView(run) {
           `
               : ''
           }
+          ${
+            ciInfo.job
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Job</span>
+            <span class="detail-value">${escapeHtml(ciInfo.job)}${ciInfo.attempt > 1 ? ` (attempt ${ciInfo.attempt})` : ''}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            ciInfo.event
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Event</span>
+            <span class="detail-value">${escapeHtml(ciInfo.event)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            ciInfo.pull_request
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Pull Request</span>
+            <span class="detail-value">${formatLink(ciInfo.pull_request_url, `#${ciInfo.pull_request}`)}</span>
+          </div>
+          `
+              : ''
+          }
+          ${
+            ciInfo.source_branch && ciInfo.target_branch
+              ? `
+          <div class="detail-item">
+            <span class="detail-label">Branches</span>
+            <span class="detail-value">${escapeHtml(ciInfo.source_branch)} → ${escapeHtml(ciInfo.target_branch)}</span>
+          </div>
+          `
+              : ''
+          }
+        </div>
+      </div>
+      `
+          : ''
+      }
+
+      ${
+        run.approval
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">Approval</div>
+        <div class="detail-grid">
+          <div class="detail-item">
+            <span class="detail-label">Decision</span>
+            <span class="detail-value ${{ approved: 'success', rejected: 'failed' }[run.approval.decision] || ''}">${escapeHtml(run.approval.decision)}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Approver</span>
+            <span class="detail-value">${escapeHtml(run.approval.approver || '-')}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Method</span>
+            <span class="detail-value">${escapeHtml(run.approval.method || '-')}</span>
+          </div>
+          ${(run.approval.reasons || [])
+            .map(
+              (reason) => `
+          <div class="detail-item">
+            <span class="detail-label">Reason</span>
+            <span class="detail-value">${escapeHtml(reason)}</span>
+          </div>
+          `
+            )
+            .join('')}
+        </div>
+      </div>
+      `
+          : ''
+      }
+
+      ${
+        run.policy
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">Policy</div>
+        <div class="detail-grid">
+          <div class="detail-item">
+            <span class="detail-label">Decision</span>
+            <span class="detail-value ${run.policy.decision === 'blocked' ? 'failed' : ''}">${escapeHtml(run.policy.decision)}</span>
+          </div>
+          ${(run.policy.violations || [])
+            .map(
+              (v) => `
+          <div class="detail-item">
+            <span class="detail-label">${escapeHtml(v.rule)} (${escapeHtml(v.effect)})</span>
+            <span class="detail-value">${escapeHtml(v.message)}</span>
+          </div>
+          `
+            )
+            .join('')}
+        </div>
+      </div>
+      `
+          : ''
+      }
+
+      ${renderRetrySection(run)}
+
+      ${renderLockSection(lockReport)}
+
+      ${
+        run.failure
+          ? `
+      <div class="detail-section">
+        <div class="detail-section-title">Failure</div>
+        <div class="detail-grid">
+          <div class="detail-item">
+            <span class="detail-label">Category</span>
+            <span class="detail-value failed">${escapeHtml(run.failure.category)}${run.failure.transient ? ' (transient)' : ''}</span>
+          </div>
+          <div class="detail-item">
+            <span class="detail-label">Error</span>
+            <span class="detail-value">${escapeHtml(run.failure.message || '-')}</span>
+          </div>
         </div>
       </div>
       `
@@ -320,6 +828,18 @@ function renderDetailsView(run) {
   `
 }
 
+function statusBadge(status) {
+  switch (status) {
+    case 'success':
+    case 'clean':
+      return 'success'
+    case 'drift':
+      return 'warning'
+    default:
+      return 'error'
+  }
+}
+
 function formatResourceStatus(status) {
   switch (status) {
     case 'success':
@@ -431,7 +951,211 @@ async function renderOutputView(run) {
   `
 }
 
+function diffLineClass(line) {
+  if (line.startsWith('+++') || line.startsWith('---') || line.startsWith('diff ')) return 'diff-file'
+  if (line.startsWith('@@')) return 'diff-hunk'
+  if (line.startsWith('+')) return 'diff-add'
+  if (line.startsWith('-')) return 'diff-del'
+  return ''
+}
+
+async function renderDiffView(run) {
+  const diff = run.git?.diff_bytes ? await fetchDiff(run.id) : null
+
+  if (!diff) {
+    return '<div class="empty-state"><p>No diff captured for this run</p></div>'
+  }
+
+  const lines = diff
+    .replace(/\n$/, '')
+    .split('\n')
+    .map((line) => `<span class="${diffLineClass(line)}">${escapeHtml(line) || ' '}</span>`)
+    .join('\n')
+
+  return `
+    <div class="output-view">
+      ${run.git.diff_truncated ? '<div class="diff-notice">Diff truncated to configured size limit</div>' : ''}
+      <pre class="output-content diff-content">${lines}</pre>
+    </div>
+  `
+}
+
+function plannedSymbol(action) {
+  switch (action) {
+    case 'create':
+      return '+'
+    case 'update':
+      return '~'
+    case 'destroy':
+      return '-'
+    default:
+      return '±'
+  }
+}
+
+async function renderDriftView() {
+  const report = await fetchDrift()
+
+  if (!report || report.workspaces.length === 0) {
+    return '<div class="empty-state"><p>No drift checks recorded. Run <code>tfjournal -- terraform plan -detailed-exitcode</code> on a schedule.</p></div>'
+  }
+
+  return `
+    <div class="drift-view">
+      <div class="drift-summary">
+        <span class="badge badge-warning">${report.drifted} drifted</span>
+        <span class="badge badge-success">${report.clean} clean</span>
+      </div>
+      <table class="events-table">
+        <thead>
+          <tr>
+            <th>Workspace</th>
+            <th>Status</th>
+            <th>Drifted since</th>
+            <th>Last checked</th>
+            <th>Checks</th>
+            <th>Changes</th>
+          </tr>
+        </thead>
+        <tbody>
+          ${report.workspaces
+            .map(
+              (ws) => `
+            <tr>
+              <td class="resource-address"><a href="#" class="detail-link" data-run-id="${escapeHtml(ws.run_id).replace(/"/g, '&quot;')}">${escapeHtml(ws.workspace)}</a></td>
+              <td><span class="badge badge-${statusBadge(ws.status)}">${ws.status}</span></td>
+              <td>${ws.since ? formatTimestamp(ws.since) : '-'}</td>
+              <td>${formatTimestamp(ws.last_checked)}</td>
+              <td>${ws.checks}</td>
+              <td>${ws.changes ? formatChanges(ws.changes) : '-'}</td>
+            </tr>
+            ${
+              ws.resources?.length
+                ? `<tr class="drift-resources"><td colspan="6">${ws.resources
+                    .map((r) => `<div><span class="resource-action ${r.action}">${plannedSymbol(r.action)}</span> ${escapeHtml(r.address)}</div>`)
+                    .join('')}</td></tr>`
+                : ''
+            }
+          `
+            )
+            .join('')}
+        </tbody>
+      </table>
+    </div>
+  `
+}
+
+async function renderFailuresView() {
+  const report = await fetchFailures()
+
+  if (!report || report.failed === 0) {
+    return '<div class="empty-state"><p>No failed runs recorded.</p></div>'
+  }
+
+  const categories = Object.entries(report.categories).sort((a, b) => b[1] - a[1])
+
+  return `
+    <div class="drift-view">
+      <div class="drift-summary">
+        <span class="badge badge-error">${report.failed} failed</span>
+        <span class="badge badge-warning">${report.transient} transient</span>
+        ${categories.map(([category, count]) => `<span class="badge badge-accent">${escapeHtml(category)} ${count}</span>`).join('')}
+      </div>
+      <table class="events-table">
+        <thead>
+          <tr>
+            <th>Workspace</th>
+            <th>Category</th>
+            <th>Count</th>
+            <th>Last seen</th>
+            <th>Error</th>
+          </tr>
+        </thead>
+        <tbody>
+          ${report.signatures
+            .map(
+              (sig) => `
+            <tr>
+              <td class="resource-address"><a href="#" class="detail-link" data-run-id="${escapeHtml(sig.last_run_id).replace(/"/g, '&quot;')}">${escapeHtml(sig.workspace)}</a></td>
+              <td><span class="badge badge-${sig.transient ? 'warning' : 'error'}">${escapeHtml(sig.category)}</span></td>
+              <td>${sig.recurring ? `<strong>${sig.count}</strong>` : sig.count}</td>
+              <td>${formatTimestamp(sig.last_seen)}</td>
+              <td class="failure-message" title="${escapeHtml(sig.message).replace(/"/g, '&quot;')}">${escapeHtml(sig.signature)}</td>
+            </tr>
+          `
+            )
+            .join('')}
+        </tbody>
+      </table>
+    </div>
+  `
+}
+
+function highlightTerms(text, query) {
+  const terms = query
+    .toLowerCase()
+    .split(/[^\p{L}\p{N}_]+/u)
+    .filter((term) => term.length >= 2)
+  const escaped = escapeHtml(text)
+  if (terms.length === 0) return escaped
+  return escaped.replace(new RegExp(`(${terms.join('|')})`, 'giu'), '<mark>$1</mark>')
+}
+
+function renderSearchView() {
+  const query = state.searchQuery.trim()
+  if (query.length < MIN_OUTPUT_QUERY) {
+    return `<div class="empty-state"><p>Type at least ${MIN_OUTPUT_QUERY} characters in the search box (<kbd>/</kbd>) to search run output.</p></div>`
+  }
+
+  const results = Object.values(state.outputMatches).sort((a, b) => new Date(b.timestamp) - new Date(a.timestamp))
+  if (results.length === 0) {
+    return `<div class="empty-state"><p>No output matches "${escapeHtml(query)}".</p></div>`
+  }
+
+  return `
+    <div class="search-view">
+      ${results
+        .map(
+          (result) => `
+        <div class="search-result">
+          <div class="search-result-header">
+            <div class="run-status ${result.status}"></div>
+            <a href="#" class="detail-link" data-run-id="${escapeHtml(result.run_id).replace(/"/g, '&quot;')}" data-run-view="output">${escapeHtml(result.workspace)}</a>
+            <span class="badge badge-accent">${escapeHtml(result.action || '-')}</span>
+            <span class="search-result-meta">${formatTimestamp(result.timestamp)} · ${escapeHtml(result.user || 'unknown')}</span>
+          </div>
+          ${result.matches
+            .map(
+              (m) => `
+          <div class="search-line"><span class="search-line-number">${m.line}</span><span class="search-line-text">${highlightTerms(m.text, query)}</span></div>
+          `
+            )
+            .join('')}
+          ${result.match_count > result.matches.length ? `<div class="search-line-more">${result.match_count - result.matches.length} more matching lines</div>` : ''}
+        </div>
+      `
+        )
+        .join('')}
+    </div>
+  `
+}
+
 async function renderContent() {
+  if (state.currentView === 'drift') {
+    contentBody.innerHTML = await renderDriftView()
+    return
+  }
+
+  if (state.currentView === 'failures') {
+    contentBody.innerHTML = await renderFailuresView()
+    return
+  }
+
+  if (state.currentView === 'search') {
+    contentBody.innerHTML = renderSearchView()
+    return
+  }
+
   if (!state.selectedRun) {
     contentBody.innerHTML = '<div class="empty-state"><p>Select a run to view details</p></div>'
     return
@@ -440,7 +1164,7 @@ async function renderContent() {
   let html = ''
   switch (state.currentView) {
     case 'details':
-      html = renderDetailsView(state.selectedRun)
+      html = renderDetailsView(state.selectedRun, state.selectedRun.lock ? await fetchLock(state.selectedRun.id) : null)
       break
     case 'events':
       html = renderEventsView(state.selectedRun)
@@ -451,6 +1175,9 @@ async function renderContent() {
     case 'output':
       html = await renderOutputView(state.selectedRun)
       break
+    case 'diff':
+      html = await renderDiffView(state.selectedRun)
+      break
   }
 
   contentBody.innerHTML = html
@@ -463,6 +1190,19 @@ function escapeHtml(text) {
   return div.innerHTML
 }
 
+function commitUrl(gitInfo) {
+  if (!gitInfo.remote_url || !gitInfo.sha) return ''
+  if (gitInfo.remote_url.includes('gitlab')) return `${gitInfo.remote_url}/-/commit/${gitInfo.sha}`
+  if (gitInfo.remote_url.includes('bitbucket.org')) return `${gitInfo.remote_url}/commits/${gitInfo.sha}`
+  return `${gitInfo.remote_url}/commit/${gitInfo.sha}`
+}
+
+function formatLink(url, text) {
+  if (!/^https?:\/\//i.test(url || '')) return escapeHtml(text)
+  const href = escapeHtml(url).replace(/"/g, '&quot;')
+  return `<a class="detail-link" href="${href}" target="_blank" rel="noopener noreferrer">${escapeHtml(text)}</a>`
+}
+
 async function selectRun(id, index) {
   state.selectedRunId = id
   state.selectedIndex = index
@@ -502,30 +1242,50 @@ function setView(view) {
   renderContent()
 }
 
+function applyPage(page) {
+  state.runs = page.runs || []
+  state.total = page.total || 0
+  state.nextCursor = page.next_cursor || ''
+}
+
 async function loadRuns() {
   runsList.innerHTML = '<div class="loading">Loading runs...</div>'
 
   try {
-    state.runs = (await fetchRunsLocal()) || []
+    applyPage(await fetchRunsLocal())
   } catch {
-    state.runs = []
+    applyPage({})
     runsList.innerHTML = '<div class="empty-state"><p>Failed to load runs</p></div>'
     return
   }
 
+  await searchOutputs()
   filterRuns()
   renderRunsList()
   selectFirstIfNeeded()
 
-  fetchRuns().then((mergedRuns) => {
-    if (mergedRuns && mergedRuns.length > 0) {
-      state.runs = mergedRuns
+  fetchRuns().then((page) => {
+    if (page && page.runs && page.runs.length > 0) {
+      applyPage(page)
       filterRuns()
       renderRunsList()
     }
   }).catch(() => {})
 }
 
+async function loadMoreRuns() {
+  if (!state.nextCursor) return
+
+  try {
+    const page = await fetchRuns(state.nextCursor)
+    state.runs = state.runs.concat(page.runs || [])
+    state.total = page.total || state.total
+    state.nextCursor = page.next_cursor || ''
+    filterRuns()
+    renderRunsList()
+  } catch {}
+}
+
 function selectFirstIfNeeded() {
   if (state.filteredRuns.length > 0) {
     const currentStillExists =
@@ -596,6 +1356,12 @@ function getActiveFilters() {
       value: state.branchFilter,
       label: `branch:${state.branchFilter}`
     })
+  if (state.stateFilter)
+    filters.push({
+      key: 'state',
+      value: state.stateFilter,
+      label: `state:${escapeHtml(state.stateFilter)}`
+    })
   if (state.hasChangesFilter) filters.push({ key: 'hasChanges', value: true, label: 'has-changes' })
   return filters
 }
@@ -640,6 +1406,10 @@ function clearFilter(key) {
       state.branchFilter = ''
       branchFilter.value = ''
       break
+    case 'state':
+      state.stateFilter = ''
+      stateFilter.value = ''
+      break
     case 'hasChanges':
       state.hasChangesFilter = false
       hasChangesFilter.checked = false
@@ -722,6 +1492,22 @@ function handleKeyDown(e) {
       setView('output')
       e.preventDefault()
       break
+    case 'f':
+      setView('diff')
+      e.preventDefault()
+      break
+    case 'r':
+      setView('drift')
+      e.preventDefault()
+      break
+    case 'x':
+      setView('failures')
+      e.preventDefault()
+      break
+    case 'm':
+      setView('search')
+      e.preventDefault()
+      break
     case '?':
       toggleHelp()
       e.preventDefault()
@@ -740,11 +1526,13 @@ function handleKeyDown(e) {
 
 searchInput.addEventListener(
   'input',
-  debounce((e) => {
+  debounce(async (e) => {
     state.searchQuery = e.target.value
+    await searchOutputs()
     filterRuns()
     renderRunsList()
     updateURL()
+    if (state.currentView === 'search') renderContent()
   }, 300)
 )
 
@@ -816,6 +1604,43 @@ branchFilter.addEventListener(
   }, 300)
 )
 
+stateFilter.addEventListener(
+  'input',
+  debounce((e) => {
+    state.stateFilter = e.target.value
+    updateFilterUI()
+    updateURL()
+    loadRuns()
+  }, 300)
+)
+
+function filterByState(id) {
+  state.stateFilter = id
+  stateFilter.value = id
+  updateFilterUI()
+  updateURL()
+  loadRuns()
+}
+
+contentBody.addEventListener('click', (e) => {
+  const link = e.target.closest('[data-state-id]')
+  if (link) {
+    e.preventDefault()
+    filterByState(link.dataset.stateId)
+    return
+  }
+  const runLink = e.target.closest('[data-run-id]')
+  if (runLink) {
+    e.preventDefault()
+    const id = runLink.dataset.runId
+    state.currentView = runLink.dataset.runView || 'details'
+    viewTabs.forEach((tab) => {
+      tab.classList.toggle('active', tab.dataset.view === state.currentView)
+    })
+    selectRun(id, state.filteredRuns.findIndex((r) => r.id === id))
+  }
+})
+
 hasChangesFilter.addEventListener('change', (e) => {
   state.hasChangesFilter = e.target.checked
   updateFilterUI()
@@ -830,6 +1655,10 @@ limitFilter.addEventListener('change', (e) => {
 })
 
 runsList.addEventListener('click', (e) => {
+  if (e.target.closest('#loadMoreBtn')) {
+    loadMoreRuns()
+    return
+  }
   const runItem = e.target.closest('.run-item')
   if (runItem) {
     const index = parseInt(runItem.dataset.index, 10)
@@ -916,6 +1745,7 @@ function updateURL() {
   if (state.programFilter) params.set('program', state.programFilter)
   if (state.actionFilter) params.set('action', state.actionFilter)
   if (state.branchFilter) params.set('branch', state.branchFilter)
+  if (state.stateFilter) params.set('state', state.stateFilter)
   if (state.hasChangesFilter) params.set('changes', '1')
   if (state.limit !== 20) params.set('limit', state.limit.toString())
 
@@ -932,6 +1762,7 @@ function loadFromURL() {
   state.programFilter = params.get('program') || ''
   state.actionFilter = params.get('action') || ''
   state.branchFilter = params.get('branch') || ''
+  state.stateFilter = params.get('state') || ''
   state.hasChangesFilter = params.get('changes') === '1'
   state.limit = parseInt(params.get('limit'), 10) || 20
   state.currentView = params.get('view') || 'details'
@@ -942,6 +1773,7 @@ function loadFromURL() {
   programFilter.value = state.programFilter
   actionFilter.value = state.actionFilter
   branchFilter.value = state.branchFilter
+  stateFilter.value = state.stateFilter
   hasChangesFilter.checked = state.hasChangesFilter
   limitFilter.value = state.limit.toString()
 